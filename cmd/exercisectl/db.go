@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"exercise/database"
+)
+
+// runDB 数据库管理命令
+func runDB(app *cli, args []string) error {
+	return subcommand(app, "db", map[string]func(*cli, []string) error{
+		"migrate":  dbMigrate,
+		"rollback": dbRollback,
+		"status":   dbStatus,
+		"reset":    dbReset,
+		"drop":     dbDrop,
+		"backup":   dbBackup,
+		"restore":  dbRestore,
+	}, []string{"migrate", "rollback", "status", "reset", "drop", "backup", "restore"}, args)
+}
+
+// dbMigrate 运行迁移
+func dbMigrate(app *cli, args []string) error {
+	if _, err := parseArgs(newFlagSet("db migrate", ""), args); err != nil {
+		return err
+	}
+	if err := database.Migrate(); err != nil {
+		return err
+	}
+	return app.out.message("✅ 数据库迁移完成")
+}
+
+// dbRollback 回滚最近一次迁移（首次迁移的回滚会删除所有表，需 --force）
+func dbRollback(app *cli, args []string) error {
+	opts, err := confirmDestructive("db rollback", "撤销最近一次迁移新增的表、列和索引", args)
+	if err != nil {
+		return err
+	}
+	result, err := database.Rollback(opts)
+	if err != nil {
+		if errors.Is(err, database.ErrNoMigration) || errors.Is(err, database.ErrDestructiveRefused) {
+			return err
+		}
+		return fmt.Errorf("回滚失败: %v", err)
+	}
+	return app.out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "已回滚迁移 #%d\n\n", result.MigrationID)
+		fmt.Fprintln(w, "表\t类型\t名称")
+		for _, c := range result.Reverted {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", c.Table, c.Kind, c.Name)
+		}
+		for _, c := range result.Irreversible {
+			fmt.Fprintf(w, "%s\t%s\t%s（无法自动撤销：%s -> %s）\n", c.Table, c.Kind, c.Name, c.Actual, c.Expected)
+		}
+	})
+}
+
+// dbStatus 输出数据库状态和迁移记录，结构不一致时以退出码 4 结束
+func dbStatus(app *cli, args []string) error {
+	if _, err := parseArgs(newFlagSet("db status", ""), args); err != nil {
+		return err
+	}
+	report, statusErr := database.CheckStatus()
+	if report == nil {
+		return statusErr
+	}
+	history, err := database.MigrationHistory()
+	if err != nil {
+		return err
+	}
+
+	status := struct {
+		*database.StatusReport
+		Migrations  int        `json:"migrations"`
+		LastApplied *time.Time `json:"last_applied,omitempty"`
+	}{StatusReport: report, Migrations: len(history)}
+	if len(history) > 0 {
+		status.LastApplied = &history[0].AppliedAt
+	}
+
+	err = app.out.print(status, func(w io.Writer) {
+		fmt.Fprint(w, report.String())
+		fmt.Fprintf(w, "\n迁移记录: %d 次", len(history))
+		if status.LastApplied != nil {
+			fmt.Fprintf(w, "，最近一次 %s", status.LastApplied.Format(time.DateTime))
+		}
+		fmt.Fprintln(w)
+	})
+	if err != nil {
+		return err
+	}
+	return statusErr
+}
+
+// dbReset 删除并重新创建所有表
+func dbReset(app *cli, args []string) error {
+	opts, err := confirmDestructive("db reset", "删除所有表并重新迁移", args)
+	if err != nil {
+		return err
+	}
+	if err := database.Reset(opts); err != nil {
+		return err
+	}
+	return app.out.message("✅ 数据库已重置")
+}
+
+// dbDrop 删除所有表
+func dbDrop(app *cli, args []string) error {
+	opts, err := confirmDestructive("db drop", "删除所有表", args)
+	if err != nil {
+		return err
+	}
+	if err := database.DropAll(opts); err != nil {
+		return err
+	}
+	return app.out.message("✅ 所有表已删除")
+}
+
+// dbBackup 备份所有表的数据，文件为 - 时写到标准输出
+func dbBackup(app *cli, args []string) error {
+	positional, err := parseArgs(newFlagSet("db backup", "<文件|->"), args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usagef("用法: exercisectl db backup <文件|->")
+	}
+	if positional[0] == "-" {
+		_, err := database.Backup(os.Stdout)
+		return err
+	}
+
+	file := positional[0]
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("创建备份文件失败: %v", err)
+	}
+	manifest, err := database.Backup(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file)
+		return fmt.Errorf("备份失败: %v", err)
+	}
+
+	return app.out.print(manifest, func(w io.Writer) {
+		fmt.Fprintf(w, "已备份 %s 到 %s（结构版本 %s）\n\n", manifest.Database, file, manifest.SchemaVersion)
+		printCounts(w, manifest.Counts)
+	})
+}
+
+// dbRestore 从备份恢复数据，默认要求目标表为空，-replace 时先清空（需 --force）
+func dbRestore(app *cli, args []string) error {
+	var opts database.RestoreOptions
+	fs := newFlagSet("db restore", "<文件|->")
+	fs.IntVar(&opts.BatchSize, "batch-size", 500, "每批插入的行数")
+	fs.BoolVar(&opts.Replace, "replace", false, "清空备份中包含的表后再恢复")
+	force := fs.Bool("force", false, "确认执行：-replace 时清空现有数据")
+	fs.StringVar(&opts.Destructive.OverrideToken, "override-token", "", "生产环境执行时须提供的令牌（app.override_token）")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usagef("用法: exercisectl db restore <文件|-> [-replace --force]")
+	}
+	if opts.Replace && !*force {
+		return usagef("db restore -replace 将清空现有数据（执行前自动备份）；确认执行请加 --force")
+	}
+
+	in := io.Reader(os.Stdin)
+	if positional[0] != "-" {
+		f, err := os.Open(positional[0])
+		if err != nil {
+			return fmt.Errorf("读取备份文件失败: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	report, err := database.Restore(in, opts)
+	if err != nil {
+		return err
+	}
+	return app.out.print(report, func(w io.Writer) {
+		fmt.Fprintf(w, "已恢复 %s 于 %s 的备份（结构版本 %s）\n\n",
+			report.Header.Database, report.Header.CreatedAt.Format(time.DateTime), report.Header.SchemaVersion)
+		printCounts(w, report.Counts)
+	})
+}
+
+// printCounts 输出各表行数
+func printCounts(w io.Writer, counts []database.TableCount) {
+	fmt.Fprintln(w, "表\t行数")
+	for _, c := range counts {
+		fmt.Fprintf(w, "%s\t%d\n", c.Table, c.Rows)
+	}
+}
+
+// confirmDestructive 解析 --force 和 --override-token 参数，未确认时拒绝执行
+func confirmDestructive(name, action string, args []string) (database.DestructiveOptions, error) {
+	var opts database.DestructiveOptions
+	fs := newFlagSet(name, "")
+	force := fs.Bool("force", false, "确认执行："+action)
+	fs.StringVar(&opts.OverrideToken, "override-token", "", "生产环境执行时须提供的令牌（app.override_token）")
+	if _, err := parseArgs(fs, args); err != nil {
+		return opts, err
+	}
+	if !*force {
+		return opts, usagef("%s 将%s（执行前自动备份）；确认执行请加 --force", name, action)
+	}
+	return opts, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"exercise/config"
+	"exercise/database"
+	"exercise/models"
+	"exercise/services"
+)
+
+// runEvents 领域事件管理命令
+func runEvents(app *cli, args []string) error {
+	return subcommand(app, "events", map[string]func(*cli, []string) error{
+		"run":     eventsRun,
+		"list":    eventsList,
+		"requeue": eventsRequeue,
+	}, []string{"run", "list", "requeue"}, args)
+}
+
+// eventsPolicy 领域事件投递策略
+func eventsPolicy(c config.EventsConfig) services.DispatchPolicy {
+	return services.DispatchPolicy{
+		PollInterval: c.PollInterval,
+		BatchSize:    c.BatchSize,
+		MaxAttempts:  c.MaxAttempts,
+		RetryBackoff: c.RetryBackoff,
+		MaxBackoff:   c.MaxBackoff,
+		Timeout:      c.Timeout,
+	}
+}
+
+// webhooksPolicy Webhook 发送策略
+func webhooksPolicy(c config.WebhooksConfig) services.DispatchPolicy {
+	return services.DispatchPolicy{
+		PollInterval: c.PollInterval,
+		BatchSize:    c.BatchSize,
+		MaxAttempts:  c.MaxAttempts,
+		RetryBackoff: c.RetryBackoff,
+		MaxBackoff:   c.MaxBackoff,
+		Timeout:      c.Timeout,
+	}
+}
+
+// viewsPolicy 浏览量批量写入策略
+func viewsPolicy(c config.ViewsConfig) services.ViewPolicy {
+	return services.ViewPolicy{
+		FlushInterval: c.FlushInterval,
+		MaxPending:    c.MaxPending,
+	}
+}
+
+// eventsRun 持续投递领域事件并发送 Webhook，同时按保留策略定期清理过期数据、批量写入浏览量，
+// 收到 SIGINT/SIGTERM 后写入剩余的浏览量并停止；
+// 运行期间配置文件修改或收到 SIGHUP 时热更新连接池和日志配置
+func eventsRun(app *cli, args []string) error {
+	if _, err := parseArgs(newFlagSet("events run", ""), args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dispatcher := services.NewEventDispatcher(eventsPolicy(app.cfg.Events))
+	webhooks := services.NewWebhookService(webhooksPolicy(app.cfg.Webhooks))
+	webhooks.Subscribe(dispatcher)
+	dispatcher.Start(ctx)
+	webhooks.Start(ctx)
+	services.NewRetentionService().Start(ctx, retentionPolicy(app.cfg.Retention))
+	viewsDone := services.NewViewCounter(viewsPolicy(app.cfg.Views)).Start(ctx)
+	if err := database.WatchConfig(ctx); err != nil {
+		log.Printf("⚠️  无法监听配置变更，本次运行不会热更新配置: %v", err)
+	}
+
+	log.Println("🚀 开始投递领域事件和 Webhook，按 Ctrl+C 停止")
+	<-ctx.Done()
+	// 数据库在本函数返回后关闭，须等剩余的浏览量写入完成再返回
+	<-viewsDone
+	return app.out.message("✅ 已停止")
+}
+
+// eventsList 按状态列出事件
+func eventsList(app *cli, args []string) error {
+	fs := newFlagSet("events list", "")
+	status := fs.String("status", models.OutboxDead, "事件状态：pending、delivered 或 dead")
+	page := fs.Int("page", 1, "页码")
+	pageSize := fs.Int("page-size", 20, "每页条数")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *status != models.OutboxPending && *status != models.OutboxDelivered && *status != models.OutboxDead {
+		return usagef("-status 无效: %q（可选 pending/delivered/dead）", *status)
+	}
+
+	events, total, err := services.NewEventDispatcher(eventsPolicy(app.cfg.Events)).ListEvents(*status, *page, *pageSize)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Total  int64                `json:"total"`
+		Page   int                  `json:"page"`
+		Events []models.OutboxEvent `json:"events"`
+	}{Total: total, Page: *page, Events: events}
+	return app.out.print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\t类型\t投递次数\t创建时间\t最近错误")
+		for _, e := range events {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n", e.ID, e.EventType, e.Attempts, e.CreatedAt.Format(time.DateTime), e.LastError)
+		}
+		fmt.Fprintf(w, "\n共 %d 条，第 %d 页\n", total, *page)
+	})
+}
+
+// eventsRequeue 将死信事件重新放回待投递队列
+func eventsRequeue(app *cli, args []string) error {
+	fs := newFlagSet("events requeue", "<ID>")
+	all := fs.Bool("all", false, "重新投递全部死信事件")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	dispatcher := services.NewEventDispatcher(eventsPolicy(app.cfg.Events))
+	if *all {
+		if len(positional) > 0 {
+			return usagef("-all 不能与事件ID同时使用")
+		}
+		n, err := dispatcher.RequeueDead()
+		if err != nil {
+			return err
+		}
+		return app.out.message("✅ 已重新投递 %d 个死信事件", n)
+	}
+
+	id, err := parseID("events requeue", positional)
+	if err != nil {
+		return err
+	}
+	if err := dispatcher.Requeue(id); err != nil {
+		return err
+	}
+	return app.out.message("✅ 事件 %d 已重新放回投递队列", id)
+}
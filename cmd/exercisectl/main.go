@@ -0,0 +1,202 @@
+// exercisectl 管理命令行工具：数据库迁移、用户管理、文章、领域事件与 Webhook、数据清理、示例数据和统计
+//
+// 用法：
+//
+//	exercisectl [全局参数] <命令> [子命令] [参数]
+//
+// 退出码：0 成功，1 执行失败，2 参数错误，3 对象不存在，4 数据库结构与模型不一致
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"exercise/config"
+	"exercise/database"
+	"exercise/services"
+)
+
+// 退出码
+const (
+	exitOK        = 0
+	exitFailure   = 1
+	exitUsage     = 2
+	exitNotFound  = 3
+	exitUnhealthy = 4
+)
+
+// usageError 命令行参数错误
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+// usagef 创建参数错误
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// command 子命令
+type command struct {
+	name    string
+	summary string
+	run     func(app *cli, args []string) error
+}
+
+// cli 命令执行环境
+type cli struct {
+	cfg *config.Config
+	out *printer
+}
+
+// commands 顶层命令
+var commands = []command{
+	{name: "db", summary: "数据库管理：migrate | rollback | status | reset | drop | backup | restore", run: runDB},
+	{name: "user", summary: "用户管理：create | get | search | deactivate | export | import | export-data | erase", run: runUser},
+	{name: "post", summary: "文章：view", run: runPost},
+	{name: "events", summary: "领域事件：run | list | requeue", run: runEvents},
+	{name: "webhook", summary: "Webhook 订阅：create | list | delete | deliveries | attempts | redeliver", run: runWebhook},
+	{name: "retention", summary: "数据清理（软删除数据、已投递事件、Webhook 投递记录）：purge", run: runRetention},
+	{name: "seed", summary: "生成示例数据（相同种子结果相同，可重复执行）", run: runSeed},
+	{name: "stats", summary: "用户统计", run: runStats},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 解析全局参数并执行命令，返回退出码
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("exercisectl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "table", "输出格式：table 或 json")
+	overrides := config.BindFlags(fs)
+	fs.Usage = func() { usage(fs, stderr) }
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "❌ 不支持的输出格式: %q（可选 table/json）\n", *output)
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "❌ 未知命令: %s\n", name)
+		fs.Usage()
+		return exitUsage
+	}
+
+	// 日志输出到标准错误，避免混入 JSON 结果
+	log.SetOutput(stderr)
+
+	cfg, err := config.Load(config.LoadOptions{Flags: overrides})
+	if err != nil {
+		fmt.Fprintf(stderr, "❌ 加载配置失败: %v\n", err)
+		return exitFailure
+	}
+	if err := database.InitDatabaseWithConfig(cfg); err != nil {
+		fmt.Fprintf(stderr, "❌ %v\n", err)
+		return exitFailure
+	}
+	defer database.CloseDatabase()
+
+	err = cmd.run(&cli{cfg: cfg, out: &printer{w: stdout, format: *output}}, fs.Args()[1:])
+	if err == nil {
+		return exitOK
+	}
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(stderr, "❌ %v\n", err)
+	}
+	return exitCode(err)
+}
+
+// exitCode 根据错误类型确定退出码
+func exitCode(err error) int {
+	var ue *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrEventNotFound),
+		errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound),
+		errors.Is(err, services.ErrPostNotFound):
+		return exitNotFound
+	case errors.Is(err, database.ErrSchemaOutdated):
+		return exitUnhealthy
+	}
+	return exitFailure
+}
+
+// usage 输出总体用法
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "用法: exercisectl [全局参数] <命令> [子命令] [参数]")
+	fmt.Fprintln(w, "\n命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\n全局参数（配置项参数覆盖配置文件和环境变量）:")
+	fs.PrintDefaults()
+}
+
+// subcommand 在命令组中查找并执行子命令
+func subcommand(app *cli, group string, subs map[string]func(*cli, []string) error, names []string, args []string) error {
+	if len(args) == 0 {
+		return usagef("用法: exercisectl %s <%s>", group, strings.Join(names, "|"))
+	}
+	fn, ok := subs[args[0]]
+	if !ok {
+		return usagef("未知子命令: %s %s（可选 %s）", group, args[0], strings.Join(names, "|"))
+	}
+	return fn(app, args[1:])
+}
+
+// newFlagSet 创建子命令参数集
+func newFlagSet(name, positional string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: exercisectl %s [参数] %s\n", name, positional)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs 解析参数，允许参数出现在位置参数之后，返回位置参数
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &usageError{msg: err.Error()}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"exercise/database"
+	"exercise/services"
+)
+
+func TestRunRejectsBadArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+		out  string
+	}{
+		{"没有命令", nil, exitUsage, "用法: exercisectl"},
+		{"未知命令", []string{"frobnicate"}, exitUsage, "未知命令: frobnicate"},
+		{"不支持的输出格式", []string{"-o", "xml", "stats"}, exitUsage, "不支持的输出格式"},
+		{"未知的全局参数", []string{"-no-such-flag", "stats"}, exitUsage, "no-such-flag"},
+		{"帮助", []string{"-h"}, exitOK, "retention"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := run(tt.args, &stdout, &stderr); got != tt.want {
+				t.Errorf("run(%v) = %d, want %d；stderr: %s", tt.args, got, tt.want, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.out) {
+				t.Errorf("stderr 不包含 %q: %s", tt.out, stderr.String())
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("出错时写入了标准输出: %s", stdout.String())
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{flag.ErrHelp, exitOK},
+		{usagef("用法: exercisectl user get <ID>"), exitUsage},
+		{fmt.Errorf("解析参数: %w", usagef("无效的ID")), exitUsage},
+		{services.ErrUserNotFound, exitNotFound},
+		{fmt.Errorf("重新投递失败: %w", services.ErrDeliveryNotFound), exitNotFound},
+		{services.ErrWebhookNotFound, exitNotFound},
+		{services.ErrEventNotFound, exitNotFound},
+		{services.ErrPostNotFound, exitNotFound},
+		{database.ErrSchemaOutdated, exitUnhealthy},
+		{errors.New("connection refused"), exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseArgsAllowsFlagsAfterPositional(t *testing.T) {
+	fs := newFlagSet("user deactivate", "<ID>")
+	by := fs.String("by", "", "操作人")
+	reason := fs.String("reason", "", "停用原因")
+
+	positional, err := parseArgs(fs, []string{"42", "-by", "admin", "extra", "-reason", "spam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(positional, []string{"42", "extra"}) || *by != "admin" || *reason != "spam" {
+		t.Errorf("parseArgs = %v, by=%q, reason=%q", positional, *by, *reason)
+	}
+
+	var ue *usageError
+	if _, err := parseArgs(newFlagSet("user get", "<ID>"), []string{"-bogus"}); !errors.As(err, &ue) {
+		t.Errorf("未知参数 = %v, want usageError", err)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	if id, err := parseID("user get", []string{"7"}); err != nil || id != 7 {
+		t.Errorf("parseID(7) = %d, %v", id, err)
+	}
+	for _, args := range [][]string{nil, {"0"}, {"-1"}, {"abc"}, {"1", "2"}} {
+		if _, err := parseID("user get", args); exitCode(err) != exitUsage {
+			t.Errorf("parseID(%v) = %v, want 参数错误", args, err)
+		}
+	}
+}
+
+func TestSubcommand(t *testing.T) {
+	called := ""
+	subs := map[string]func(*cli, []string) error{
+		"list": func(_ *cli, args []string) error {
+			called = strings.Join(append([]string{"list"}, args...), " ")
+			return nil
+		},
+	}
+	if err := subcommand(nil, "webhook", subs, []string{"list"}, []string{"list", "-page", "2"}); err != nil || called != "list -page 2" {
+		t.Errorf("subcommand 调用了 %q, err=%v", called, err)
+	}
+	for _, args := range [][]string{nil, {"purge"}} {
+		err := subcommand(nil, "webhook", subs, []string{"list"}, args)
+		if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "list") {
+			t.Errorf("subcommand(%v) = %v, want 列出可选子命令的参数错误", args, err)
+		}
+	}
+}
+
+func TestDestructiveCommandsRequireForce(t *testing.T) {
+	for name, fn := range map[string]func(*cli, []string) error{
+		"rollback": dbRollback,
+		"reset":    dbReset,
+		"drop":     dbDrop,
+	} {
+		err := fn(nil, []string{"-override-token", "secret"})
+		if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "--force") {
+			t.Errorf("db %s 未加 --force = %v, want 提示 --force 的参数错误", name, err)
+		}
+	}
+}
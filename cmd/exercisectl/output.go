@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// printer 按 table 或 json 格式输出结果
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// print 输出结果：json 格式直接序列化 v，table 格式由 table 函数写入以 \t 分隔的行
+func (p *printer) print(v interface{}, table func(w io.Writer)) error {
+	if p.format == "json" {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// message 输出一条提示信息
+func (p *printer) message(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	return p.print(map[string]string{"message": msg}, func(w io.Writer) {
+		fmt.Fprintln(w, msg)
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"exercise/services"
+)
+
+// runPost 文章命令
+func runPost(app *cli, args []string) error {
+	return subcommand(app, "post", map[string]func(*cli, []string) error{
+		"view": postView,
+	}, []string{"view"}, args)
+}
+
+// postView 查看已发布的文章并记录一次浏览
+func postView(app *cli, args []string) error {
+	id, err := parseID("post view", args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	views := services.NewViewCounter(viewsPolicy(app.cfg.Views))
+	done := views.Start(ctx)
+	post, err := services.NewPostService(views).ViewPost(id)
+	// 写入本次浏览后再返回，数据库在返回后关闭
+	cancel()
+	<-done
+	if err != nil {
+		return err
+	}
+
+	return app.out.print(post, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%d\n", post.ID)
+		fmt.Fprintf(w, "标题\t%s\n", post.Title)
+		fmt.Fprintf(w, "Slug\t%s\n", post.Slug)
+		if post.PublishedAt != nil {
+			fmt.Fprintf(w, "发布时间\t%s\n", post.PublishedAt.Format(time.DateTime))
+		}
+		fmt.Fprintf(w, "浏览量\t%d\n", post.Views)
+		fmt.Fprintf(w, "\n%s\n", post.Content)
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"exercise/config"
+	"exercise/services"
+)
+
+// runRetention 数据清理命令
+func runRetention(app *cli, args []string) error {
+	return subcommand(app, "retention", map[string]func(*cli, []string) error{
+		"purge": retentionPurge,
+	}, []string{"purge"}, args)
+}
+
+// retentionPolicy 数据保留策略
+func retentionPolicy(c config.RetentionConfig) services.RetentionPolicy {
+	return services.RetentionPolicy{
+		Days:         c.SoftDeleteDays,
+		EventDays:    c.EventDays,
+		DeliveryDays: c.DeliveryDays,
+		Interval:     c.Interval,
+		BatchSize:    c.BatchSize,
+	}
+}
+
+// retentionPurge 立即执行一次清理，默认使用配置中的保留天数
+func retentionPurge(app *cli, args []string) error {
+	policy := retentionPolicy(app.cfg.Retention)
+	fs := newFlagSet("retention purge", "")
+	fs.IntVar(&policy.Days, "days", policy.Days, "彻底删除软删除超过该天数的用户和评论（0 表示不清理）")
+	fs.IntVar(&policy.EventDays, "event-days", policy.EventDays, "删除投递超过该天数的领域事件（0 表示不清理）")
+	fs.IntVar(&policy.DeliveryDays, "delivery-days", policy.DeliveryDays, "删除成功超过该天数的 Webhook 投递记录（0 表示不清理）")
+	fs.IntVar(&policy.BatchSize, "batch-size", policy.BatchSize, "每批删除的行数")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if policy.Days < 0 || policy.EventDays < 0 || policy.DeliveryDays < 0 || policy.BatchSize <= 0 {
+		return usagef("-days、-event-days、-delivery-days 不能为负数，-batch-size 须大于0")
+	}
+	if !policy.Enabled() {
+		return app.out.message("ℹ️ 保留天数均为 0，未执行清理")
+	}
+
+	report, err := services.NewRetentionService().PurgeExpired(policy)
+	if err != nil {
+		return err
+	}
+	return app.out.print(report, func(w io.Writer) {
+		fmt.Fprintln(w, "数据\t删除\t截止时间")
+		fmt.Fprintf(w, "用户\t%d\t%s\n", report.Users, cutoffLabel(report.Cutoff))
+		fmt.Fprintf(w, "评论\t%d\t%s\n", report.Comments, cutoffLabel(report.Cutoff))
+		fmt.Fprintf(w, "已投递事件\t%d\t%s\n", report.Events, cutoffLabel(report.EventsCutoff))
+		fmt.Fprintf(w, "Webhook 投递记录\t%d\t%s\n", report.Deliveries, cutoffLabel(report.DeliveriesCutoff))
+	})
+}
+
+// cutoffLabel 截止时间，未清理时为“不清理”
+func cutoffLabel(cutoff time.Time) string {
+	if cutoff.IsZero() {
+		return "不清理"
+	}
+	return cutoff.Format(time.DateTime)
+}
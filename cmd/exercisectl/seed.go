@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"exercise/seed"
+)
+
+// runSeed 生成示例数据（相同种子生成相同数据，可重复执行）
+func runSeed(app *cli, args []string) error {
+	opts := seed.DefaultOptions()
+	fs := newFlagSet("seed", "")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "随机数种子")
+	fs.IntVar(&opts.Users, "users", opts.Users, "用户数")
+	fs.IntVar(&opts.PostsPerUser, "posts", opts.PostsPerUser, "每个用户最多的文章数")
+	fs.IntVar(&opts.CommentsPerPost, "comments", opts.CommentsPerPost, "每篇文章最多的评论数")
+	fs.IntVar(&opts.Courses, "courses", opts.Courses, "课程数")
+	fs.IntVar(&opts.EnrollmentsPerUser, "enrollments", opts.EnrollmentsPerUser, "每个用户最多选修的课程数")
+	fs.IntVar(&opts.BatchSize, "batch-size", opts.BatchSize, "每批插入的行数")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if opts.Users <= 0 {
+		return usagef("-users 必须大于0")
+	}
+
+	report, err := seed.Run(opts)
+	if err != nil {
+		return fmt.Errorf("填充示例数据失败: %v", err)
+	}
+	return app.out.print(report, func(w io.Writer) {
+		fmt.Fprintf(w, "新增用户\t%d\n", report.Users)
+		fmt.Fprintf(w, "新增资料\t%d\n", report.Profiles)
+		fmt.Fprintf(w, "新增文章\t%d\n", report.Posts)
+		fmt.Fprintf(w, "新增评论\t%d\n", report.Comments)
+		fmt.Fprintf(w, "新增点赞\t%d\n", report.Likes)
+		fmt.Fprintf(w, "新增课程\t%d\n", report.Courses)
+		fmt.Fprintf(w, "新增任课\t%d\n", report.Teachers)
+		fmt.Fprintf(w, "新增选课\t%d\n", report.Enrollments)
+		fmt.Fprintf(w, "已存在跳过\t%d\n", report.Skipped)
+	})
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"exercise/models"
+	"exercise/repositories"
+	"exercise/services"
+	"exercise/storage"
+)
+
+// runUser 用户管理命令
+func runUser(app *cli, args []string) error {
+	return subcommand(app, "user", map[string]func(*cli, []string) error{
+		"create":      userCreate,
+		"get":         userGet,
+		"search":      userSearch,
+		"deactivate":  userDeactivate,
+		"export":      userExport,
+		"import":      userImport,
+		"export-data": userExportData,
+		"erase":       userErase,
+	}, []string{"create", "get", "search", "deactivate", "export", "import", "export-data", "erase"}, args)
+}
+
+// privacyService 按配置创建个人数据服务，文件存储不可用时导出包不含头像
+func privacyService(app *cli) services.PrivacyService {
+	store, err := storage.New(app.cfg.Storage)
+	if err != nil {
+		log.Printf("⚠️  文件存储不可用，导出包将不含头像: %v", err)
+		store = nil
+	}
+	return services.NewPrivacyService(store, services.PrivacyPolicy{KeepContent: app.cfg.Privacy.KeepContent})
+}
+
+// userExportData 将用户的个人数据导出为zip包
+func userExportData(app *cli, args []string) error {
+	fs := newFlagSet("user export-data", "<ID>")
+	file := fs.String("file", "", "输出的zip文件（必填）")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID("user export-data", positional)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		return usagef("user export-data 需要 -file")
+	}
+
+	f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("创建 %s 失败: %v", *file, err)
+	}
+	manifest, err := privacyService(app).ExportUserData(id, f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("写入 %s 失败: %v", *file, closeErr)
+	}
+	if err != nil {
+		os.Remove(*file)
+		return err
+	}
+	return app.out.print(manifest, func(w io.Writer) {
+		fmt.Fprintln(w, "文件\t大小\tSHA256")
+		for _, f := range manifest.Files {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", f.Name, f.Size, f.SHA256)
+		}
+		for _, note := range manifest.Notes {
+			fmt.Fprintf(w, "\nℹ️ %s", note)
+		}
+		fmt.Fprintf(w, "\n✅ 已导出到 %s\n", *file)
+	})
+}
+
+// userErase 擦除用户的个人数据，默认是否保留文章和评论内容由 privacy.keep_content 决定
+func userErase(app *cli, args []string) error {
+	fs := newFlagSet("user erase", "<ID>")
+	var opts repositories.ErasureOptions
+	fs.BoolFunc("keep-content", "保留文章和评论内容，只替换其中的个人信息（默认取 privacy.keep_content）", func(v string) error {
+		keep, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		opts.KeepContent = &keep
+		return nil
+	})
+	fs.StringVar(&opts.Actor, "by", "exercisectl", "操作人")
+	force := fs.Bool("force", false, "确认执行：擦除后无法恢复")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID("user erase", positional)
+	if err != nil {
+		return err
+	}
+	if !*force {
+		return usagef("user erase 将不可恢复地擦除用户 %d 的个人数据；确认执行请加 --force", id)
+	}
+
+	result, err := privacyService(app).EraseUser(id, opts)
+	if err != nil {
+		return err
+	}
+	return app.out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "用户\t%d\n", result.UserID)
+		fmt.Fprintf(w, "匿名化文章\t%d\n", result.PostsAnonymized)
+		fmt.Fprintf(w, "匿名化评论\t%d\n", result.CommentsAnonymized)
+		fmt.Fprintf(w, "删除资料\t%v\n", result.ProfileRemoved)
+		fmt.Fprintf(w, "删除选课记录\t%d\n", result.EnrollmentsRemoved)
+	})
+}
+
+// userCreate 注册用户
+func userCreate(app *cli, args []string) error {
+	fs := newFlagSet("user create", "")
+	user := &models.User{IsActive: true}
+	fs.StringVar(&user.Username, "username", "", "用户名（必填）")
+	fs.StringVar(&user.Email, "email", "", "邮箱（必填）")
+	fs.StringVar(&user.Password, "password", "", "密码（必填）")
+	fs.IntVar(&user.Age, "age", 0, "年龄（必填）")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if user.Username == "" || user.Email == "" || user.Password == "" {
+		return usagef("user create 需要 -username、-email 和 -password")
+	}
+
+	if err := services.NewUserService().Register(user); err != nil {
+		return fmt.Errorf("创建用户失败: %w", err)
+	}
+	return printUser(app, user)
+}
+
+// userGet 查看用户
+func userGet(app *cli, args []string) error {
+	id, err := parseID("user get", args)
+	if err != nil {
+		return err
+	}
+	user, err := services.NewUserService().GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	return printUser(app, user)
+}
+
+// userSearch 搜索用户
+func userSearch(app *cli, args []string) error {
+	fs := newFlagSet("user search", "[关键字]")
+	state := fs.String("active", "active", "账户状态：active、inactive 或 any")
+	page := fs.Int("page", 1, "页码")
+	pageSize := fs.Int("page-size", 20, "每页条数")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return usagef("user search 只接受一个关键字")
+	}
+	active, ok := map[string]repositories.ActiveFilter{
+		"active":   repositories.ActiveOnly,
+		"inactive": repositories.InactiveOnly,
+		"any":      repositories.AnyActiveState,
+	}[*state]
+	if !ok {
+		return usagef("-active 无效: %q（可选 active/inactive/any）", *state)
+	}
+	keyword := ""
+	if len(positional) == 1 {
+		keyword = positional[0]
+	}
+
+	users, total, err := services.NewUserService().SearchUsers(keyword, active, *page, *pageSize)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Total int64         `json:"total"`
+		Page  int           `json:"page"`
+		Users []models.User `json:"users"`
+	}{Total: total, Page: *page, Users: users}
+	return app.out.print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\t用户名\t邮箱\t年龄\t状态\t注册时间")
+		for _, u := range users {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n", u.ID, u.Username, u.Email, u.Age, activeLabel(u.IsActive), u.CreatedAt.Format(time.DateTime))
+		}
+		fmt.Fprintf(w, "\n共 %d 条，第 %d 页\n", total, *page)
+	})
+}
+
+// userDeactivate 停用账户
+func userDeactivate(app *cli, args []string) error {
+	fs := newFlagSet("user deactivate", "<ID>")
+	by := fs.String("by", "exercisectl", "操作人")
+	reason := fs.String("reason", "", "停用原因")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID("user deactivate", positional)
+	if err != nil {
+		return err
+	}
+
+	if err := services.NewUserService().DeactivateAccount(id, *by, *reason); err != nil {
+		return err
+	}
+	return app.out.message("✅ 用户 %d 已停用", id)
+}
+
+// userExport 导出全部用户为JSON，写入文件或标准输出
+func userExport(app *cli, args []string) error {
+	fs := newFlagSet("user export", "")
+	file := fs.String("file", "", "输出文件，为空时写入标准输出")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	data, err := services.NewUserService().ExportUsers()
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		_, err = fmt.Fprintln(app.out.w, string(data))
+		return err
+	}
+	if err := os.WriteFile(*file, data, 0o600); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", *file, err)
+	}
+	return app.out.message("✅ 已导出到 %s", *file)
+}
+
+// userImport 从JSON文件导入用户，- 表示标准输入
+func userImport(app *cli, args []string) error {
+	positional, err := parseArgs(newFlagSet("user import", "<文件|->"), args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usagef("用法: exercisectl user import <文件|->")
+	}
+
+	var data []byte
+	if positional[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(positional[0])
+	}
+	if err != nil {
+		return fmt.Errorf("读取导入文件失败: %v", err)
+	}
+
+	count, importErr := services.NewUserService().ImportUsers(data)
+	result := map[string]int{"imported": count}
+	err = app.out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "已导入 %d 个用户\n", count)
+	})
+	if importErr != nil {
+		return importErr
+	}
+	return err
+}
+
+// runStats 输出用户统计
+func runStats(app *cli, args []string) error {
+	if _, err := parseArgs(newFlagSet("stats", ""), args); err != nil {
+		return err
+	}
+	stats, err := services.NewUserService().GetUserStats()
+	if err != nil {
+		return err
+	}
+	return app.out.print(stats, func(w io.Writer) {
+		fmt.Fprintf(w, "总用户数\t%d\n", stats.TotalUsers)
+		fmt.Fprintf(w, "活跃用户\t%d\n", stats.ActiveUsers)
+		fmt.Fprintf(w, "停用用户\t%d\n", stats.InactiveUsers)
+		fmt.Fprintf(w, "今日注册\t%d\n", stats.TodayRegisters)
+		fmt.Fprintf(w, "平均年龄\t%.2f\n", stats.AvgAge)
+		if len(stats.TopDomains) > 0 {
+			fmt.Fprintf(w, "常用邮箱域名\t%v\n", stats.TopDomains)
+		}
+	})
+}
+
+// printUser 输出单个用户
+func printUser(app *cli, user *models.User) error {
+	return app.out.print(user, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%d\n", user.ID)
+		fmt.Fprintf(w, "用户名\t%s\n", user.Username)
+		fmt.Fprintf(w, "邮箱\t%s\n", user.Email)
+		fmt.Fprintf(w, "年龄\t%d\n", user.Age)
+		fmt.Fprintf(w, "状态\t%s\n", activeLabel(user.IsActive))
+		if !user.IsActive && user.DeactivatedAt != nil {
+			fmt.Fprintf(w, "停用时间\t%s（%s：%s）\n", user.DeactivatedAt.Format(time.DateTime), user.DeactivatedBy, user.DeactivationReason)
+		}
+		fmt.Fprintf(w, "注册时间\t%s\n", user.CreatedAt.Format(time.DateTime))
+		if user.Profile != nil {
+			fmt.Fprintf(w, "姓名\t%s\n", user.Profile.FullName())
+			fmt.Fprintf(w, "简介\t%s\n", user.Profile.Bio)
+		}
+		if len(user.Posts) > 0 {
+			fmt.Fprintf(w, "文章数\t%d\n", len(user.Posts))
+		}
+	})
+}
+
+// parseID 解析唯一的位置参数为用户ID
+func parseID(name string, args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, usagef("用法: exercisectl %s <ID>", name)
+	}
+	id, err := strconv.ParseUint(args[0], 10, 0)
+	if err != nil || id == 0 {
+		return 0, usagef("无效的ID: %q", args[0])
+	}
+	return uint(id), nil
+}
+
+// activeLabel 账户状态文本
+func activeLabel(active bool) string {
+	if active {
+		return "活跃"
+	}
+	return "停用"
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"exercise/models"
+	"exercise/services"
+)
+
+// runWebhook Webhook 订阅管理命令
+func runWebhook(app *cli, args []string) error {
+	return subcommand(app, "webhook", map[string]func(*cli, []string) error{
+		"create":     webhookCreate,
+		"list":       webhookList,
+		"delete":     webhookDelete,
+		"deliveries": webhookDeliveries,
+		"attempts":   webhookAttempts,
+		"redeliver":  webhookRedeliver,
+	}, []string{"create", "list", "delete", "deliveries", "attempts", "redeliver"}, args)
+}
+
+// webhookCreate 创建 Webhook 订阅，签名密钥只在创建时输出一次
+func webhookCreate(app *cli, args []string) error {
+	fs := newFlagSet("webhook create", "")
+	webhook := &models.Webhook{}
+	fs.StringVar(&webhook.URL, "url", "", "接收地址（必填）")
+	events := fs.String("events", "", "订阅的事件类型，逗号分隔（必填，可选 "+strings.Join(models.EventTypes(), "/")+"）")
+	fs.StringVar(&webhook.Secret, "secret", "", "签名密钥（默认自动生成）")
+	fs.StringVar(&webhook.Description, "description", "", "备注")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if webhook.URL == "" || *events == "" {
+		return usagef("webhook create 需要 -url 和 -events")
+	}
+	for _, t := range strings.Split(*events, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			webhook.EventTypes = append(webhook.EventTypes, t)
+		}
+	}
+
+	if err := services.NewWebhookService(webhooksPolicy(app.cfg.Webhooks)).CreateWebhook(webhook); err != nil {
+		return err
+	}
+	result := struct {
+		*models.Webhook
+		Secret string `json:"secret"`
+	}{Webhook: webhook, Secret: webhook.Secret}
+	return app.out.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%d\n", webhook.ID)
+		fmt.Fprintf(w, "地址\t%s\n", webhook.URL)
+		fmt.Fprintf(w, "事件\t%s\n", strings.Join(webhook.EventTypes, ","))
+		fmt.Fprintf(w, "密钥\t%s\n", webhook.Secret)
+		fmt.Fprintln(w, "\n⚠️  密钥只显示这一次，请妥善保存")
+	})
+}
+
+// webhookList 列出 Webhook 订阅
+func webhookList(app *cli, args []string) error {
+	if _, err := parseArgs(newFlagSet("webhook list", ""), args); err != nil {
+		return err
+	}
+	webhooks, err := services.NewWebhookService(webhooksPolicy(app.cfg.Webhooks)).ListWebhooks()
+	if err != nil {
+		return err
+	}
+	return app.out.print(webhooks, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\t地址\t事件\t状态\t备注")
+		for _, wh := range webhooks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", wh.ID, wh.URL, strings.Join(wh.EventTypes, ","), activeLabel(wh.IsActive), wh.Description)
+		}
+	})
+}
+
+// webhookDelete 删除 Webhook 订阅及其投递记录
+func webhookDelete(app *cli, args []string) error {
+	id, err := parseID("webhook delete", args)
+	if err != nil {
+		return err
+	}
+	if err := services.NewWebhookService(webhooksPolicy(app.cfg.Webhooks)).DeleteWebhook(id); err != nil {
+		return err
+	}
+	return app.out.message("✅ Webhook %d 已删除", id)
+}
+
+// webhookDeliveries 查看投递日志
+func webhookDeliveries(app *cli, args []string) error {
+	fs := newFlagSet("webhook deliveries", "")
+	webhookID := fs.Uint("webhook", 0, "只看该 Webhook 的投递")
+	status := fs.String("status", "", "投递状态：pending、succeeded 或 failed（默认全部）")
+	page := fs.Int("page", 1, "页码")
+	pageSize := fs.Int("page-size", 20, "每页条数")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	switch *status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		return usagef("-status 无效: %q（可选 pending/succeeded/failed）", *status)
+	}
+
+	deliveries, total, err := services.NewWebhookService(webhooksPolicy(app.cfg.Webhooks)).
+		ListDeliveries(*webhookID, *status, *page, *pageSize)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Total      int64                    `json:"total"`
+		Page       int                      `json:"page"`
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}{Total: total, Page: *page, Deliveries: deliveries}
+	return app.out.print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tWebhook\t事件\t状态\t次数\t响应码\t耗时\t创建时间\t最近错误")
+		for _, d := range deliveries {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%d\t%d\t%dms\t%s\t%s\n", d.ID, d.WebhookID, d.EventType, d.Status,
+				d.Attempts, d.ResponseCode, d.DurationMs, d.CreatedAt.Format(time.DateTime), d.LastError)
+		}
+		fmt.Fprintf(w, "\n共 %d 条，第 %d 页\n", total, *page)
+	})
+}
+
+// webhookAttempts 查看投递的每次请求记录
+func webhookAttempts(app *cli, args []string) error {
+	id, err := parseID("webhook attempts", args)
+	if err != nil {
+		return err
+	}
+	attempts, err := services.NewWebhookService(webhooksPolicy(app.cfg.Webhooks)).ListAttempts(id)
+	if err != nil {
+		return err
+	}
+	return app.out.print(attempts, func(w io.Writer) {
+		fmt.Fprintln(w, "次数	响应码	耗时	时间	错误")
+		for _, a := range attempts {
+			fmt.Fprintf(w, "%d\t%d\t%dms\t%s\t%s\n", a.Attempt, a.ResponseCode, a.DurationMs, a.CreatedAt.Format(time.DateTime), a.Error)
+		}
+	})
+}
+
+// webhookRedeliver 手动重新投递
+func webhookRedeliver(app *cli, args []string) error {
+	id, err := parseID("webhook redeliver", args)
+	if err != nil {
+		return err
+	}
+	if err := services.NewWebhookService(webhooksPolicy(app.cfg.Webhooks)).Redeliver(id); err != nil {
+		return err
+	}
+	return app.out.message("✅ 投递 %d 已重新放回发送队列", id)
+}
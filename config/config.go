@@ -2,77 +2,189 @@ package config
 
 import (
 	"fmt"
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
+	"reflect"
+	"time"
 )
 
 // Config 存储应用程序配置
+// 加载优先级（后者覆盖前者）：默认值 < 配置文件(YAML/TOML) < .env文件 < 环境变量 < 命令行参数
 type Config struct {
-	DBHost            string
-	DBPort            int
-	DBUser            string
-	DBPassword        string
-	DBName            string
-	DBMaxOpenConns    int
-	DBMaxIdleConns    int
-	DBConnMaxLifetime int
-	DBLogMode         bool
-	DBLogLevel        string
+	App       AppConfig       `yaml:"app" toml:"app"`
+	DB        DBConfig        `yaml:"db" toml:"db"`
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	Retention RetentionConfig `yaml:"retention" toml:"retention"`
+	Privacy   PrivacyConfig   `yaml:"privacy" toml:"privacy"`
+	Storage   StorageConfig   `yaml:"storage" toml:"storage"`
+	Avatar    AvatarConfig    `yaml:"avatar" toml:"avatar"`
+	Views     ViewsConfig     `yaml:"views" toml:"views"`
+	Events    EventsConfig    `yaml:"events" toml:"events"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks" toml:"webhooks"`
+
+	sources map[string]string // 每个配置项的来源，用于调试输出
+	file    string            // 实际读取的配置文件，未使用配置文件时为空
+	options LoadOptions       // 加载时使用的选项，重新加载时复用
+}
+
+// 运行环境
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// AppConfig 应用配置
+type AppConfig struct {
+	Env           string `yaml:"env" toml:"env" env:"APP_ENV" default:"development"`                          // 运行环境：development/test/staging/production
+	OverrideToken string `yaml:"override_token" toml:"override_token" env:"APP_OVERRIDE_TOKEN" secret:"true"` // 生产环境执行破坏性操作时须提供的令牌，为空表示禁止
+}
+
+// IsProduction 是否为生产环境
+func (c AppConfig) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// DBConfig 数据库配置
+type DBConfig struct {
+	Host            string        `yaml:"host" toml:"host" env:"DB_HOST" default:"localhost"`
+	Port            int           `yaml:"port" toml:"port" env:"DB_PORT" default:"3306"`
+	User            string        `yaml:"user" toml:"user" env:"DB_USER" default:"root"`
+	Password        string        `yaml:"password" toml:"password" env:"DB_PASSWORD" default:"password" secret:"true"`
+	Name            string        `yaml:"name" toml:"name" env:"DB_NAME" default:"gorm_learning_db"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" default:"100"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" default:"10"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" default:"300"` // 纯数字按秒计
+	LogMode         bool          `yaml:"log_mode" toml:"log_mode" env:"DB_LOG_MODE" default:"true"`
+	LogLevel        string        `yaml:"log_level" toml:"log_level" env:"DB_LOG_LEVEL" default:"info"`
+	SlowThreshold   time.Duration `yaml:"slow_threshold" toml:"slow_threshold" env:"DB_SLOW_THRESHOLD" default:"200ms"` // 0 表示不检测慢查询
+	LogRedact       string        `yaml:"log_redact" toml:"log_redact" env:"DB_LOG_REDACT" default:"password"`          // 日志中隐藏参数值的列，逗号分隔；* 表示隐藏全部参数
+	DropAllowlist   string        `yaml:"drop_allowlist" toml:"drop_allowlist" env:"DB_DROP_ALLOWLIST"`                 // 允许删除全部表的数据库名，逗号分隔，支持 * 通配；默认为空，即拒绝所有破坏性操作
+	BackupDir       string        `yaml:"backup_dir" toml:"backup_dir" env:"DB_BACKUP_DIR" default:"./backups"`         // 删除前自动备份的存放目录
+}
+
+// ServerConfig 服务配置
+type ServerConfig struct {
+	Host         string        `yaml:"host" toml:"host" env:"SERVER_HOST" default:"0.0.0.0"`
+	Port         int           `yaml:"port" toml:"port" env:"SERVER_PORT" default:"8080"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"15s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"15s"`
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	JWTSecret         string        `yaml:"jwt_secret" toml:"jwt_secret" env:"AUTH_JWT_SECRET" secret:"true"`
+	TokenTTL          time.Duration `yaml:"token_ttl" toml:"token_ttl" env:"AUTH_TOKEN_TTL" default:"24h"`
+	PasswordMinLength int           `yaml:"password_min_length" toml:"password_min_length" env:"AUTH_PASSWORD_MIN_LENGTH" default:"8"`
+}
+
+// MailConfig 邮件配置（Host为空表示不发送邮件）
+type MailConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"MAIL_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"MAIL_PORT" default:"587"`
+	Username string `yaml:"username" toml:"username" env:"MAIL_USERNAME"`
+	Password string `yaml:"password" toml:"password" env:"MAIL_PASSWORD" secret:"true"`
+	From     string `yaml:"from" toml:"from" env:"MAIL_FROM"`
+}
+
+// RetentionConfig 数据保留策略：软删除的用户和评论、已投递的领域事件、成功的 Webhook 投递记录各自设置保留天数
+type RetentionConfig struct {
+	SoftDeleteDays int           `yaml:"soft_delete_days" toml:"soft_delete_days" env:"RETENTION_SOFT_DELETE_DAYS" default:"30"` // 软删除超过该天数后彻底删除，0 表示不清理
+	EventDays      int           `yaml:"event_days" toml:"event_days" env:"RETENTION_EVENT_DAYS" default:"7"`                    // 已投递的领域事件保留天数，0 表示不清理
+	DeliveryDays   int           `yaml:"delivery_days" toml:"delivery_days" env:"RETENTION_DELIVERY_DAYS" default:"30"`          // 成功的 Webhook 投递记录保留天数，0 表示不清理
+	Interval       time.Duration `yaml:"interval" toml:"interval" env:"RETENTION_INTERVAL" default:"24h"`                        // 清理任务执行间隔
+	BatchSize      int           `yaml:"batch_size" toml:"batch_size" env:"RETENTION_BATCH_SIZE" default:"500"`                  // 每批删除的行数
+}
+
+// PrivacyConfig 个人数据处理配置
+type PrivacyConfig struct {
+	KeepContent bool `yaml:"keep_content" toml:"keep_content" env:"PRIVACY_KEEP_CONTENT" default:"true"` // 擦除用户时保留其文章和评论内容（仅去除其中的个人信息）
 }
 
+// StorageConfig 文件存储配置
+type StorageConfig struct {
+	Driver    string          `yaml:"driver" toml:"driver" env:"STORAGE_DRIVER" default:"local"`                                     // local 或 s3
+	Dir       string          `yaml:"dir" toml:"dir" env:"STORAGE_DIR" default:"./uploads"`                                          // local：存储目录
+	PublicURL string          `yaml:"public_url" toml:"public_url" env:"STORAGE_PUBLIC_URL" default:"http://localhost:8080/uploads"` // local：对外访问地址前缀
+	S3        S3StorageConfig `yaml:"s3" toml:"s3"`
+}
+
+// S3StorageConfig S3兼容存储配置（AWS S3、MinIO等）
+type S3StorageConfig struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint" env:"STORAGE_S3_ENDPOINT"` // 如 http://localhost:9000，为空时使用 AWS 地址
+	Region    string `yaml:"region" toml:"region" env:"STORAGE_S3_REGION" default:"us-east-1"`
+	Bucket    string `yaml:"bucket" toml:"bucket" env:"STORAGE_S3_BUCKET"`
+	AccessKey string `yaml:"access_key" toml:"access_key" env:"STORAGE_S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key" env:"STORAGE_S3_SECRET_KEY" secret:"true"`
+	PathStyle bool   `yaml:"path_style" toml:"path_style" env:"STORAGE_S3_PATH_STYLE" default:"true"` // 使用 endpoint/bucket/key 形式的地址（MinIO 需要）
+	PublicURL string `yaml:"public_url" toml:"public_url" env:"STORAGE_S3_PUBLIC_URL"`                // 对外访问地址前缀（如CDN），为空时使用桶地址
+}
+
+// AvatarConfig 头像上传配置
+type AvatarConfig struct {
+	MaxBytes  int `yaml:"max_bytes" toml:"max_bytes" env:"AVATAR_MAX_BYTES" default:"5242880"`     // 上传文件大小上限（字节）
+	MaxPixels int `yaml:"max_pixels" toml:"max_pixels" env:"AVATAR_MAX_PIXELS" default:"25000000"` // 图片像素数上限，防止解码超大图片
+}
+
+// ViewsConfig 文章浏览量写入配置（内存中累计后批量写入）
+type ViewsConfig struct {
+	FlushInterval time.Duration `yaml:"flush_interval" toml:"flush_interval" env:"VIEWS_FLUSH_INTERVAL" default:"5s"` // 定期写入数据库的间隔
+	MaxPending    int           `yaml:"max_pending" toml:"max_pending" env:"VIEWS_MAX_PENDING" default:"1000"`        // 累计的文章数达到该值时提前写入
+}
+
+// EventsConfig 领域事件投递配置（outbox 中的事件投递给进程内的订阅者）
+type EventsConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"EVENTS_POLL_INTERVAL" default:"1s"` // 轮询 outbox 的间隔
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size" env:"EVENTS_BATCH_SIZE" default:"50"`          // 每次领取的事件数
+	MaxAttempts  int           `yaml:"max_attempts" toml:"max_attempts" env:"EVENTS_MAX_ATTEMPTS" default:"8"`     // 最多投递次数，用尽后移入死信
+	RetryBackoff time.Duration `yaml:"retry_backoff" toml:"retry_backoff" env:"EVENTS_RETRY_BACKOFF" default:"5s"` // 第一次重试前的等待时间，之后每次加倍
+	MaxBackoff   time.Duration `yaml:"max_backoff" toml:"max_backoff" env:"EVENTS_MAX_BACKOFF" default:"10m"`      // 重试等待时间的上限
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" env:"EVENTS_TIMEOUT" default:"10s"`                  // 单个事件的处理时限
+}
+
+// WebhooksConfig Webhook 发送配置
+type WebhooksConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" default:"1s"`  // 轮询待发送投递的间隔
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size" env:"WEBHOOKS_BATCH_SIZE" default:"20"`           // 每次领取的投递数
+	MaxAttempts  int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" default:"10"`     // 最多发送次数，用尽后标记为失败
+	RetryBackoff time.Duration `yaml:"retry_backoff" toml:"retry_backoff" env:"WEBHOOKS_RETRY_BACKOFF" default:"10s"` // 第一次重试前的等待时间，之后每次加倍
+	MaxBackoff   time.Duration `yaml:"max_backoff" toml:"max_backoff" env:"WEBHOOKS_MAX_BACKOFF" default:"1h"`        // 重试等待时间的上限
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOKS_TIMEOUT" default:"10s"`                   // 单个请求的时限
+}
+
+// LoadConfig 使用默认选项加载配置（不解析命令行参数）
 func LoadConfig() (*Config, error) {
-	// 加载.env文件
-	err := godotenv.Load()
-	if err != nil {
-		return nil, fmt.Errorf("加载.env文件失败: %v", err)
-	}
-
-	// 解析端口
-	dbPort, err := strconv.Atoi(getEnv("DB_PORT", "3306"))
-	if err != nil {
-		return nil, fmt.Errorf("解析DB_PORT失败: %v", err)
-	}
-
-	// 解析连接池配置
-	maxOpenConns, err := strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "100"))
-	if err != nil {
-		return nil, fmt.Errorf("解析DB_MAX_OPEN_CONNS失败: %v", err)
-	}
-
-	maxIdleConns, err := strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "10"))
-	if err != nil {
-		return nil, fmt.Errorf("解析DB_MAX_IDLE_CONNS失败: %v", err)
-	}
-
-	connMaxLifetime, err := strconv.Atoi(getEnv("DB_CONN_MAX_LIFETIME", "300"))
-	if err != nil {
-		return nil, fmt.Errorf("解析DB_CONN_MAX_LIFETIME失败: %v", err)
-	}
-
-	// 解析日志模式
-	logMode := getEnv("DB_LOG_MODE", "true") == "true"
-
-	return &Config{
-		DBHost:            getEnv("DB_HOST", "localhost"),
-		DBPort:            dbPort,
-		DBUser:            getEnv("DB_USER", "root"),
-		DBPassword:        getEnv("DB_PASSWORD", "password"),
-		DBName:            getEnv("DB_NAME", "gorm_learning_db"),
-		DBMaxOpenConns:    maxOpenConns,
-		DBMaxIdleConns:    maxIdleConns,
-		DBConnMaxLifetime: connMaxLifetime,
-		DBLogMode:         logMode,
-		DBLogLevel:        getEnv("DB_LOG_LEVEL", "info"),
-	}, nil
-}
-
-// getEnv 获取环境变量，如果不存在则使用默认值
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
+	return Load(LoadOptions{})
+}
+
+// Source 返回配置项（如 db.host）的来源
+func (c *Config) Source(key string) string {
+	return c.sources[key]
+}
+
+// File 返回实际读取的配置文件路径，未使用配置文件时为空
+func (c *Config) File() string {
+	return c.file
+}
+
+// Changes 返回与另一份配置相比取值不同的配置项（如 db.host）
+func (c *Config) Changes(other *Config) []string {
+	before, after := c.values(), other.values()
+	var keys []string
+	walk(reflect.ValueOf(c).Elem(), "", func(_ reflect.StructField, _ reflect.Value, key string) {
+		if before[key] != after[key] {
+			keys = append(keys, key)
+		}
+	})
+	return keys
+}
+
+// values 返回全部配置项的字符串取值
+func (c *Config) values() map[string]string {
+	values := make(map[string]string)
+	walk(reflect.ValueOf(c).Elem(), "", func(_ reflect.StructField, v reflect.Value, key string) {
+		values[key] = fmt.Sprint(v.Interface())
+	})
+	return values
 }
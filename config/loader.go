@@ -0,0 +1,319 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置来源
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceDotEnv  = "dotenv"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// defaultConfigFiles 未指定配置文件时依次查找的文件
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// LoadOptions 配置加载选项
+type LoadOptions struct {
+	ConfigFile string         // 配置文件路径，为空时读取 CONFIG_FILE 或查找默认文件
+	EnvFile    string         // .env 文件路径，为空时使用 .env（不存在则跳过）
+	Flags      *FlagOverrides // 命令行参数，由 BindFlags 注册
+}
+
+// FlagOverrides 命令行参数中显式设置的配置项
+type FlagOverrides struct {
+	configFile string
+	values     map[string]*flagValue // 按环境变量名索引
+}
+
+// flagValue 记录命令行参数是否被显式设置
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (f *flagValue) String() string   { return f.value }
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+func (f *flagValue) Set(v string) error {
+	f.value, f.set = v, true
+	return nil
+}
+
+// BindFlags 在FlagSet上注册 -config 及每个配置项对应的参数（如 DB_HOST 对应 -db-host）
+func BindFlags(fs *flag.FlagSet) *FlagOverrides {
+	overrides := &FlagOverrides{values: make(map[string]*flagValue)}
+	fs.StringVar(&overrides.configFile, "config", "", "配置文件路径（YAML或TOML）")
+
+	walk(reflect.ValueOf(&Config{}).Elem(), "", func(f reflect.StructField, v reflect.Value, key string) {
+		env := f.Tag.Get("env")
+		fv := &flagValue{isBool: v.Kind() == reflect.Bool}
+		overrides.values[env] = fv
+		fs.Var(fv, flagName(env), fmt.Sprintf("%s（环境变量 %s）", key, env))
+	})
+
+	return overrides
+}
+
+// flagName 由环境变量名生成命令行参数名
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// Load 按优先级加载配置并校验
+func Load(opts LoadOptions) (*Config, error) {
+	cfg := &Config{sources: make(map[string]string), options: opts}
+	var errs []error
+
+	// 1. 默认值
+	walk(reflect.ValueOf(cfg).Elem(), "", func(f reflect.StructField, v reflect.Value, key string) {
+		if def, ok := f.Tag.Lookup("default"); ok {
+			if err := setValue(v, def); err != nil {
+				errs = append(errs, fmt.Errorf("%s 默认值无效: %v", key, err))
+			}
+		}
+		cfg.sources[key] = SourceDefault
+	})
+
+	// 2. 配置文件
+	path := opts.ConfigFile
+	if opts.Flags != nil && opts.Flags.configFile != "" {
+		path = opts.Flags.configFile
+	}
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if err := cfg.applyFile(path); err != nil {
+		return nil, err
+	}
+
+	// 3. .env 文件（不修改进程环境变量）
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = ".env"
+	}
+	dotenv, err := godotenv.Read(envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("加载%s文件失败: %v", envFile, err)
+	}
+	errs = append(errs, cfg.applyLookup(SourceDotEnv, nonEmpty(func(key string) (string, bool) {
+		v, ok := dotenv[key]
+		return v, ok
+	}))...)
+
+	// 4. 环境变量
+	errs = append(errs, cfg.applyLookup(SourceEnv, nonEmpty(os.LookupEnv))...)
+
+	// 5. 命令行参数
+	if opts.Flags != nil {
+		errs = append(errs, cfg.applyLookup(SourceFlag, func(key string) (string, bool) {
+			if fv, ok := opts.Flags.values[key]; ok && fv.set {
+				if fv.isBool && fv.value == "" {
+					return "true", true
+				}
+				return fv.value, true
+			}
+			return "", false
+		})...)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("解析配置失败: %w", errors.Join(errs...))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Reload 使用首次加载时的选项重新加载配置
+func (c *Config) Reload() (*Config, error) {
+	return Load(c.options)
+}
+
+// applyFile 读取YAML或TOML配置文件，path为空时查找默认文件
+func (c *Config) applyFile(path string) error {
+	explicit := path != ""
+	if !explicit {
+		for _, name := range defaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件 %s 失败: %v", path, err)
+	}
+	c.file = path
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("不支持的配置文件格式: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", raw, values)
+
+	var errs []error
+	known := make(map[string]bool)
+	walk(reflect.ValueOf(c).Elem(), "", func(f reflect.StructField, v reflect.Value, key string) {
+		known[key] = true
+		if value, ok := values[key]; ok {
+			if err := setValue(v, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s 无效: %v", path, key, err))
+				return
+			}
+			c.sources[key] = SourceFile
+		}
+	})
+	for key := range values {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("%s: 未知配置项 %s", path, key))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("解析配置失败: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// applyLookup 按环境变量名读取配置；敏感项支持 <NAME>_FILE 指向的文件
+func (c *Config) applyLookup(source string, lookup func(string) (string, bool)) []error {
+	var errs []error
+	walk(reflect.ValueOf(c).Elem(), "", func(f reflect.StructField, v reflect.Value, key string) {
+		env := f.Tag.Get("env")
+		value, ok := lookup(env)
+		if f.Tag.Get("secret") == "true" {
+			if file, hasFile := lookup(env + "_FILE"); hasFile && file != "" {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("读取 %s_FILE 失败: %v", env, err))
+					return
+				}
+				value, ok = strings.TrimRight(string(data), "\r\n"), true
+			}
+		}
+		if !ok {
+			return
+		}
+		if err := setValue(v, value); err != nil {
+			errs = append(errs, fmt.Errorf("解析%s失败: %v", env, err))
+			return
+		}
+		c.sources[key] = source
+	})
+	return errs
+}
+
+// nonEmpty 将值为空的变量视为未设置，DB_HOST= 这样的空值不会覆盖默认值和配置文件；
+// 需要清空某项时可在配置文件中设置空字符串或使用命令行参数
+func nonEmpty(lookup func(string) (string, bool)) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := lookup(key)
+		return value, ok && value != ""
+	}
+}
+
+// walk 遍历配置的叶子字段，key 为 section.field 形式
+func walk(v reflect.Value, prefix string, fn func(reflect.StructField, reflect.Value, string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key := f.Tag.Get("yaml")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(time.Duration(0)) {
+			walk(v.Field(i), key, fn)
+			continue
+		}
+		fn(f, v.Field(i), key)
+	}
+}
+
+// flatten 将嵌套的配置文件内容展开为 section.field 形式
+func flatten(prefix string, raw map[string]interface{}, out map[string]string) {
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = fmt.Sprint(v)
+	}
+}
+
+// setValue 将字符串解析后写入字段
+func setValue(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		// 纯数字按秒计，兼容旧的 DB_CONN_MAX_LIFETIME=300 写法
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			v.SetInt(int64(time.Duration(n) * time.Second))
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("不支持的配置类型 %s", v.Type())
+	}
+	return nil
+}
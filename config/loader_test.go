@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile 在临时目录中写入文件并返回路径
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入 %s 失败: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadIgnoresEmptyEnv(t *testing.T) {
+	file := writeFile(t, "config.yaml", "db:\n  host: db.internal\n")
+	dotenv := writeFile(t, ".env", "DB_NAME=\nDB_USER=app\n")
+	t.Setenv("DB_HOST", "")
+	t.Setenv("DB_PORT", "")
+	t.Setenv("DB_USER", "")
+
+	cfg, err := Load(LoadOptions{ConfigFile: file, EnvFile: dotenv})
+	if err != nil {
+		t.Fatalf("Load 失败: %v", err)
+	}
+
+	tests := []struct {
+		key, source string
+		got, want   interface{}
+	}{
+		{"db.host", SourceFile, cfg.DB.Host, "db.internal"},
+		{"db.port", SourceDefault, cfg.DB.Port, 3306},
+		{"db.name", SourceDefault, cfg.DB.Name, "gorm_learning_db"},
+		{"db.user", SourceDotEnv, cfg.DB.User, "app"},
+		// 默认不允许删除任何数据库，包括默认连接的库
+		{"db.drop_allowlist", SourceDefault, cfg.DB.DropAllowlist, ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+		if got := cfg.Source(tt.key); got != tt.source {
+			t.Errorf("%s 来源 = %s, want %s", tt.key, got, tt.source)
+		}
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	file := writeFile(t, "config.yaml", "db:\n  host: db.internal\n")
+	t.Setenv("DB_HOST", "db.env")
+
+	cfg, err := Load(LoadOptions{ConfigFile: file, EnvFile: filepath.Join(t.TempDir(), ".env")})
+	if err != nil {
+		t.Fatalf("Load 失败: %v", err)
+	}
+	if cfg.DB.Host != "db.env" || cfg.Source("db.host") != SourceEnv {
+		t.Errorf("db.host = %s (%s), want db.env (env)", cfg.DB.Host, cfg.Source("db.host"))
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// redacted 敏感配置在输出中的占位符
+const redacted = "******"
+
+// validEnvs 支持的运行环境
+var validEnvs = map[string]bool{EnvDevelopment: true, EnvTest: true, EnvStaging: true, EnvProduction: true}
+
+// validLogLevels 支持的数据库日志级别
+var validLogLevels = map[string]bool{"silent": true, "error": true, "warn": true, "info": true}
+
+// Validate 校验配置，返回全部错误
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validEnvs[c.App.Env], "app.env 无效: %q（可选 development/test/staging/production）", c.App.Env)
+
+	check(c.DB.Host != "", "db.host 不能为空")
+	check(c.DB.Port > 0 && c.DB.Port <= 65535, "db.port 超出范围: %d", c.DB.Port)
+	check(c.DB.User != "", "db.user 不能为空")
+	check(c.DB.Name != "", "db.name 不能为空")
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns 不能为负数: %d", c.DB.MaxOpenConns)
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns 不能为负数: %d", c.DB.MaxIdleConns)
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns,
+		"db.max_idle_conns(%d) 不能大于 db.max_open_conns(%d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime 不能为负数: %s", c.DB.ConnMaxLifetime)
+	check(c.DB.SlowThreshold >= 0, "db.slow_threshold 不能为负数: %s", c.DB.SlowThreshold)
+	check(validLogLevels[strings.ToLower(c.DB.LogLevel)], "db.log_level 无效: %q（可选 silent/error/warn/info）", c.DB.LogLevel)
+	for _, pattern := range strings.Split(c.DB.DropAllowlist, ",") {
+		_, err := path.Match(strings.TrimSpace(pattern), "")
+		check(err == nil, "db.drop_allowlist 中的模式无效: %q", pattern)
+	}
+	check(c.DB.BackupDir != "", "db.backup_dir 不能为空")
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port 超出范围: %d", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout 不能为负数: %s", c.Server.ReadTimeout)
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout 不能为负数: %s", c.Server.WriteTimeout)
+
+	check(c.Auth.TokenTTL > 0, "auth.token_ttl 必须大于0: %s", c.Auth.TokenTTL)
+	check(c.Auth.PasswordMinLength > 0, "auth.password_min_length 必须大于0: %d", c.Auth.PasswordMinLength)
+
+	check(c.Retention.SoftDeleteDays >= 0, "retention.soft_delete_days 不能为负数: %d", c.Retention.SoftDeleteDays)
+	check(c.Retention.EventDays >= 0, "retention.event_days 不能为负数: %d", c.Retention.EventDays)
+	check(c.Retention.DeliveryDays >= 0, "retention.delivery_days 不能为负数: %d", c.Retention.DeliveryDays)
+	check(c.Retention.Interval > 0, "retention.interval 必须大于0: %s", c.Retention.Interval)
+	check(c.Retention.BatchSize > 0, "retention.batch_size 必须大于0: %d", c.Retention.BatchSize)
+
+	switch c.Storage.Driver {
+	case "local":
+		check(c.Storage.Dir != "", "storage.dir 不能为空")
+		check(c.Storage.PublicURL != "", "storage.public_url 不能为空")
+	case "s3":
+		check(c.Storage.S3.Bucket != "", "storage.s3.bucket 不能为空")
+		check(c.Storage.S3.Region != "", "storage.s3.region 不能为空")
+		check(c.Storage.S3.AccessKey != "" && c.Storage.S3.SecretKey != "", "storage.s3.access_key 和 storage.s3.secret_key 不能为空")
+	default:
+		check(false, "storage.driver 无效: %q（可选 local/s3）", c.Storage.Driver)
+	}
+	for _, f := range []struct{ key, raw string }{
+		{"storage.public_url", c.Storage.PublicURL},
+		{"storage.s3.endpoint", c.Storage.S3.Endpoint},
+		{"storage.s3.public_url", c.Storage.S3.PublicURL},
+	} {
+		if f.raw != "" {
+			u, err := url.Parse(f.raw)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+				"%s 不是有效的http(s)地址: %q", f.key, f.raw)
+		}
+	}
+	check(c.Avatar.MaxBytes > 0, "avatar.max_bytes 必须大于0: %d", c.Avatar.MaxBytes)
+	check(c.Avatar.MaxPixels > 0, "avatar.max_pixels 必须大于0: %d", c.Avatar.MaxPixels)
+	check(c.Views.FlushInterval > 0, "views.flush_interval 必须大于0: %s", c.Views.FlushInterval)
+	check(c.Views.MaxPending > 0, "views.max_pending 必须大于0: %d", c.Views.MaxPending)
+	check(c.Events.PollInterval > 0, "events.poll_interval 必须大于0: %s", c.Events.PollInterval)
+	check(c.Events.BatchSize > 0, "events.batch_size 必须大于0: %d", c.Events.BatchSize)
+	check(c.Events.MaxAttempts > 0, "events.max_attempts 必须大于0: %d", c.Events.MaxAttempts)
+	check(c.Events.RetryBackoff > 0, "events.retry_backoff 必须大于0: %s", c.Events.RetryBackoff)
+	check(c.Events.MaxBackoff >= c.Events.RetryBackoff, "events.max_backoff 不能小于 events.retry_backoff: %s", c.Events.MaxBackoff)
+	check(c.Events.Timeout > 0, "events.timeout 必须大于0: %s", c.Events.Timeout)
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval 必须大于0: %s", c.Webhooks.PollInterval)
+	check(c.Webhooks.BatchSize > 0, "webhooks.batch_size 必须大于0: %d", c.Webhooks.BatchSize)
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts 必须大于0: %d", c.Webhooks.MaxAttempts)
+	check(c.Webhooks.RetryBackoff > 0, "webhooks.retry_backoff 必须大于0: %s", c.Webhooks.RetryBackoff)
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.RetryBackoff, "webhooks.max_backoff 不能小于 webhooks.retry_backoff: %s", c.Webhooks.MaxBackoff)
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout 必须大于0: %s", c.Webhooks.Timeout)
+
+	if c.Mail.Host != "" {
+		check(c.Mail.Port > 0 && c.Mail.Port <= 65535, "mail.port 超出范围: %d", c.Mail.Port)
+		_, err := mail.ParseAddress(c.Mail.From)
+		check(err == nil, "mail.from 不是有效的邮箱地址: %q", c.Mail.From)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("配置校验失败: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Dump 输出全部配置项及其来源，敏感项以 ****** 代替，用于调试
+func (c *Config) Dump() string {
+	var b strings.Builder
+	walk(reflect.ValueOf(c).Elem(), "", func(f reflect.StructField, v reflect.Value, key string) {
+		value := fmt.Sprint(v.Interface())
+		if f.Tag.Get("secret") == "true" && value != "" {
+			value = redacted
+		}
+		source := c.sources[key]
+		if source == "" {
+			source = SourceDefault
+		}
+		fmt.Fprintf(&b, "%-26s = %-24s (%s)\n", key, value, source)
+	})
+	return b.String()
+}
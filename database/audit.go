@@ -0,0 +1,381 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// auditedTables 需要记录审计日志的表
+var auditedTables = map[string]bool{
+	"users":   true,
+	"posts":   true,
+	"courses": true,
+}
+
+// auditRedactedColumns 审计日志中只记录“已修改”、不记录取值的列
+var auditRedactedColumns = map[string]bool{
+	"password": true,
+}
+
+// auditIgnoredColumns 更新时不记录变化的列（乐观锁版本号随每次更新变化，浏览量和点赞数是计数器）
+var auditIgnoredColumns = map[string]bool{
+	"version": true,
+	"views":   true,
+	"likes":   true,
+}
+
+// auditBeforeKey 更新、删除前加载的原始数据在Statement中的键
+const auditBeforeKey = "audit:before"
+
+// auditContextKey 审计信息在 context 中的键
+type auditContextKey struct{}
+
+// auditSkipKey 跳过审计的标记在 context 中的键
+type auditSkipKey struct{}
+
+// AuditInfo 审计日志中记录的操作人与请求ID
+type AuditInfo struct {
+	Actor     string
+	RequestID string
+}
+
+// WithAuditInfo 在 context 中附加操作人与请求ID，配合 db.WithContext(ctx) 使用
+func WithAuditInfo(ctx context.Context, actor, requestID string) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, AuditInfo{Actor: actor, RequestID: requestID})
+}
+
+// AuditInfoFrom 读取 context 中的审计信息
+func AuditInfoFrom(ctx context.Context) AuditInfo {
+	if ctx == nil {
+		return AuditInfo{}
+	}
+	info, _ := ctx.Value(auditContextKey{}).(AuditInfo)
+	return info
+}
+
+// WithoutAudit 返回不记录审计日志的 context，用于调用方自行写入审计记录的场景（如擦除个人数据时不能再把旧值写入审计日志）
+func WithoutAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditSkipKey{}, true)
+}
+
+// RegisterAuditCallbacks 注册审计回调：在同一事务内为创建、更新、删除写入审计日志
+func RegisterAuditCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("audit:after_create", auditAfterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("audit:before_update", auditLoadBeforeUpdate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("audit:after_update", auditAfterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("audit:before_delete", auditLoadBefore); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("audit:after_delete", auditAfterDelete)
+}
+
+// audited 判断当前语句是否需要审计
+func audited(db *gorm.DB) bool {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil {
+		return false
+	}
+	if skip, _ := db.Statement.Context.Value(auditSkipKey{}).(bool); skip {
+		return false
+	}
+	return auditedTables[db.Statement.Schema.Table] && len(db.Statement.Schema.PrimaryFields) == 1
+}
+
+// auditAfterCreate 记录新建的数据
+func auditAfterCreate(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+
+	var entries []models.AuditLog
+	eachRow(db.Statement.ReflectValue, func(row reflect.Value) {
+		changes := make(map[string]models.FieldChange)
+		for _, field := range auditFields(db.Statement.Schema) {
+			value, zero := field.ValueOf(db.Statement.Context, row)
+			if !zero {
+				changes[field.DBName] = models.FieldChange{After: auditValue(field, value)}
+			}
+		}
+		entries = append(entries, newAuditLog(db, row, models.AuditActionCreate, changes))
+	})
+	writeAuditLogs(db, entries)
+}
+
+// auditLoadBeforeUpdate 在更新前加载将被修改的数据；只修改计数器等不记录变化的列时直接跳过
+func auditLoadBeforeUpdate(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	if columns, ok := updatedColumns(db.Statement); ok && onlyIgnoredColumns(db.Statement.Schema, columns) {
+		return
+	}
+	auditLoadBefore(db)
+}
+
+// updatedColumns 返回更新语句要修改的列，无法确定（如按结构体非零字段更新）时返回false
+func updatedColumns(stmt *gorm.Statement) ([]string, bool) {
+	if c, ok := stmt.Clauses["SET"]; ok {
+		if set, ok := c.Expression.(clause.Set); ok && len(set) > 0 {
+			columns := make([]string, 0, len(set))
+			for _, assignment := range set {
+				columns = append(columns, assignment.Column.Name)
+			}
+			return columns, true
+		}
+	}
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		columns := make([]string, 0, len(dest))
+		for column := range dest {
+			columns = append(columns, column)
+		}
+		return columns, len(columns) > 0
+	}
+	if len(stmt.Selects) > 0 {
+		for _, column := range stmt.Selects {
+			if column == "*" {
+				return nil, false
+			}
+		}
+		return stmt.Selects, true
+	}
+	return nil, false
+}
+
+// onlyIgnoredColumns 判断修改的列是否全部不需要记录变化（忽略列和自动更新时间）
+func onlyIgnoredColumns(s *schema.Schema, columns []string) bool {
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil || (!auditIgnoredColumns[field.DBName] && field.AutoUpdateTime == 0) {
+			return false
+		}
+	}
+	return len(columns) > 0
+}
+
+// auditLoadBefore 在更新、删除前加载将被修改的数据
+// 在语句所在的事务中加行锁读取，避免读取到修改之间被其他事务改动，导致记录的旧值与实际不符
+func auditLoadBefore(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+
+	stmt := db.Statement
+	query := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Clauses(clause.Locking{Strength: "UPDATE"})
+	if stmt.Unscoped {
+		query = query.Unscoped()
+	}
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			query = query.Clauses(where)
+		}
+	}
+	// Model(&user).Updates(...)、Delete(&user) 等以主键定位的语句
+	if stmt.ReflectValue.Kind() == reflect.Struct {
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if value, zero := pk.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+			query = query.Where(clause.Eq{Column: clause.Column{Table: stmt.Schema.Table, Name: pk.DBName}, Value: value})
+		}
+	}
+
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	if err := query.Table(stmt.Schema.Table).Find(rows.Interface()).Error; err != nil {
+		db.AddError(fmt.Errorf("审计：加载修改前数据失败: %v", err))
+		return
+	}
+	db.InstanceSet(auditBeforeKey, rows.Elem())
+}
+
+// auditAfterUpdate 对比更新前后的数据，记录发生变化的字段
+func auditAfterUpdate(db *gorm.DB) {
+	before, ok := auditBefore(db)
+	if !ok || before.Len() == 0 {
+		return
+	}
+
+	stmt := db.Statement
+	pk := stmt.Schema.PrioritizedPrimaryField
+	ids := make([]interface{}, 0, before.Len())
+	for i := 0; i < before.Len(); i++ {
+		id, _ := pk.ValueOf(stmt.Context, before.Index(i))
+		ids = append(ids, id)
+	}
+
+	after := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Unscoped().
+		Table(stmt.Schema.Table).Where(clause.IN{Column: clause.Column{Name: pk.DBName}, Values: ids}).
+		Find(after.Interface()).Error
+	if err != nil {
+		db.AddError(fmt.Errorf("审计：加载修改后数据失败: %v", err))
+		return
+	}
+
+	afterByID := make(map[interface{}]reflect.Value, after.Elem().Len())
+	for i := 0; i < after.Elem().Len(); i++ {
+		row := after.Elem().Index(i)
+		id, _ := pk.ValueOf(stmt.Context, row)
+		afterByID[id] = row
+	}
+
+	var entries []models.AuditLog
+	for i := 0; i < before.Len(); i++ {
+		oldRow := before.Index(i)
+		id, _ := pk.ValueOf(stmt.Context, oldRow)
+		newRow, ok := afterByID[id]
+		if !ok {
+			continue
+		}
+
+		changes := make(map[string]models.FieldChange)
+		for _, field := range auditFields(stmt.Schema) {
+			if field.AutoUpdateTime > 0 || auditIgnoredColumns[field.DBName] {
+				continue
+			}
+			oldValue, _ := field.ValueOf(stmt.Context, oldRow)
+			newValue, _ := field.ValueOf(stmt.Context, newRow)
+			if !valueChanged(oldValue, newValue) {
+				continue
+			}
+			changes[field.DBName] = models.FieldChange{
+				Before: auditValue(field, oldValue),
+				After:  auditValue(field, newValue),
+			}
+		}
+		if len(changes) > 0 {
+			entries = append(entries, newAuditLog(db, newRow, models.AuditActionUpdate, changes))
+		}
+	}
+	writeAuditLogs(db, entries)
+}
+
+// auditAfterDelete 记录被删除的数据（含软删除）
+func auditAfterDelete(db *gorm.DB) {
+	before, ok := auditBefore(db)
+	if !ok {
+		return
+	}
+
+	var entries []models.AuditLog
+	for i := 0; i < before.Len(); i++ {
+		row := before.Index(i)
+		changes := make(map[string]models.FieldChange)
+		for _, field := range auditFields(db.Statement.Schema) {
+			value, zero := field.ValueOf(db.Statement.Context, row)
+			if !zero {
+				changes[field.DBName] = models.FieldChange{Before: auditValue(field, value)}
+			}
+		}
+		entries = append(entries, newAuditLog(db, row, models.AuditActionDelete, changes))
+	}
+	writeAuditLogs(db, entries)
+}
+
+// auditBefore 取出更新、删除前加载的数据
+func auditBefore(db *gorm.DB) (reflect.Value, bool) {
+	if !audited(db) {
+		return reflect.Value{}, false
+	}
+	v, ok := db.InstanceGet(auditBeforeKey)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	rows, ok := v.(reflect.Value)
+	return rows, ok
+}
+
+// auditFields 参与审计的字段（可写的数据库列，不含关联和生成列）
+func auditFields(s *schema.Schema) []*schema.Field {
+	fields := make([]*schema.Field, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		if field.DBName != "" && field.Readable && (field.Creatable || field.Updatable) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// auditValue 返回写入审计日志的字段值，敏感字段以占位符代替
+func auditValue(field *schema.Field, value interface{}) interface{} {
+	if auditRedactedColumns[field.DBName] {
+		return redactedValue
+	}
+	return value
+}
+
+// valueChanged 以JSON形式比较字段值，避免时间、JSON等类型的指针和精度差异
+func valueChanged(before, after interface{}) bool {
+	a, errA := json.Marshal(before)
+	b, errB := json.Marshal(after)
+	if errA != nil || errB != nil {
+		return !reflect.DeepEqual(before, after)
+	}
+	return !bytes.Equal(a, b)
+}
+
+// newAuditLog 构造一条审计日志
+func newAuditLog(db *gorm.DB, row reflect.Value, action string, changes map[string]models.FieldChange) models.AuditLog {
+	info := AuditInfoFrom(db.Statement.Context)
+	id, _ := db.Statement.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, row)
+	data, _ := json.Marshal(changes)
+	return models.AuditLog{
+		Actor:      info.Actor,
+		EntityType: db.Statement.Schema.Table,
+		EntityID:   toUint(id),
+		Action:     action,
+		Changes:    data,
+		RequestID:  info.RequestID,
+	}
+}
+
+// writeAuditLogs 在当前语句所在的连接（事务）中写入审计日志，失败时整个操作回滚
+func writeAuditLogs(db *gorm.DB, entries []models.AuditLog) {
+	if len(entries) == 0 {
+		return
+	}
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&entries).Error
+	if err != nil {
+		db.AddError(fmt.Errorf("写入审计日志失败: %v", err))
+	}
+}
+
+// eachRow 遍历单条或批量操作的每一行
+func eachRow(v reflect.Value, fn func(reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			row := reflect.Indirect(v.Index(i))
+			if row.Kind() == reflect.Struct {
+				fn(row)
+			}
+		}
+	case reflect.Struct:
+		fn(v)
+	}
+}
+
+// toUint 将主键值转换为 uint
+func toUint(v interface{}) uint {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uint(rv.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint(rv.Int())
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+package database_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// postQueries 记录对 posts 表的查询及其是否加了行锁
+type postQueries struct {
+	mu     sync.Mutex
+	locked []bool
+}
+
+func recordPostQueries(t *testing.T, db *gorm.DB) *postQueries {
+	t.Helper()
+	q := &postQueries{}
+	err := db.Callback().Query().Before("gorm:query").Register("test:record_posts", func(tx *gorm.DB) {
+		if tx.Statement.Table != "posts" {
+			return
+		}
+		c, ok := tx.Statement.Clauses["FOR"]
+		locking, _ := c.Expression.(clause.Locking)
+		q.mu.Lock()
+		q.locked = append(q.locked, ok && locking.Strength == "UPDATE")
+		q.mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func (q *postQueries) reset() {
+	q.mu.Lock()
+	q.locked = nil
+	q.mu.Unlock()
+}
+
+func (q *postQueries) snapshot() []bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]bool(nil), q.locked...)
+}
+
+// updateLogs 返回文章的更新审计日志
+func updateLogs(t *testing.T, db *gorm.DB, postID uint) []models.AuditLog {
+	t.Helper()
+	var logs []models.AuditLog
+	err := db.Where("entity_type = ? AND entity_id = ? AND action = ?", "posts", postID, models.AuditActionUpdate).
+		Order("id").Find(&logs).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
+
+func createPost(t *testing.T, db *gorm.DB) *models.Post {
+	t.Helper()
+	post := &models.Post{Title: "旧标题", Content: "正文", Slug: "audit-post", AuthorID: 1}
+	if err := db.Omit("Author", "Comments").Create(post).Error; err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func TestAuditLoadsBeforeImageForUpdate(t *testing.T) {
+	db := testdb.Open(t)
+	post := createPost(t, db)
+	queries := recordPostQueries(t, db)
+
+	if err := db.Model(post).Update("title", "新标题").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次查询是更新前加载原始数据，须加行锁；第二次是更新后读取新值
+	locked := queries.snapshot()
+	if len(locked) == 0 || !locked[0] {
+		t.Fatalf("加载修改前数据未使用 FOR UPDATE: %v", locked)
+	}
+
+	logs := updateLogs(t, db, post.ID)
+	if len(logs) != 1 {
+		t.Fatalf("更新审计日志 %d 条, want 1", len(logs))
+	}
+	var changes map[string]models.FieldChange
+	if err := json.Unmarshal(logs[0].Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if change, ok := changes["title"]; !ok || change.Before != "旧标题" || change.After != "新标题" {
+		t.Errorf("title 变更 = %+v, want 旧标题 -> 新标题", changes["title"])
+	}
+}
+
+func TestAuditSkipsIgnoredColumns(t *testing.T) {
+	db := testdb.Open(t)
+	post := createPost(t, db)
+	queries := recordPostQueries(t, db)
+
+	tests := []struct {
+		name   string
+		update func() error
+	}{
+		{"IncrementViews", func() error { return post.IncrementViews(db, 3) }},
+		{"UpdateColumn", func() error {
+			return db.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("views", gorm.Expr("views + 1")).Error
+		}},
+		{"Updates 计数器和版本号", func() error {
+			return db.Model(post).Updates(map[string]interface{}{"views": 10, "version": 2}).Error
+		}},
+		{"Select 忽略列", func() error {
+			return db.Model(post).Select("views").Updates(&models.Post{Views: 20}).Error
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries.reset()
+			if err := tt.update(); err != nil {
+				t.Fatal(err)
+			}
+			if got := queries.snapshot(); len(got) != 0 {
+				t.Errorf("只修改忽略列时仍查询了 posts %d 次", len(got))
+			}
+		})
+	}
+	if logs := updateLogs(t, db, post.ID); len(logs) != 0 {
+		t.Errorf("只修改忽略列时写入了 %d 条审计日志", len(logs))
+	}
+
+	// 同时修改忽略列和普通列时照常审计，只记录普通列
+	if err := db.Model(post).Updates(map[string]interface{}{"views": 30, "title": "新标题"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	logs := updateLogs(t, db, post.ID)
+	if len(logs) != 1 {
+		t.Fatalf("更新审计日志 %d 条, want 1", len(logs))
+	}
+	var changes map[string]models.FieldChange
+	if err := json.Unmarshal(logs[0].Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := changes["views"]; ok || len(changes) != 1 {
+		t.Errorf("变更 = %v, want 只有 title", changes)
+	}
+}
@@ -0,0 +1,488 @@
+package database
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// BackupFormatVersion 备份文件格式版本，格式不兼容时递增
+const BackupFormatVersion = 1
+
+// backupChunkRows 备份时每条记录包含的行数
+const backupChunkRows = 500
+
+// defaultRestoreBatchSize 恢复时默认每批插入的行数
+const defaultRestoreBatchSize = 500
+
+var (
+	// ErrIncompatibleBackup 备份与当前版本或表结构不兼容
+	ErrIncompatibleBackup = errors.New("备份不兼容")
+	// ErrCorruptBackup 备份文件损坏或不完整
+	ErrCorruptBackup = errors.New("备份文件损坏")
+)
+
+// 备份记录类型
+const (
+	recordHeader   = "header"
+	recordRows     = "rows"
+	recordTableEnd = "table_end"
+	recordEnd      = "end"
+)
+
+// BackupHeader 备份元数据，位于备份文件开头
+type BackupHeader struct {
+	FormatVersion int           `json:"format_version"`
+	SchemaVersion string        `json:"schema_version"` // 各表列定义的摘要
+	MigrationID   uint          `json:"migration_id"`   // 备份时最近一次迁移记录的ID
+	Database      string        `json:"database"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Tables        []BackupTable `json:"tables"` // 按依赖顺序排列
+}
+
+// BackupTable 备份中的表及其列
+type BackupTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// TableCount 表的行数
+type TableCount struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// BackupManifest 备份结果
+type BackupManifest struct {
+	BackupHeader
+	Counts []TableCount `json:"counts"`
+}
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	BatchSize   int                // 每批插入的行数，默认 500
+	Replace     bool               // 清空备份中包含的表后再恢复，否则要求这些表为空
+	Destructive DestructiveOptions // Replace 时用于破坏性操作检查
+}
+
+// RestoreReport 恢复结果
+type RestoreReport struct {
+	Header BackupHeader `json:"header"`
+	Counts []TableCount `json:"counts"`
+}
+
+// backupRecord 备份文件中的一行（gzip 压缩的 JSON Lines）
+type backupRecord struct {
+	Kind   string          `json:"kind"`
+	Header *BackupHeader   `json:"header,omitempty"`
+	Table  string          `json:"table,omitempty"`
+	Rows   [][]interface{} `json:"rows,omitempty"`
+	Count  int64           `json:"count,omitempty"`
+}
+
+// backupColumns 表中需要备份的列（生成列等只读字段除外）
+func backupColumns(s *schema.Schema) []*schema.Field {
+	var fields []*schema.Field
+	for _, f := range s.Fields {
+		if f.DBName != "" && f.Creatable {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// schemaVersion 根据各表的列名与类型计算结构摘要
+func schemaVersion(db *gorm.DB, targets []tableTarget) string {
+	h := sha256.New()
+	for _, t := range targets {
+		fmt.Fprintf(h, "%s(", t.schema.Table)
+		for _, f := range backupColumns(t.schema) {
+			fmt.Fprintf(h, "%s %s,", f.DBName, normalizeType(db.Dialector.DataTypeOf(f)))
+		}
+		fmt.Fprint(h, ")\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// latestMigrationID 返回最近一次迁移记录的ID，没有记录时为 0
+func latestMigrationID(db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable(&models.SchemaMigration{}) {
+		return 0, nil
+	}
+	var last models.SchemaMigration
+	err := db.Order("id DESC").Limit(1).Find(&last).Error
+	return last.ID, err
+}
+
+// Backup 将所有已注册表的数据按依赖顺序写入 w（gzip 压缩的 JSON Lines），
+// 在只读事务中读取以保证各表数据一致
+func Backup(w io.Writer) (*BackupManifest, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+	return backup(db, w)
+}
+
+func backup(db *gorm.DB, w io.Writer) (*BackupManifest, error) {
+	targets, err := registeredTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{BackupHeader: BackupHeader{
+		FormatVersion: BackupFormatVersion,
+		SchemaVersion: schemaVersion(db, targets),
+		Database:      db.Migrator().CurrentDatabase(),
+		CreatedAt:     time.Now(),
+	}}
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		id, err := latestMigrationID(tx)
+		if err != nil {
+			return fmt.Errorf("读取迁移记录失败: %v", err)
+		}
+		manifest.MigrationID = id
+
+		var existing []tableTarget
+		for _, t := range targets {
+			if !tx.Migrator().HasTable(t.schema.Table) {
+				continue
+			}
+			existing = append(existing, t)
+			table := BackupTable{Name: t.schema.Table}
+			for _, f := range backupColumns(t.schema) {
+				table.Columns = append(table.Columns, f.DBName)
+			}
+			manifest.Tables = append(manifest.Tables, table)
+		}
+		if err := enc.Encode(backupRecord{Kind: recordHeader, Header: &manifest.BackupHeader}); err != nil {
+			return err
+		}
+
+		for i, t := range existing {
+			count, err := backupTable(tx, enc, manifest.Tables[i])
+			if err != nil {
+				return fmt.Errorf("备份表 %s 失败: %v", t.schema.Table, err)
+			}
+			manifest.Counts = append(manifest.Counts, TableCount{Table: t.schema.Table, Rows: count})
+		}
+		return enc.Encode(backupRecord{Kind: recordEnd})
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// backupTable 逐行读取表数据，分块写入，返回行数
+func backupTable(db *gorm.DB, enc *json.Encoder, table BackupTable) (int64, error) {
+	rows, err := db.Table(table.Name).Select(table.Columns).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	chunk := make([][]interface{}, 0, backupChunkRows)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := enc.Encode(backupRecord{Kind: recordRows, Table: table.Name, Rows: chunk})
+		chunk = chunk[:0]
+		return err
+	}
+
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := db.ScanRows(rows, &row); err != nil {
+			return 0, err
+		}
+		values := make([]interface{}, len(table.Columns))
+		for i, col := range table.Columns {
+			if b, ok := row[col].([]byte); ok {
+				values[i] = string(b)
+			} else {
+				values[i] = row[col]
+			}
+		}
+		chunk = append(chunk, values)
+		count++
+		if len(chunk) == backupChunkRows {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return count, enc.Encode(backupRecord{Kind: recordTableEnd, Table: table.Name, Count: count})
+}
+
+// Restore 从 Backup 生成的数据恢复：校验格式与表结构兼容性后，
+// 在同一事务中关闭外键检查分批插入，提交前核对每个表的行数，任一步失败则整体回滚
+func Restore(r io.Reader, opts RestoreOptions) (*RestoreReport, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultRestoreBatchSize
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptBackup, err)
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(zr)
+	dec.UseNumber()
+
+	var first backupRecord
+	if err := dec.Decode(&first); err != nil || first.Kind != recordHeader || first.Header == nil {
+		return nil, fmt.Errorf("%w: 缺少备份元数据", ErrCorruptBackup)
+	}
+	header := *first.Header
+
+	targets, err := registeredTables(db)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := checkCompatible(db, header, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Replace {
+		if err := guardDestructive(db, "restore", opts.Destructive); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &RestoreReport{Header: header}
+	err = db.WithContext(WithoutAudit(context.Background())).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+			return fmt.Errorf("关闭外键检查失败: %v", err)
+		}
+		defer tx.Exec("SET FOREIGN_KEY_CHECKS = 1")
+
+		if err := prepareRestore(tx, header.Tables, opts.Replace); err != nil {
+			return err
+		}
+		counts, err := loadBackup(tx, dec, header, fields, opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		report.Counts = counts
+		return verifyRestore(tx, counts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("♻️ 已从 %s 的备份恢复 %d 个表", header.CreatedAt.Format(time.DateTime), len(report.Counts))
+	return report, nil
+}
+
+// checkCompatible 校验备份格式版本，以及备份中的每个表和列在当前模型中都存在
+// 返回各表按备份列顺序对应的字段
+func checkCompatible(db *gorm.DB, header BackupHeader, targets []tableTarget) (map[string][]*schema.Field, error) {
+	if header.FormatVersion < 1 || header.FormatVersion > BackupFormatVersion {
+		return nil, fmt.Errorf("%w: 不支持的备份格式版本 %d（当前为 %d）", ErrIncompatibleBackup, header.FormatVersion, BackupFormatVersion)
+	}
+
+	current := make(map[string]*schema.Schema, len(targets))
+	for _, t := range targets {
+		current[t.schema.Table] = t.schema
+	}
+
+	var problems []string
+	fields := make(map[string][]*schema.Field, len(header.Tables))
+	for _, table := range header.Tables {
+		s, ok := current[table.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("表 %s 已不存在", table.Name))
+			continue
+		}
+		if !db.Migrator().HasTable(table.Name) {
+			problems = append(problems, fmt.Sprintf("表 %s 尚未创建，请先执行迁移", table.Name))
+			continue
+		}
+		for _, col := range table.Columns {
+			f := s.LookUpField(col)
+			if f == nil || f.DBName != col || !f.Creatable {
+				problems = append(problems, fmt.Sprintf("表 %s 缺少列 %s", table.Name, col))
+				continue
+			}
+			fields[table.Name] = append(fields[table.Name], f)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrIncompatibleBackup, strings.Join(problems, "；"))
+	}
+
+	if version := schemaVersion(db, targets); version != header.SchemaVersion {
+		log.Printf("⚠️ 备份的结构版本 %s 与当前 %s 不同，缺失的列将使用默认值", header.SchemaVersion, version)
+	}
+	return fields, nil
+}
+
+// prepareRestore 检查目标表为空，Replace 时先清空
+func prepareRestore(tx *gorm.DB, tables []BackupTable, replace bool) error {
+	for _, table := range tables {
+		if replace {
+			if err := tx.Exec("DELETE FROM " + quote(tx, table.Name)).Error; err != nil {
+				return fmt.Errorf("清空表 %s 失败: %v", table.Name, err)
+			}
+			continue
+		}
+		var count int64
+		if err := tx.Table(table.Name).Count(&count).Error; err != nil {
+			return fmt.Errorf("统计表 %s 行数失败: %v", table.Name, err)
+		}
+		if count > 0 {
+			return fmt.Errorf("表 %s 已有 %d 行数据，如需覆盖请使用 Replace", table.Name, count)
+		}
+	}
+	return nil
+}
+
+// loadBackup 读取数据记录并分批插入，校验每个表的行数与备份中记录的一致
+func loadBackup(tx *gorm.DB, dec *json.Decoder, header BackupHeader, fields map[string][]*schema.Field, batchSize int) ([]TableCount, error) {
+	loaded := make(map[string]int64, len(header.Tables))
+	ended := make(map[string]bool, len(header.Tables))
+	var batch []map[string]interface{}
+	var batchTable string
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Table(batchTable).Create(&batch).Error; err != nil {
+			return fmt.Errorf("写入表 %s 失败: %v", batchTable, err)
+		}
+		batch = nil
+		return nil
+	}
+
+	for {
+		var rec backupRecord
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("%w: 文件不完整", ErrCorruptBackup)
+			}
+			return nil, fmt.Errorf("%w: %v", ErrCorruptBackup, err)
+		}
+
+		switch rec.Kind {
+		case recordRows:
+			columns, ok := fields[rec.Table]
+			if !ok || ended[rec.Table] {
+				return nil, fmt.Errorf("%w: 意外的表 %s 数据", ErrCorruptBackup, rec.Table)
+			}
+			if rec.Table != batchTable {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+				batchTable = rec.Table
+			}
+			for _, values := range rec.Rows {
+				row, err := restoreRow(columns, values)
+				if err != nil {
+					return nil, fmt.Errorf("%w: 表 %s 第 %d 行: %v", ErrCorruptBackup, rec.Table, loaded[rec.Table]+1, err)
+				}
+				batch = append(batch, row)
+				loaded[rec.Table]++
+				if len(batch) == batchSize {
+					if err := flush(); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case recordTableEnd:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if loaded[rec.Table] != rec.Count {
+				return nil, fmt.Errorf("%w: 表 %s 应有 %d 行，实际读取 %d 行", ErrCorruptBackup, rec.Table, rec.Count, loaded[rec.Table])
+			}
+			ended[rec.Table] = true
+		case recordEnd:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			counts := make([]TableCount, 0, len(header.Tables))
+			for _, table := range header.Tables {
+				if !ended[table.Name] {
+					return nil, fmt.Errorf("%w: 缺少表 %s 的结束标记", ErrCorruptBackup, table.Name)
+				}
+				counts = append(counts, TableCount{Table: table.Name, Rows: loaded[table.Name]})
+			}
+			return counts, nil
+		default:
+			return nil, fmt.Errorf("%w: 未知记录类型 %q", ErrCorruptBackup, rec.Kind)
+		}
+	}
+}
+
+// restoreRow 将一行数据还原为列名到取值的映射，时间列解析为 time.Time
+func restoreRow(fields []*schema.Field, values []interface{}) (map[string]interface{}, error) {
+	if len(values) != len(fields) {
+		return nil, fmt.Errorf("应有 %d 列，实际 %d 列", len(fields), len(values))
+	}
+	row := make(map[string]interface{}, len(fields))
+	for i, f := range fields {
+		value := values[i]
+		if s, ok := value.(string); ok && f.DataType == schema.Time {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("列 %s: %v", f.DBName, err)
+			}
+			value = t
+		}
+		row[f.DBName] = value
+	}
+	return row, nil
+}
+
+// verifyRestore 核对恢复后各表的实际行数
+func verifyRestore(tx *gorm.DB, counts []TableCount) error {
+	var mismatched []string
+	for _, c := range counts {
+		var actual int64
+		if err := tx.Table(c.Table).Count(&actual).Error; err != nil {
+			return fmt.Errorf("统计表 %s 行数失败: %v", c.Table, err)
+		}
+		if actual != c.Rows {
+			mismatched = append(mismatched, fmt.Sprintf("%s 应有 %d 行，实际 %d 行", c.Table, c.Rows, actual))
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("恢复后行数校验失败: %s", strings.Join(mismatched, "；"))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"exercise/config"
+)
+
+// writeBackup 把记录写成备份文件格式
+func writeBackup(t *testing.T, records ...backupRecord) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestBackupWritesRegisteredTables(t *testing.T) {
+	useGuardDB(t, config.EnvDevelopment, "main")
+
+	var buf bytes.Buffer
+	manifest, err := Backup(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只包含已创建的表（迁移用户表时一并创建了多对多关联的 courses 和 user_courses），
+	// 按注册顺序排列，中间表在最后
+	var names []string
+	for _, table := range manifest.Tables {
+		names = append(names, table.Name)
+	}
+	if want := []string{"users", "courses", "outbox", "user_courses"}; !slices.Equal(names, want) {
+		t.Errorf("备份的表 = %v, want %v", names, want)
+	}
+	columns := manifest.Tables[0].Columns
+	for _, col := range []string{"id", "username", "email", "password", "deleted_at"} {
+		if !slices.Contains(columns, col) {
+			t.Errorf("users 的备份列缺少 %s: %v", col, columns)
+		}
+	}
+	// 生成列由数据库计算，不备份
+	for _, col := range []string{"active_username", "active_email"} {
+		if slices.Contains(columns, col) {
+			t.Errorf("users 的备份列包含生成列 %s", col)
+		}
+	}
+
+	header, counts := readBackup(t, &buf)
+	if header.FormatVersion != BackupFormatVersion || header.SchemaVersion != manifest.SchemaVersion {
+		t.Errorf("备份元数据 = %+v", header)
+	}
+	if counts["users"] != 1 || counts["outbox"] != 1 {
+		t.Errorf("备份的行数 = %v, want users 1、outbox 1", counts)
+	}
+}
+
+func TestRestoreRejectsBadBackups(t *testing.T) {
+	useGuardDB(t, config.EnvDevelopment, "main")
+
+	var valid bytes.Buffer
+	manifest, err := Backup(&valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := manifest.BackupHeader
+	withTables := func(tables ...BackupTable) *BackupHeader {
+		h := header
+		h.Tables = tables
+		return &h
+	}
+	newer := header
+	newer.FormatVersion = BackupFormatVersion + 1
+
+	tests := []struct {
+		name   string
+		backup *bytes.Buffer
+		want   error
+	}{
+		{"不是 gzip", bytes.NewBufferString("id,username\n1,alice\n"), ErrCorruptBackup},
+		{"缺少元数据", writeBackup(t, backupRecord{Kind: recordEnd}), ErrCorruptBackup},
+		{"格式版本过新", writeBackup(t, backupRecord{Kind: recordHeader, Header: &newer}), ErrIncompatibleBackup},
+		{"表已不存在", writeBackup(t, backupRecord{Kind: recordHeader, Header: withTables(BackupTable{Name: "legacy_users", Columns: []string{"id"}})}), ErrIncompatibleBackup},
+		{"表尚未创建", writeBackup(t, backupRecord{Kind: recordHeader, Header: withTables(BackupTable{Name: "posts", Columns: []string{"id"}})}), ErrIncompatibleBackup},
+		{"列已不存在", writeBackup(t, backupRecord{Kind: recordHeader, Header: withTables(BackupTable{Name: "users", Columns: []string{"id", "nickname"}})}), ErrIncompatibleBackup},
+		{"生成列不可写入", writeBackup(t, backupRecord{Kind: recordHeader, Header: withTables(BackupTable{Name: "users", Columns: []string{"id", "active_email"}})}), ErrIncompatibleBackup},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Restore(tt.backup, RestoreOptions{}); !errors.Is(err, tt.want) {
+				t.Errorf("Restore = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreReplaceIsGuarded(t *testing.T) {
+	_, cfg := useGuardDB(t, config.EnvProduction, "main")
+
+	var buf bytes.Buffer
+	if _, err := Backup(&buf); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Restore(&buf, RestoreOptions{Replace: true, Destructive: DestructiveOptions{OverrideToken: "wrong"}})
+	if !errors.Is(err, ErrDestructiveRefused) {
+		t.Fatalf("生产环境令牌错误时 Restore = %v, want ErrDestructiveRefused", err)
+	}
+	if files := backups(t, cfg); len(files) != 0 {
+		t.Errorf("拒绝恢复时仍然备份了: %v", files)
+	}
+}
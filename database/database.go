@@ -3,7 +3,8 @@ package database
 import (
 	"fmt"
 	"log"
-	"time"
+	"os"
+	"strings"
 
 	"exercise/config"
 
@@ -15,28 +16,43 @@ import (
 // DB 全局数据库连接实例
 var DB *gorm.DB
 
-// InitDatabase 初始化数据库连接
+// InitDatabase 使用默认配置初始化数据库连接
 func InitDatabase() error {
 	// 加载配置
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("加载配置失败: %v", err)
 	}
+	return InitDatabaseWithConfig(cfg)
+}
+
+// InitDatabaseWithConfig 使用指定配置初始化数据库连接
+func InitDatabaseWithConfig(cfg *config.Config) error {
+	var err error
 
 	// 构建DSN
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBName,
+		cfg.DB.User,
+		cfg.DB.Password,
+		cfg.DB.Host,
+		cfg.DB.Port,
+		cfg.DB.Name,
 	)
 
-	// 配置GORM日志
-	gormConfig := &gorm.Config{}
-	if cfg.DBLogMode {
-		gormConfig.Logger = logger.Default.LogMode(logger.Info)
+	// 配置GORM日志（DB_LOG_MODE=false 时不输出任何SQL）
+	level := logger.Silent
+	if cfg.DB.LogMode {
+		if level, err = ParseLogLevel(cfg.DB.LogLevel); err != nil {
+			return err
+		}
 	}
+	queryLogger = NewQueryLogger(os.Stdout, QueryLoggerOptions{
+		Level:                     level,
+		SlowThreshold:             cfg.DB.SlowThreshold,
+		RedactColumns:             strings.Split(cfg.DB.LogRedact, ","),
+		IgnoreRecordNotFoundError: true,
+	})
+	gormConfig := &gorm.Config{Logger: queryLogger}
 
 	// 建立数据库连接
 	db, err := gorm.Open(mysql.Open(dsn), gormConfig)
@@ -44,6 +60,21 @@ func InitDatabase() error {
 		return fmt.Errorf("连接数据库失败: %v", err)
 	}
 
+	if err := SetupJoinTables(db); err != nil {
+		return err
+	}
+
+	// 注册审计回调
+	if err := RegisterAuditCallbacks(db); err != nil {
+		return fmt.Errorf("注册审计回调失败: %v", err)
+	}
+	if err := RegisterTxCallbacks(db); err != nil {
+		return fmt.Errorf("注册事务回调失败: %v", err)
+	}
+	if err := RegisterErrorCallbacks(db); err != nil {
+		return fmt.Errorf("注册错误翻译回调失败: %v", err)
+	}
+
 	// 获取通用数据库对象
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -51,9 +82,9 @@ func InitDatabase() error {
 	}
 
 	// 设置连接池配置
-	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
-	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
-	sqlDB.SetConnMaxLifetime(time.Duration(cfg.DBConnMaxLifetime) * time.Second)
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
 
 	// 测试连接
 	err = sqlDB.Ping()
@@ -63,8 +94,9 @@ func InitDatabase() error {
 
 	// 设置全局数据库实例
 	DB = db
+	currentConfig = cfg
 
-	log.Printf("✅ 数据库连接成功: %s:%d/%s", cfg.DBHost, cfg.DBPort, cfg.DBName)
+	log.Printf("✅ 数据库连接成功: %s:%d/%s", cfg.DB.Host, cfg.DB.Port, cfg.DB.Name)
 	return nil
 }
 
@@ -0,0 +1,578 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"exercise/models"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 查询 information_schema 中不存在的表时的 MySQL 错误码
+const (
+	mysqlUnknownTable = 1109 // Unknown table 'x' in information_schema
+	mysqlNoSuchTable  = 1146 // Table 'x' doesn't exist
+)
+
+// ChangeKind 结构差异类型
+type ChangeKind string
+
+const (
+	MissingTable      ChangeKind = "missing_table"
+	MissingColumn     ChangeKind = "missing_column"
+	ExtraColumn       ChangeKind = "extra_column"
+	ColumnType        ChangeKind = "column_type"
+	ColumnNullable    ChangeKind = "column_nullable"
+	ColumnDefault     ChangeKind = "column_default"
+	MissingUnique     ChangeKind = "missing_unique"
+	MissingIndex      ChangeKind = "missing_index"
+	ExtraIndex        ChangeKind = "extra_index"
+	MissingCheck      ChangeKind = "missing_check"
+	ExtraCheck        ChangeKind = "extra_check"
+	MissingForeignKey ChangeKind = "missing_foreign_key"
+	ExtraForeignKey   ChangeKind = "extra_foreign_key"
+)
+
+// destructive 是否为会丢失数据或约束的变更
+func (k ChangeKind) destructive() bool {
+	switch k {
+	case ExtraColumn, ExtraIndex, ExtraCheck, ExtraForeignKey:
+		return true
+	}
+	return false
+}
+
+// SchemaChange 单项结构差异
+type SchemaChange struct {
+	Table    string     `json:"table"`
+	Kind     ChangeKind `json:"kind"`
+	Name     string     `json:"name"`               // 列、索引或约束名
+	Expected string     `json:"expected,omitempty"` // 模型定义
+	Actual   string     `json:"actual,omitempty"`   // 数据库现状
+	SQL      []string   `json:"sql,omitempty"`      // 修复语句
+}
+
+// SchemaDiff 模型定义与线上数据库的结构差异
+type SchemaDiff struct {
+	Database string         `json:"database"`
+	Changes  []SchemaChange `json:"changes"`
+}
+
+// HasChanges 是否存在差异
+func (d *SchemaDiff) HasChanges() bool {
+	return len(d.Changes) > 0
+}
+
+// String 以可读文本输出差异
+func (d *SchemaDiff) String() string {
+	if !d.HasChanges() {
+		return fmt.Sprintf("数据库 %s 与模型定义一致\n", d.Database)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "数据库 %s 与模型定义存在 %d 处差异:\n", d.Database, len(d.Changes))
+
+	table := ""
+	for _, c := range d.Changes {
+		if c.Table != table {
+			table = c.Table
+			fmt.Fprintf(&b, "\n表 %s:\n", table)
+		}
+		switch c.Kind {
+		case MissingTable:
+			b.WriteString("  + 表不存在\n")
+		case MissingColumn:
+			fmt.Fprintf(&b, "  + 缺少列 %s %s\n", c.Name, c.Expected)
+		case ExtraColumn:
+			fmt.Fprintf(&b, "  - 多余列 %s %s\n", c.Name, c.Actual)
+		case MissingUnique:
+			fmt.Fprintf(&b, "  + 缺少唯一约束 %s (%s)\n", c.Name, c.Expected)
+		case MissingIndex:
+			fmt.Fprintf(&b, "  + 缺少索引 %s\n", c.Name)
+		case ExtraIndex:
+			fmt.Fprintf(&b, "  - 多余索引 %s\n", c.Name)
+		case MissingCheck:
+			fmt.Fprintf(&b, "  + 缺少检查约束 %s (%s)\n", c.Name, c.Expected)
+		case ExtraCheck:
+			fmt.Fprintf(&b, "  - 多余检查约束 %s (%s)\n", c.Name, c.Actual)
+		case MissingForeignKey:
+			fmt.Fprintf(&b, "  + 缺少外键 %s\n", c.Name)
+		case ExtraForeignKey:
+			fmt.Fprintf(&b, "  - 多余外键 %s\n", c.Name)
+		default:
+			fmt.Fprintf(&b, "  ~ 列 %s %s: 期望 %s，实际 %s\n", c.Name, kindLabels[c.Kind], c.Expected, c.Actual)
+		}
+	}
+
+	return b.String()
+}
+
+// kindLabels 列属性差异的中文描述
+var kindLabels = map[ChangeKind]string{
+	ColumnType:     "类型不一致",
+	ColumnNullable: "可空性不一致",
+	ColumnDefault:  "默认值不一致",
+}
+
+// SQL 输出使数据库与模型一致的语句
+// 删除类语句会丢失数据，默认以注释形式输出，includeDestructive为true时才生成可执行语句
+func (d *SchemaDiff) SQL(includeDestructive bool) string {
+	var b strings.Builder
+	for _, c := range d.Changes {
+		for _, stmt := range c.SQL {
+			if c.Kind.destructive() && !includeDestructive {
+				b.WriteString("-- ")
+			}
+			b.WriteString(stmt)
+			b.WriteString(";\n")
+		}
+	}
+	return b.String()
+}
+
+// Diff 对比模型定义与线上数据库结构
+func Diff() (*SchemaDiff, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+
+	targets, err := registeredTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SchemaDiff{Database: db.Migrator().CurrentDatabase()}
+	schemas := schemasOf(targets)
+	expected := expectedIndexes(db, targets)
+	fkNames := foreignKeyNames(schemas)
+
+	for _, t := range targets {
+		changes, err := diffTable(db, t, expected[t.schema.Table], fkNames[t.schema.Table])
+		if err != nil {
+			return nil, err
+		}
+		diff.Changes = append(diff.Changes, changes...)
+	}
+
+	return diff, nil
+}
+
+// diffTable 对比单个表
+func diffTable(db *gorm.DB, t tableTarget, expectedIdx, fkNames map[string]bool) ([]SchemaChange, error) {
+	s := t.schema
+	migrator := db.Migrator()
+
+	if !migrator.HasTable(s.Table) {
+		stmts, err := planSQL(db, s.Table, func(m gorm.Migrator) error {
+			return m.CreateTable(t.model)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []SchemaChange{{Table: s.Table, Kind: MissingTable, Name: s.Table, SQL: stmts}}, nil
+	}
+
+	var changes []SchemaChange
+
+	columnChanges, err := diffColumns(db, t)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, columnChanges...)
+
+	indexChanges, err := diffIndexes(db, t, expectedIdx, fkNames)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, indexChanges...)
+
+	checkChanges, err := diffChecks(db, t)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, checkChanges...)
+
+	fkChanges, err := diffForeignKeys(db, t, fkNames)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, fkChanges...)
+
+	return changes, nil
+}
+
+// diffColumns 对比列的类型、可空性、默认值和唯一性
+func diffColumns(db *gorm.DB, t tableTarget) ([]SchemaChange, error) {
+	s := t.schema
+	columnTypes, err := db.Migrator().ColumnTypes(s.Table)
+	if err != nil {
+		return nil, fmt.Errorf("获取表 %s 列信息失败: %v", s.Table, err)
+	}
+
+	live := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, ct := range columnTypes {
+		live[ct.Name()] = ct
+	}
+
+	var changes []SchemaChange
+	for _, dbName := range s.DBNames {
+		field := s.FieldsByDBName[dbName]
+		if field.IgnoreMigration {
+			continue
+		}
+
+		ct, ok := live[dbName]
+		if !ok {
+			stmts, err := planSQL(db, s.Table, func(m gorm.Migrator) error {
+				return m.AddColumn(t.model, field.DBName)
+			})
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, SchemaChange{
+				Table: s.Table, Kind: MissingColumn, Name: dbName,
+				Expected: db.Migrator().FullDataTypeOf(field).SQL, SQL: stmts,
+			})
+			continue
+		}
+		delete(live, dbName)
+
+		var kinds []SchemaChange
+		expectedType := normalizeType(db.Dialector.DataTypeOf(field))
+		if actualType, _ := ct.ColumnType(); !field.PrimaryKey && expectedType != normalizeType(actualType) {
+			kinds = append(kinds, SchemaChange{Kind: ColumnType, Expected: expectedType, Actual: normalizeType(actualType)})
+		}
+
+		expectedNullable := !field.NotNull && !field.PrimaryKey
+		if nullable, ok := ct.Nullable(); ok && nullable != expectedNullable {
+			kinds = append(kinds, SchemaChange{Kind: ColumnNullable, Expected: nullLabel(expectedNullable), Actual: nullLabel(nullable)})
+		}
+
+		if field.HasDefaultValue && field.DefaultValue != "" && !field.AutoIncrement {
+			actual, _ := ct.DefaultValue()
+			if normalizeDefault(field.DefaultValue) != normalizeDefault(actual) {
+				kinds = append(kinds, SchemaChange{Kind: ColumnDefault, Expected: field.DefaultValue, Actual: actual})
+			}
+		}
+
+		if len(kinds) == 0 {
+			continue
+		}
+
+		// 同一列的多项差异共用一条 MODIFY 语句
+		stmts, err := planSQL(db, s.Table, func(m gorm.Migrator) error {
+			return m.AlterColumn(t.model, field.DBName)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range kinds {
+			c.Table, c.Name = s.Table, dbName
+			if i == 0 {
+				c.SQL = stmts
+			}
+			changes = append(changes, c)
+		}
+	}
+
+	// 模型中已不存在的列（AutoMigrate不会删除）
+	extra := make([]string, 0, len(live))
+	for name := range live {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		actualType, _ := live[name].ColumnType()
+		changes = append(changes, SchemaChange{
+			Table: s.Table, Kind: ExtraColumn, Name: name, Actual: actualType,
+			SQL: []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quote(db, s.Table), quote(db, name))},
+		})
+	}
+
+	return changes, nil
+}
+
+// diffIndexes 对比索引
+func diffIndexes(db *gorm.DB, t tableTarget, expected, fkNames map[string]bool) ([]SchemaChange, error) {
+	s := t.schema
+	indexes, err := db.Migrator().GetIndexes(s.Table)
+	if err != nil {
+		return nil, fmt.Errorf("获取表 %s 索引失败: %v", s.Table, err)
+	}
+
+	live := make(map[string]bool)
+	var changes []SchemaChange
+	for _, idx := range indexes {
+		if pk, _ := idx.PrimaryKey(); pk {
+			continue
+		}
+		live[idx.Name()] = true
+		if expected[idx.Name()] || fkNames[idx.Name()] {
+			continue
+		}
+		changes = append(changes, SchemaChange{
+			Table: s.Table, Kind: ExtraIndex, Name: idx.Name(), Actual: strings.Join(idx.Columns(), ", "),
+			SQL: []string{fmt.Sprintf("DROP INDEX %s ON %s", quote(db, idx.Name()), quote(db, s.Table))},
+		})
+	}
+
+	missing := make([]string, 0)
+	for name := range expected {
+		if !live[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	uniques := s.ParseUniqueConstraints()
+	for _, name := range missing {
+		if uni, ok := uniques[name]; ok {
+			stmts, err := planSQL(db, s.Table, func(m gorm.Migrator) error {
+				return m.CreateConstraint(t.model, name)
+			})
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, SchemaChange{Table: s.Table, Kind: MissingUnique, Name: name, Expected: uni.Field.DBName, SQL: stmts})
+			continue
+		}
+
+		stmts, err := planSQL(db, s.Table, func(m gorm.Migrator) error {
+			if s.LookIndex(name) != nil {
+				return m.CreateIndex(t.model, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(stmts) == 0 {
+			// 模型声明的额外索引
+			if indexer, ok := t.model.(models.Indexer); ok {
+				for _, idx := range indexer.Indexes() {
+					if idx.Name == name {
+						stmts = append(stmts, createIndexSQL(db, s.Table, idx))
+					}
+				}
+			}
+		}
+		changes = append(changes, SchemaChange{Table: s.Table, Kind: MissingIndex, Name: name, SQL: stmts})
+	}
+
+	return changes, nil
+}
+
+// diffChecks 对比检查约束（需要 MySQL 8.0.16 及以上）
+func diffChecks(db *gorm.DB, t tableTarget) ([]SchemaChange, error) {
+	s := t.schema
+
+	var rows []struct {
+		Name   string `gorm:"column:CONSTRAINT_NAME"`
+		Clause string `gorm:"column:CHECK_CLAUSE"`
+	}
+	err := db.Raw(`
+		SELECT tc.CONSTRAINT_NAME, cc.CHECK_CLAUSE
+		FROM information_schema.TABLE_CONSTRAINTS tc
+		JOIN information_schema.CHECK_CONSTRAINTS cc
+			ON cc.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND cc.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.TABLE_NAME = ? AND tc.CONSTRAINT_TYPE = 'CHECK'
+	`, s.Table).Scan(&rows).Error
+	if err != nil {
+		if checksUnsupported(err) {
+			// 旧版本MySQL没有CHECK_CONSTRAINTS表，跳过检查约束对比
+			return nil, nil
+		}
+		return nil, fmt.Errorf("获取表 %s 检查约束失败: %v", s.Table, err)
+	}
+
+	live := make(map[string]string, len(rows))
+	for _, row := range rows {
+		live[row.Name] = row.Clause
+	}
+
+	var changes []SchemaChange
+	checks := s.ParseCheckConstraints()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := live[name]; ok {
+			delete(live, name)
+			continue
+		}
+		stmts, err := planSQL(db, s.Table, func(m gorm.Migrator) error {
+			return m.CreateConstraint(t.model, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, SchemaChange{
+			Table: s.Table, Kind: MissingCheck, Name: name, Expected: checks[name].Constraint, SQL: stmts,
+		})
+	}
+
+	extra := make([]string, 0, len(live))
+	for name := range live {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		changes = append(changes, SchemaChange{
+			Table: s.Table, Kind: ExtraCheck, Name: name, Actual: live[name],
+			SQL: []string{fmt.Sprintf("ALTER TABLE %s DROP CHECK %s", quote(db, s.Table), quote(db, name))},
+		})
+	}
+
+	return changes, nil
+}
+
+// checksUnsupported 判断是否因为 information_schema 中没有 CHECK_CONSTRAINTS 表（MySQL 8.0.16 之前）而查询失败，
+// 权限不足、超时等其他错误不能当作没有差异
+func checksUnsupported(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlUnknownTable || mysqlErr.Number == mysqlNoSuchTable)
+}
+
+// diffForeignKeys 对比外键约束
+// expected 为外键列所在表上应有的约束名
+func diffForeignKeys(db *gorm.DB, t tableTarget, expected map[string]bool) ([]SchemaChange, error) {
+	s := t.schema
+
+	var liveNames []string
+	err := db.Raw(`
+		SELECT CONSTRAINT_NAME
+		FROM information_schema.REFERENTIAL_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = DATABASE() AND TABLE_NAME = ?
+	`, s.Table).Scan(&liveNames).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取表 %s 外键失败: %v", s.Table, err)
+	}
+
+	live := make(map[string]bool, len(liveNames))
+	for _, name := range liveNames {
+		live[name] = true
+	}
+
+	var changes []SchemaChange
+	for _, name := range sortedKeys(expected) {
+		if live[name] {
+			delete(live, name)
+			continue
+		}
+		stmts, err := planSQL(db, s.Table, func(m gorm.Migrator) error {
+			return m.CreateConstraint(t.model, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, SchemaChange{Table: s.Table, Kind: MissingForeignKey, Name: name, SQL: stmts})
+	}
+
+	for _, name := range sortedKeys(live) {
+		changes = append(changes, SchemaChange{
+			Table: s.Table, Kind: ExtraForeignKey, Name: name,
+			SQL: []string{fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", quote(db, s.Table), quote(db, name))},
+		})
+	}
+
+	return changes, nil
+}
+
+// planSQL 以DryRun模式运行迁移器，收集其将要执行的DDL语句
+func planSQL(db *gorm.DB, table string, fn func(m gorm.Migrator) error) ([]string, error) {
+	recorder := &sqlRecorder{}
+	tx := db.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true, Logger: recorder})
+	if err := fn(tx.Table(table).Migrator()); err != nil {
+		return nil, fmt.Errorf("生成表 %s 的修复语句失败: %v", table, err)
+	}
+	return recorder.statements, nil
+}
+
+// sqlRecorder 记录DryRun期间生成的DDL语句
+type sqlRecorder struct {
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface      { return r }
+func (r *sqlRecorder) Info(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	sql = strings.TrimSpace(sql)
+	upper := strings.ToUpper(sql)
+	if strings.HasPrefix(upper, "SELECT") || strings.HasPrefix(upper, "SHOW") {
+		return
+	}
+	r.statements = append(r.statements, sql)
+}
+
+var (
+	intDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+	multiSpace      = regexp.MustCompile(`\s+`)
+)
+
+// normalizeType 统一类型写法（忽略整型显示宽度、bool别名、大小写）
+func normalizeType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(multiSpace.ReplaceAllString(t, " ")))
+	// 生成列只比较类型，表达式由数据库改写后无法直接比较
+	if i := strings.Index(t, " generated always as"); i >= 0 {
+		t = t[:i]
+	}
+	switch t {
+	case "bool", "boolean", "tinyint(1)":
+		return "tinyint(1)"
+	}
+	t = strings.Replace(t, "integer", "int", 1)
+	return intDisplayWidth.ReplaceAllString(t, "$1")
+}
+
+// normalizeDefault 统一默认值写法
+func normalizeDefault(v string) string {
+	v = strings.Trim(strings.TrimSpace(v), "'\"")
+	switch strings.ToLower(v) {
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	}
+	return v
+}
+
+// nullLabel 可空性描述
+func nullLabel(nullable bool) string {
+	if nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+// quote 按方言引用标识符
+func quote(db *gorm.DB, name string) string {
+	var b strings.Builder
+	db.Dialector.QuoteTo(&b, name)
+	return b.String()
+}
+
+// sortedKeys 返回排序后的键
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
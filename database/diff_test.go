@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestChecksUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"未知的 information_schema 表", &mysql.MySQLError{Number: 1109, Message: "Unknown table 'CHECK_CONSTRAINTS' in information_schema"}, true},
+		{"表不存在", &mysql.MySQLError{Number: 1146, Message: "Table 'information_schema.CHECK_CONSTRAINTS' doesn't exist"}, true},
+		{"包装后的错误", fmt.Errorf("查询失败: %w", &mysql.MySQLError{Number: 1109}), true},
+		{"权限不足", &mysql.MySQLError{Number: 1142, Message: "SELECT command denied to user"}, false},
+		{"语法错误", &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}, false},
+		{"超时", context.DeadlineExceeded, false},
+		{"其他错误", errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checksUnsupported(tt.err); got != tt.want {
+				t.Errorf("checksUnsupported(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
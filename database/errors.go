@@ -0,0 +1,265 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"exercise/models"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 需要翻译为领域错误的 MySQL 错误码
+const (
+	mysqlDuplicateEntry  = 1062
+	mysqlRowIsReferenced = 1451
+	mysqlNoReferencedRow = 1452
+	mysqlCheckViolated   = 3819
+)
+
+// mysqlPrimaryKeyIndex MySQL 中主键索引的名称
+const mysqlPrimaryKeyIndex = "PRIMARY"
+
+// 领域错误，用 errors.Is 判断；DBError 中带有约束名和字段名
+var (
+	// ErrNotFound 记录不存在（与 gorm.ErrRecordNotFound 相同）
+	ErrNotFound = gorm.ErrRecordNotFound
+	// ErrDuplicate 违反唯一约束（1062）
+	ErrDuplicate = errors.New("数据重复")
+	// ErrReferenced 记录仍被其他记录引用，不能删除或修改（1451）
+	ErrReferenced = errors.New("数据仍被其他记录引用")
+	// ErrInvalidReference 引用的记录不存在（1452）
+	ErrInvalidReference = errors.New("引用的记录不存在")
+	// ErrCheckViolation 违反检查约束（3819）
+	ErrCheckViolation = errors.New("数据不满足约束条件")
+	// ErrDeadlock 事务发生死锁（1213），TxManager 会自动重试
+	ErrDeadlock = errors.New("事务发生死锁")
+	// ErrLockTimeout 等待行锁超时（1205），TxManager 会自动重试
+	ErrLockTimeout = errors.New("等待锁超时")
+)
+
+// gormEquivalents 与领域错误含义相同的 GORM 错误，DBError 同样匹配
+var gormEquivalents = map[error]error{
+	ErrDuplicate:        gorm.ErrDuplicatedKey,
+	ErrInvalidReference: gorm.ErrForeignKeyViolated,
+	ErrCheckViolation:   gorm.ErrCheckConstraintViolated,
+}
+
+var (
+	// duplicateKeyPattern 匹配 1062 错误中的索引名：Duplicate entry 'x' for key 'users.idx_users_email'
+	duplicateKeyPattern = regexp.MustCompile(`for key '([^']+)'`)
+	// foreignKeyPattern 匹配 1451/1452 错误中的外键约束：(`db`.`comments`, CONSTRAINT `fk` FOREIGN KEY (`post_id`) ...
+	foreignKeyPattern = regexp.MustCompile("`([^`]+)`, CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`")
+	// checkPattern 匹配 3819 错误中的检查约束名：Check constraint 'chk_users_age' is violated.
+	checkPattern = regexp.MustCompile(`constraint '([^']+)'`)
+)
+
+// DBError 翻译后的数据库错误
+type DBError struct {
+	Kind       error  // 领域错误，如 ErrDuplicate
+	Table      string // 约束所在的表（可能为空）
+	Constraint string // 约束或索引名（可能为空）
+	Field      string // 涉及的列，多列时以逗号分隔（可能为空）
+	Err        error  // 驱动返回的原始错误
+}
+
+// Error 实现 error 接口
+func (e *DBError) Error() string {
+	switch {
+	case e.Field != "" && e.Constraint != "":
+		return fmt.Sprintf("%v: 字段 %s（约束 %s）", e.Kind, e.Field, e.Constraint)
+	case e.Constraint != "":
+		return fmt.Sprintf("%v: 约束 %s", e.Kind, e.Constraint)
+	}
+	return e.Kind.Error()
+}
+
+// Is 匹配领域错误以及含义相同的 GORM 错误
+func (e *DBError) Is(target error) bool {
+	return target == e.Kind || (target != nil && gormEquivalents[e.Kind] == target)
+}
+
+// Unwrap 返回驱动的原始错误，errors.As 仍可取出 *mysql.MySQLError
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// TranslateError 把 MySQL 驱动错误翻译为 DBError，sch 用于把索引和检查约束名解析为字段名（可为 nil）；
+// 其他错误原样返回
+func TranslateError(err error, sch *schema.Schema) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	switch mysqlErr.Number {
+	case mysqlDuplicateEntry:
+		e := &DBError{Kind: ErrDuplicate, Err: err}
+		if m := duplicateKeyPattern.FindStringSubmatch(mysqlErr.Message); m != nil {
+			// MySQL 8 的索引名带有表名前缀
+			if i := strings.LastIndex(m[1], "."); i >= 0 {
+				e.Table, e.Constraint = m[1][:i], m[1][i+1:]
+			} else {
+				e.Constraint = m[1]
+			}
+			e.Field = indexFields(sch, e.Constraint)
+		}
+		if e.Table == "" && sch != nil {
+			e.Table = sch.Table
+		}
+		return e
+	case mysqlRowIsReferenced, mysqlNoReferencedRow:
+		e := &DBError{Kind: ErrReferenced, Err: err}
+		if mysqlErr.Number == mysqlNoReferencedRow {
+			e.Kind = ErrInvalidReference
+		}
+		// 表、约束和字段都是引用方（子表）的
+		if m := foreignKeyPattern.FindStringSubmatch(mysqlErr.Message); m != nil {
+			e.Table, e.Constraint, e.Field = m[1], m[2], m[3]
+		}
+		return e
+	case mysqlCheckViolated:
+		e := &DBError{Kind: ErrCheckViolation, Err: err}
+		if m := checkPattern.FindStringSubmatch(mysqlErr.Message); m != nil {
+			e.Constraint = m[1]
+			e.Field = checkField(sch, e.Constraint)
+		}
+		if sch != nil {
+			e.Table = sch.Table
+		}
+		return e
+	case mysqlDeadlock:
+		return &DBError{Kind: ErrDeadlock, Err: err}
+	case mysqlLockWaitTimeout:
+		return &DBError{Kind: ErrLockTimeout, Err: err}
+	}
+	return err
+}
+
+// indexFields 返回唯一索引包含的列：依次查找GORM标签生成的索引和唯一约束、模型声明的额外索引，
+// 都找不到时按 GORM 的命名规则 idx_<表名>_<列名> 或 uni_<表名>_<列名> 推断；生成列换成它取值的源列
+func indexFields(sch *schema.Schema, name string) string {
+	if sch == nil {
+		return ""
+	}
+	var columns []string
+	if name == mysqlPrimaryKeyIndex {
+		for _, field := range sch.PrimaryFields {
+			columns = append(columns, field.DBName)
+		}
+	} else if index := taggedIndex(sch, name); index != nil {
+		for _, field := range index.Fields {
+			columns = append(columns, field.DBName)
+		}
+	} else if uni, ok := sch.ParseUniqueConstraints()[name]; ok {
+		columns = []string{uni.Field.DBName}
+	} else if index, ok := declaredIndex(sch, name); ok {
+		for _, col := range index.Columns {
+			columns = append(columns, col.Name)
+		}
+	} else {
+		column := strings.TrimPrefix(name, "idx_"+sch.Table+"_")
+		column = strings.TrimPrefix(column, "uni_"+sch.Table+"_")
+		columns = []string{column}
+	}
+	for i, column := range columns {
+		columns[i] = sourceColumn(sch, column)
+	}
+	return strings.Join(columns, ",")
+}
+
+// taggedIndex 按名称查找GORM标签生成的索引
+func taggedIndex(sch *schema.Schema, name string) *schema.Index {
+	for _, index := range sch.ParseIndexes() {
+		if index.Name == name {
+			return index
+		}
+	}
+	return nil
+}
+
+// declaredIndex 查找模型通过 Indexes() 声明的额外索引
+func declaredIndex(sch *schema.Schema, name string) (models.Index, bool) {
+	if sch.ModelType == nil {
+		return models.Index{}, false
+	}
+	indexer, ok := reflect.New(sch.ModelType).Interface().(models.Indexer)
+	if !ok {
+		return models.Index{}, false
+	}
+	for _, index := range indexer.Indexes() {
+		if index.Name == name {
+			return index, true
+		}
+	}
+	return models.Index{}, false
+}
+
+// generatedSourcePattern 匹配生成列表达式中取值的源列：GENERATED ALWAYS AS (CASE WHEN ... THEN email END)
+var generatedSourcePattern = regexp.MustCompile(`(?i)GENERATED\s+ALWAYS\s+AS\s*\(.*\bTHEN\s+(\w+)\s+END`)
+
+// sourceColumn 生成列（如 active_email）返回其源列（email），其他列原样返回
+func sourceColumn(sch *schema.Schema, column string) string {
+	field, ok := sch.FieldsByDBName[column]
+	if !ok {
+		return column
+	}
+	m := generatedSourcePattern.FindStringSubmatch(field.TagSettings["TYPE"])
+	if m == nil {
+		return column
+	}
+	if _, ok := sch.FieldsByDBName[m[1]]; !ok {
+		return column
+	}
+	return m[1]
+}
+
+// checkField 返回检查约束所在的列，找不到约束时按 GORM 的命名规则 chk_<表名>_<列名> 推断
+func checkField(sch *schema.Schema, name string) string {
+	if sch == nil {
+		return ""
+	}
+	if chk, ok := sch.ParseCheckConstraints()[name]; ok && chk.Field != nil {
+		return chk.Field.DBName
+	}
+	return strings.TrimPrefix(name, "chk_"+sch.Table+"_")
+}
+
+// RegisterErrorCallbacks 注册错误翻译回调：所有仓储经由 GORM 执行的语句出错时，
+// db.Error 都会被替换为 DBError，调用方用 errors.Is 判断领域错误即可
+//
+// 不使用 gorm.Config.TranslateError：它只返回 gorm.ErrDuplicatedKey 等，丢失了约束名和字段名
+func RegisterErrorCallbacks(db *gorm.DB) error {
+	translate := func(db *gorm.DB) {
+		if db.Error != nil {
+			db.Error = TranslateError(db.Error, db.Statement.Schema)
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("errors:translate_create", translate); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("errors:translate_query", translate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("errors:translate_update", translate); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("errors:translate_delete", translate); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("errors:translate_row", translate); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("errors:translate_raw", translate)
+}
@@ -0,0 +1,238 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"exercise/models"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// parseSchema 解析模型的 schema，与 GORM 默认命名规则一致
+func parseSchema(t *testing.T, model interface{}) *schema.Schema {
+	t.Helper()
+	sch, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sch
+}
+
+func TestTranslateError(t *testing.T) {
+	users := parseSchema(t, &models.User{})
+	posts := parseSchema(t, &models.Post{})
+	profiles := parseSchema(t, &models.Profile{})
+	deliveries := parseSchema(t, &models.WebhookDelivery{})
+	comments := parseSchema(t, &models.Comment{})
+
+	tests := []struct {
+		name       string
+		err        *mysql.MySQLError
+		sch        *schema.Schema
+		kind       error
+		gormErr    error
+		table      string
+		constraint string
+		field      string
+	}{
+		{
+			name:       "邮箱重复（生成列上的唯一索引）",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.uni_users_active_email'"},
+			sch:        users,
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "users",
+			constraint: "uni_users_active_email",
+			field:      "email",
+		},
+		{
+			name:       "用户名重复（MySQL 5.7 不带表名）",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'alice' for key 'uni_users_active_username'"},
+			sch:        users,
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "users",
+			constraint: "uni_users_active_username",
+			field:      "username",
+		},
+		{
+			name:       "unique 标签生成的唯一约束",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'hello' for key 'posts.uni_posts_slug'"},
+			sch:        posts,
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "posts",
+			constraint: "uni_posts_slug",
+			field:      "slug",
+		},
+		{
+			name:       "一对一外键列的唯一约束",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'profiles.uni_profiles_user_id'"},
+			sch:        profiles,
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "profiles",
+			constraint: "uni_profiles_user_id",
+			field:      "user_id",
+		},
+		{
+			name:       "模型声明的复合唯一索引",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1-2' for key 'webhook_deliveries.uni_webhook_deliveries_webhook_id_event_id'"},
+			sch:        deliveries,
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "webhook_deliveries",
+			constraint: "uni_webhook_deliveries_webhook_id_event_id",
+			field:      "webhook_id,event_id",
+		},
+		{
+			name:       "主键重复",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'users.PRIMARY'"},
+			sch:        users,
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "users",
+			constraint: "PRIMARY",
+			field:      "id",
+		},
+		{
+			name:       "未声明的索引按命名规则推断",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'users.uni_users_nickname'"},
+			sch:        users,
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "users",
+			constraint: "uni_users_nickname",
+			field:      "nickname",
+		},
+		{
+			name:       "没有 schema 时只有约束名",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'users.uni_users_active_email'"},
+			kind:       ErrDuplicate,
+			gormErr:    gorm.ErrDuplicatedKey,
+			table:      "users",
+			constraint: "uni_users_active_email",
+		},
+		{
+			name:       "删除仍被引用的文章",
+			err:        &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row: a foreign key constraint fails (`exercise`.`comments`, CONSTRAINT `fk_posts_comments` FOREIGN KEY (`post_id`) REFERENCES `posts` (`id`))"},
+			sch:        posts,
+			kind:       ErrReferenced,
+			table:      "comments",
+			constraint: "fk_posts_comments",
+			field:      "post_id",
+		},
+		{
+			name:       "引用不存在的用户",
+			err:        &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`exercise`.`comments`, CONSTRAINT `fk_comments_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"},
+			sch:        comments,
+			kind:       ErrInvalidReference,
+			gormErr:    gorm.ErrForeignKeyViolated,
+			table:      "comments",
+			constraint: "fk_comments_user",
+			field:      "user_id",
+		},
+		{
+			name:       "年龄违反检查约束",
+			err:        &mysql.MySQLError{Number: 3819, Message: "Check constraint 'chk_users_age' is violated."},
+			sch:        users,
+			kind:       ErrCheckViolation,
+			gormErr:    gorm.ErrCheckConstraintViolated,
+			table:      "users",
+			constraint: "chk_users_age",
+			field:      "age",
+		},
+		{
+			name:       "评分违反检查约束",
+			err:        &mysql.MySQLError{Number: 3819, Message: "Check constraint 'chk_comments_rating' is violated."},
+			sch:        comments,
+			kind:       ErrCheckViolation,
+			gormErr:    gorm.ErrCheckConstraintViolated,
+			table:      "comments",
+			constraint: "chk_comments_rating",
+			field:      "rating",
+		},
+		{
+			name: "死锁",
+			err:  &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"},
+			sch:  users,
+			kind: ErrDeadlock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := TranslateError(fmt.Errorf("执行失败: %w", tt.err), tt.sch)
+			var dbErr *DBError
+			if !errors.As(err, &dbErr) {
+				t.Fatalf("TranslateError 返回 %T: %v", err, err)
+			}
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("Kind = %v, want %v", dbErr.Kind, tt.kind)
+			}
+			if tt.gormErr != nil && !errors.Is(err, tt.gormErr) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.gormErr)
+			}
+			if dbErr.Table != tt.table || dbErr.Constraint != tt.constraint || dbErr.Field != tt.field {
+				t.Errorf("Table, Constraint, Field = %q, %q, %q, want %q, %q, %q",
+					dbErr.Table, dbErr.Constraint, dbErr.Field, tt.table, tt.constraint, tt.field)
+			}
+			var mysqlErr *mysql.MySQLError
+			if !errors.As(err, &mysqlErr) || mysqlErr != tt.err {
+				t.Errorf("取不出原始驱动错误: %v", err)
+			}
+			// 已翻译的错误再次翻译时保持不变
+			if again := TranslateError(err, tt.sch); again != err {
+				t.Errorf("重复翻译得到 %v", again)
+			}
+		})
+	}
+}
+
+func TestTranslateErrorIgnoresOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		&mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"},
+		gorm.ErrRecordNotFound,
+		errors.New("connection refused"),
+	} {
+		if got := TranslateError(err, nil); got != err {
+			t.Errorf("TranslateError(%v) = %v, want 原样返回", err, got)
+		}
+	}
+}
+
+func TestTranslateLockErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		kind  error
+		other error
+	}{
+		{"死锁", &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}, ErrDeadlock, ErrLockTimeout},
+		{"锁等待超时", &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded; try restarting transaction"}, ErrLockTimeout, ErrDeadlock},
+		{"包装后的锁等待超时", fmt.Errorf("更新失败: %w", &mysql.MySQLError{Number: 1205}), ErrLockTimeout, ErrDeadlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := TranslateError(tt.err, nil)
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("TranslateError(%v) = %v, want %v", tt.err, err, tt.kind)
+			}
+			if errors.Is(err, tt.other) {
+				t.Errorf("TranslateError(%v) 同时匹配 %v", tt.err, tt.other)
+			}
+			var mysqlErr *mysql.MySQLError
+			if !errors.As(err, &mysqlErr) {
+				t.Errorf("翻译后取不出驱动错误: %v", err)
+			}
+			// 翻译后仍由 TxManager 重试
+			if !retryableTxError(err) {
+				t.Errorf("翻译后的 %v 不再可重试", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrDestructiveRefused 破坏性操作被拒绝
+var ErrDestructiveRefused = errors.New("拒绝执行破坏性操作")
+
+// DestructiveOptions 破坏性操作（删除全部表、重置数据库）的选项
+type DestructiveOptions struct {
+	OverrideToken string // 生产环境须与 app.override_token 一致
+}
+
+// guardDestructive 执行破坏性操作前的检查：
+// 生产环境须提供正确的令牌，数据库名须在 db.drop_allowlist 中，检查通过后自动备份
+func guardDestructive(db *gorm.DB, op string, opts DestructiveOptions) error {
+	cfg := CurrentConfig()
+	if cfg == nil {
+		return fmt.Errorf("%w: 未加载配置，无法确认运行环境", ErrDestructiveRefused)
+	}
+
+	if cfg.App.IsProduction() {
+		token := cfg.App.OverrideToken
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(opts.OverrideToken)) != 1 {
+			return fmt.Errorf("%w: 生产环境执行 %s 需要提供正确的令牌", ErrDestructiveRefused, op)
+		}
+		log.Printf("⚠️ 已使用令牌在生产环境执行 %s", op)
+	}
+
+	name := db.Migrator().CurrentDatabase()
+	if !dropAllowed(name, cfg.DB.DropAllowlist) {
+		return fmt.Errorf("%w: 数据库 %s 不在 db.drop_allowlist 中（默认为空，须显式配置）", ErrDestructiveRefused, name)
+	}
+
+	file, err := snapshot(db, cfg.DB.BackupDir, name)
+	if err != nil {
+		return fmt.Errorf("%w: 自动备份失败: %v", ErrDestructiveRefused, err)
+	}
+	log.Printf("💾 已备份数据库 %s 到 %s", name, file)
+	return nil
+}
+
+// dropAllowed 判断数据库名是否匹配白名单中的某个模式
+func dropAllowed(name, allowlist string) bool {
+	for _, pattern := range strings.Split(allowlist, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot 将数据库备份到备份目录，返回文件路径
+func snapshot(db *gorm.DB, dir, name string) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, fmt.Sprintf("%s-%s.jsonl.gz", name, time.Now().Format("20060102-150405.000000")))
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := backup(db, f); err != nil {
+		return "", err
+	}
+	return file, f.Sync()
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"exercise/config"
+	"exercise/models"
+
+	"gorm.io/gorm"
+)
+
+// useGuardDB 打开只有一个用户的测试数据库，使用指定的运行环境和白名单
+func useGuardDB(t *testing.T, env, allowlist string) (*gorm.DB, *config.Config) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := config.Load(config.LoadOptions{EnvFile: filepath.Join(t.TempDir(), ".env")})
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	cfg.App.Env = env
+	cfg.App.OverrideToken = "let-me-in"
+	cfg.DB.DropAllowlist = allowlist
+	cfg.DB.BackupDir = t.TempDir()
+	// 内存数据库的每个连接是独立的库
+	cfg.DB.MaxOpenConns = 1
+
+	// 备份跳过不存在的表，只建用户表和注册时写入事件的 outbox
+	db := useReloadDB(t, cfg)
+	if err := db.AutoMigrate(&models.User{}, &models.OutboxEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.User{Username: "alice", Email: "alice@example.com", Password: "secret123", Age: 30}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, cfg
+}
+
+// backups 返回备份目录中的文件
+func backups(t *testing.T, cfg *config.Config) []string {
+	t.Helper()
+	entries, err := os.ReadDir(cfg.DB.BackupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// readBackup 解析备份文件，返回元数据和各表的行数
+func readBackup(t *testing.T, r io.Reader) (BackupHeader, map[string]int64) {
+	t.Helper()
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(zr)
+	var header BackupHeader
+	counts := make(map[string]int64)
+	for {
+		var record backupRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("备份没有结束标记: %v", err)
+		}
+		switch record.Kind {
+		case recordHeader:
+			header = *record.Header
+		case recordTableEnd:
+			counts[record.Table] = record.Count
+		case recordEnd:
+			return header, counts
+		}
+	}
+}
+
+func TestDropAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist string
+		want      bool
+	}{
+		{"gorm_learning_db", "gorm_learning_db,*_dev,*_test", true},
+		{"shop_test", "gorm_learning_db, *_dev , *_test", true},
+		{"shop", "gorm_learning_db,*_dev,*_test", false},
+		{"shop_test_backup", "*_test", false},
+		{"shop_test", "", false},
+		{"shop_test", ",,", false},
+	}
+	for _, tt := range tests {
+		if got := dropAllowed(tt.name, tt.allowlist); got != tt.want {
+			t.Errorf("dropAllowed(%q, %q) = %v, want %v", tt.name, tt.allowlist, got, tt.want)
+		}
+	}
+}
+
+func TestGuardDestructive(t *testing.T) {
+	// SQLite 的库名为 main
+	tests := []struct {
+		name      string
+		env       string
+		allowlist string
+		token     string
+		refused   bool
+	}{
+		{"开发环境且在白名单中", config.EnvDevelopment, "main", "", false},
+		{"不在白名单中", config.EnvDevelopment, "*_test", "", true},
+		{"生产环境缺少令牌", config.EnvProduction, "main", "", true},
+		{"生产环境令牌错误", config.EnvProduction, "main", "let-me", true},
+		{"生产环境令牌正确", config.EnvProduction, "main", "let-me-in", false},
+		{"生产环境令牌正确但不在白名单中", config.EnvProduction, "prod_db", "let-me-in", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cfg := useGuardDB(t, tt.env, tt.allowlist)
+			err := guardDestructive(db, "DropAll", DestructiveOptions{OverrideToken: tt.token})
+			if tt.refused {
+				if !errors.Is(err, ErrDestructiveRefused) {
+					t.Fatalf("guardDestructive = %v, want ErrDestructiveRefused", err)
+				}
+				if files := backups(t, cfg); len(files) != 0 {
+					t.Errorf("拒绝执行时仍然备份了: %v", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("guardDestructive = %v", err)
+			}
+			files := backups(t, cfg)
+			if len(files) != 1 {
+				t.Fatalf("备份文件 = %v, want 1 个", files)
+			}
+
+			// 自动备份可以被解析，且包含已有数据
+			f, err := os.Open(filepath.Join(cfg.DB.BackupDir, files[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			header, counts := readBackup(t, f)
+			if header.Database != "main" {
+				t.Errorf("备份的库名 = %q, want main", header.Database)
+			}
+			if counts["users"] != 1 {
+				t.Errorf("备份中的用户数 = %d, want 1", counts["users"])
+			}
+		})
+	}
+}
+
+func TestDestructiveOperationsRequireConfig(t *testing.T) {
+	db, _ := useGuardDB(t, config.EnvDevelopment, "main")
+	reloadMu.Lock()
+	saved := currentConfig
+	currentConfig = nil
+	reloadMu.Unlock()
+	defer func() {
+		reloadMu.Lock()
+		currentConfig = saved
+		reloadMu.Unlock()
+	}()
+
+	if err := guardDestructive(db, "Reset", DestructiveOptions{}); !errors.Is(err, ErrDestructiveRefused) {
+		t.Errorf("未加载配置时 guardDestructive = %v, want ErrDestructiveRefused", err)
+	}
+}
+
+func TestRollbackIsGuarded(t *testing.T) {
+	db, cfg := useGuardDB(t, config.EnvDevelopment, "*_test")
+	if err := db.AutoMigrate(&models.SchemaMigration{}); err != nil {
+		t.Fatal(err)
+	}
+	// 首次迁移记录的是新建所有表，回滚会删除这些表
+	diff := &SchemaDiff{Changes: []SchemaChange{{Table: "users", Kind: MissingTable, Name: "users"}}}
+	if err := recordMigration(db, diff); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Rollback(DestructiveOptions{}); !errors.Is(err, ErrDestructiveRefused) {
+		t.Fatalf("Rollback = %v, want ErrDestructiveRefused", err)
+	}
+	if !db.Migrator().HasTable("users") {
+		t.Error("拒绝回滚后 users 表被删除")
+	}
+	if history, err := MigrationHistory(); err != nil || len(history) != 1 {
+		t.Errorf("拒绝回滚后迁移记录 = %d 条, %v, want 1 条", len(history), err)
+	}
+	if files := backups(t, cfg); len(files) != 0 {
+		t.Errorf("拒绝执行时仍然备份了: %v", files)
+	}
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"exercise/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoMigration 没有可回滚的迁移
+var ErrNoMigration = errors.New("没有可回滚的迁移")
+
+// rollbackOrder 回滚时各类变更的撤销顺序：先约束和索引，再列，最后表
+var rollbackOrder = []ChangeKind{MissingForeignKey, MissingCheck, MissingIndex, MissingUnique, MissingColumn, MissingTable}
+
+// RollbackResult 回滚结果
+type RollbackResult struct {
+	MigrationID  uint           `json:"migration_id"`
+	Reverted     []SchemaChange `json:"reverted"`     // 已撤销的新增
+	Irreversible []SchemaChange `json:"irreversible"` // 列类型、可空性、默认值等修改无法自动撤销
+}
+
+// recordMigration 记录一次迁移带来的结构差异，没有差异时不记录
+func recordMigration(db *gorm.DB, diff *SchemaDiff) error {
+	if diff == nil || !diff.HasChanges() {
+		return nil
+	}
+	changes, err := json.Marshal(diff.Changes)
+	if err != nil {
+		return err
+	}
+	return db.Create(&models.SchemaMigration{Changes: changes}).Error
+}
+
+// MigrationHistory 返回迁移记录（最新的在前）
+func MigrationHistory() ([]models.SchemaMigration, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+	if !db.Migrator().HasTable(&models.SchemaMigration{}) {
+		return nil, nil
+	}
+
+	var history []models.SchemaMigration
+	if err := db.Order("id DESC").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("获取迁移记录失败: %v", err)
+	}
+	return history, nil
+}
+
+// Rollback 回滚最近一次迁移：撤销其新增的表、列、索引和约束，并删除迁移记录
+// 对已有列的修改无法自动撤销，在结果的 Irreversible 中列出
+// 首次迁移的回滚会删除所有表，因此与 DropAll 一样须通过 guardDestructive 检查
+func Rollback(opts DestructiveOptions) (*RollbackResult, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+
+	history, err := MigrationHistory()
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, ErrNoMigration
+	}
+	last := history[0]
+
+	var changes []SchemaChange
+	if err := json.Unmarshal(last.Changes, &changes); err != nil {
+		return nil, fmt.Errorf("解析迁移记录 %d 失败: %v", last.ID, err)
+	}
+
+	result := &RollbackResult{MigrationID: last.ID}
+	droppedTables := make(map[string]bool)
+	for _, c := range changes {
+		if c.Kind == MissingTable {
+			droppedTables[c.Table] = true
+		}
+	}
+
+	if err := guardDestructive(db, "rollback", opts); err != nil {
+		return nil, err
+	}
+
+	// 外键检查是会话变量，禁用与恢复须在同一连接上执行，避免连接带着禁用状态回到连接池
+	err = db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+			return fmt.Errorf("禁用外键约束失败: %v", err)
+		}
+		defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+
+		for _, kind := range rollbackOrder {
+			// 按记录的逆序撤销，后创建的先删除
+			for i := len(changes) - 1; i >= 0; i-- {
+				c := changes[i]
+				if c.Kind != kind || (kind != MissingTable && droppedTables[c.Table]) {
+					continue
+				}
+				// 迁移记录表本身保留，只删除记录
+				if kind == MissingTable && c.Table == (models.SchemaMigration{}).TableName() {
+					continue
+				}
+				if err := conn.Exec(revertSQL(conn, c)).Error; err != nil {
+					return fmt.Errorf("回滚 %s %s 失败: %v", c.Table, c.Name, err)
+				}
+				log.Printf("↩️ 已回滚: %s %s %s", c.Table, c.Kind, c.Name)
+				result.Reverted = append(result.Reverted, c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	for _, c := range changes {
+		switch c.Kind {
+		case ColumnType, ColumnNullable, ColumnDefault:
+			result.Irreversible = append(result.Irreversible, c)
+		}
+	}
+
+	if err := db.Delete(&models.SchemaMigration{}, last.ID).Error; err != nil {
+		return result, fmt.Errorf("删除迁移记录失败: %v", err)
+	}
+	return result, nil
+}
+
+// revertSQL 生成撤销一项新增的语句
+func revertSQL(db *gorm.DB, c SchemaChange) string {
+	table, name := quote(db, c.Table), quote(db, c.Name)
+	switch c.Kind {
+	case MissingTable:
+		return fmt.Sprintf("DROP TABLE IF EXISTS %s", table)
+	case MissingColumn:
+		return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, name)
+	case MissingForeignKey:
+		return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", table, name)
+	case MissingCheck:
+		return fmt.Sprintf("ALTER TABLE %s DROP CHECK %s", table, name)
+	}
+	return fmt.Sprintf("DROP INDEX %s ON %s", name, table)
+}
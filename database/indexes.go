@@ -0,0 +1,217 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"exercise/models"
+
+	"gorm.io/gorm"
+)
+
+// obsoleteIndexes 旧版本遗留的索引：createIndexes 创建的重复索引，
+// 以及包含已删除用户的用户名、邮箱唯一约束（已由 uni_users_active_* 代替）
+var obsoleteIndexes = map[string][]string{
+	"users": {"idx_users_email", "idx_users_username", "uni_users_email", "uni_users_username"},
+	"posts": {"idx_posts_slug"},
+}
+
+// declaredIndexes 收集各表模型上声明的额外索引
+func declaredIndexes(targets []tableTarget) map[string][]models.Index {
+	declared := make(map[string][]models.Index)
+	for _, t := range targets {
+		if indexer, ok := t.model.(models.Indexer); ok {
+			declared[t.schema.Table] = append(declared[t.schema.Table], indexer.Indexes()...)
+		}
+	}
+	return declared
+}
+
+// indexEntry 参与重复检测的索引
+type indexEntry struct {
+	name      string
+	signature string
+}
+
+// validateIndexes 检查声明的索引：列必须存在，名称不能冲突，列组合不能与已有索引或唯一约束重复
+func validateIndexes(targets []tableTarget) error {
+	declared := declaredIndexes(targets)
+	var errs []error
+
+	for _, t := range targets {
+		s := t.schema
+		var entries []indexEntry
+
+		// GORM标签生成的索引和唯一约束
+		for _, idx := range s.ParseIndexes() {
+			cols := make([]string, 0, len(idx.Fields))
+			for _, f := range idx.Fields {
+				cols = append(cols, columnSignature(f.DBName, f.Length, strings.EqualFold(f.Sort, "desc")))
+			}
+			entries = append(entries, indexEntry{idx.Name, indexSignature(strings.EqualFold(idx.Class, "FULLTEXT"), cols)})
+		}
+		for name, uni := range s.ParseUniqueConstraints() {
+			entries = append(entries, indexEntry{name, indexSignature(false, []string{uni.Field.DBName})})
+		}
+
+		// 模型声明的索引
+		for _, idx := range declared[s.Table] {
+			if idx.Name == "" || len(idx.Columns) == 0 {
+				errs = append(errs, fmt.Errorf("表 %s 的索引缺少名称或列", s.Table))
+				continue
+			}
+			cols := make([]string, 0, len(idx.Columns))
+			for _, col := range idx.Columns {
+				if _, ok := s.FieldsByDBName[col.Name]; !ok {
+					errs = append(errs, fmt.Errorf("索引 %s.%s 引用了不存在的列 %s", s.Table, idx.Name, col.Name))
+				}
+				if idx.FullText && (col.Length > 0 || col.Desc) {
+					errs = append(errs, fmt.Errorf("全文索引 %s.%s 不支持前缀长度或降序", s.Table, idx.Name))
+				}
+				cols = append(cols, columnSignature(col.Name, col.Length, col.Desc))
+			}
+			entries = append(entries, indexEntry{idx.Name, indexSignature(idx.FullText, cols)})
+		}
+
+		byName := make(map[string]bool)
+		bySignature := make(map[string]string)
+		for _, e := range entries {
+			if byName[e.name] {
+				errs = append(errs, fmt.Errorf("表 %s 的索引名 %s 重复", s.Table, e.name))
+				continue
+			}
+			byName[e.name] = true
+			if other, ok := bySignature[e.signature]; ok {
+				errs = append(errs, fmt.Errorf("表 %s 的索引 %s 与 %s 重复", s.Table, e.name, other))
+				continue
+			}
+			bySignature[e.signature] = e.name
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// indexSignature 索引列组合的标识（唯一与否不影响重复判断）
+func indexSignature(fullText bool, cols []string) string {
+	kind := "btree"
+	if fullText {
+		kind = "fulltext"
+	}
+	return kind + ":" + strings.Join(cols, ",")
+}
+
+// columnSignature 索引列的标识
+func columnSignature(name string, length int, desc bool) string {
+	sig := name
+	if length > 0 {
+		sig += fmt.Sprintf("(%d)", length)
+	}
+	if desc {
+		sig += " desc"
+	}
+	return sig
+}
+
+// createIndexSQL 按方言生成建索引语句，方言不支持时返回空字符串
+func createIndexSQL(db *gorm.DB, table string, idx models.Index) string {
+	mysql := db.Dialector.Name() == "mysql"
+	if idx.FullText && !mysql {
+		return ""
+	}
+
+	cols := make([]string, 0, len(idx.Columns))
+	for _, col := range idx.Columns {
+		str := quote(db, col.Name)
+		if col.Length > 0 && mysql {
+			str += fmt.Sprintf("(%d)", col.Length)
+		}
+		if col.Desc {
+			str += " DESC"
+		}
+		cols = append(cols, str)
+	}
+
+	sql := "CREATE "
+	switch {
+	case idx.FullText:
+		sql += "FULLTEXT "
+	case idx.Unique:
+		sql += "UNIQUE "
+	}
+	sql += fmt.Sprintf("INDEX %s ON %s (%s)", quote(db, idx.Name), quote(db, table), strings.Join(cols, ", "))
+	if idx.FullText && idx.Parser != "" {
+		sql += " WITH PARSER " + idx.Parser
+	}
+	return sql
+}
+
+// CreateIndexes 创建模型声明的额外索引（已存在则跳过，可重复执行）
+// Migrate 在建表后调用；不经过 Migrate 建表的数据库（如测试用的 SQLite）也应调用它，而不是自行生成语句
+func CreateIndexes(db *gorm.DB) error {
+	targets, err := registeredTables(db)
+	if err != nil {
+		return err
+	}
+	if err := validateIndexes(targets); err != nil {
+		return fmt.Errorf("索引定义有误: %v", err)
+	}
+
+	migrator := db.Migrator()
+
+	// 清理旧版本遗留的重复索引
+	tables := make([]string, 0, len(obsoleteIndexes))
+	for table := range obsoleteIndexes {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		for _, name := range obsoleteIndexes[table] {
+			if !migrator.HasIndex(table, name) {
+				continue
+			}
+			if err := migrator.DropIndex(table, name); err != nil {
+				return fmt.Errorf("删除旧索引 %s.%s 失败: %v", table, name, err)
+			}
+			log.Printf("🗑️ 旧索引已删除: %s.%s", table, name)
+		}
+	}
+
+	declared := declaredIndexes(targets)
+	for _, t := range targets {
+		table := t.schema.Table
+		for _, idx := range declared[table] {
+			if migrator.HasIndex(table, idx.Name) {
+				continue
+			}
+
+			sql := createIndexSQL(db, table, idx)
+			if sql == "" {
+				log.Printf("ℹ️ %s 不支持索引 %s.%s，已跳过", db.Dialector.Name(), table, idx.Name)
+				continue
+			}
+			if err := db.Exec(sql).Error; err != nil {
+				return fmt.Errorf("创建索引 %s.%s 失败: %v", table, idx.Name, err)
+			}
+			log.Printf("✅ 索引已创建: %s.%s", table, idx.Name)
+		}
+	}
+
+	return nil
+}
+
+// declaredIndexNames 返回模型声明的额外索引名称（不含当前方言不支持的索引）
+func declaredIndexNames(db *gorm.DB, targets []tableTarget) map[string][]string {
+	names := make(map[string][]string)
+	for table, indexes := range declaredIndexes(targets) {
+		for _, idx := range indexes {
+			if createIndexSQL(db, table, idx) != "" {
+				names[table] = append(names[table], idx.Name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"exercise/models"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// indexedArticle 声明了有问题的索引的测试模型
+type indexedArticle struct {
+	ID     uint   `gorm:"primaryKey"`
+	Title  string `gorm:"index"`
+	Slug   string `gorm:"unique"`
+	Status string
+	Body   string
+
+	indexes []models.Index `gorm:"-"`
+}
+
+// Indexes 额外索引
+func (a indexedArticle) Indexes() []models.Index {
+	return a.indexes
+}
+
+// dryRunDB 不连接数据库的 GORM 实例，用于生成SQL
+func dryRunDB(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// articleTargets 解析带有指定额外索引的测试模型
+func articleTargets(t *testing.T, db *gorm.DB, indexes ...models.Index) []tableTarget {
+	t.Helper()
+	model := &indexedArticle{indexes: indexes}
+	s, err := parseModel(db, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []tableTarget{{schema: s, model: model}}
+}
+
+func TestValidateIndexes(t *testing.T) {
+	db := dryRunDB(t, sqlite.Open(":memory:"))
+
+	tests := []struct {
+		name    string
+		indexes []models.Index
+		wantErr string
+	}{
+		{"复合索引", []models.Index{{Name: "idx_articles_status_title", Columns: models.Columns("status", "title")}}, ""},
+		{"降序与前缀索引", []models.Index{{Name: "idx_articles_body", Columns: []models.IndexColumn{{Name: "body", Length: 20, Desc: true}}}}, ""},
+		{"缺少名称", []models.Index{{Columns: models.Columns("status")}}, "缺少名称或列"},
+		{"列不存在", []models.Index{{Name: "idx_articles_author", Columns: models.Columns("author_id")}}, "不存在的列 author_id"},
+		{"与标签索引同名", []models.Index{{Name: "idx_indexed_articles_title", Columns: models.Columns("status")}}, "索引名 idx_indexed_articles_title 重复"},
+		{"与标签索引的列相同", []models.Index{{Name: "idx_articles_title", Columns: models.Columns("title")}}, "与 idx_indexed_articles_title 重复"},
+		{"与唯一约束的列相同", []models.Index{{Name: "uni_articles_slug", Columns: models.Columns("slug"), Unique: true}}, "与 uni_indexed_articles_slug 重复"},
+		{"全文索引不支持降序", []models.Index{{Name: "ft_articles_body", Columns: []models.IndexColumn{{Name: "body", Desc: true}}, FullText: true}}, "不支持前缀长度或降序"},
+		{"全文索引与普通索引的列可以相同", []models.Index{{Name: "ft_articles_title", Columns: models.Columns("title"), FullText: true}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIndexes(articleTargets(t, db, tt.indexes...))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validateIndexes = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("validateIndexes = %v, want 包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisteredIndexesAreValid(t *testing.T) {
+	db := dryRunDB(t, sqlite.Open(":memory:"))
+	if err := SetupJoinTables(db); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := registeredTables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateIndexes(targets); err != nil {
+		t.Errorf("已注册模型的索引定义有误: %v", err)
+	}
+}
+
+func TestCreateIndexSQL(t *testing.T) {
+	mysqlDB := dryRunDB(t, mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:3306)/test", SkipInitializeWithVersion: true}))
+	sqliteDB := dryRunDB(t, sqlite.Open(":memory:"))
+
+	composite := models.Index{Name: "idx_posts_status_published_at", Columns: []models.IndexColumn{
+		{Name: "status"}, {Name: "published_at", Desc: true},
+	}}
+	prefix := models.Index{Name: "uni_posts_slug", Columns: []models.IndexColumn{{Name: "slug", Length: 50}}, Unique: true}
+	fullText := models.Index{Name: "ft_posts_title_content", Columns: models.Columns("title", "content"), FullText: true, Parser: "ngram"}
+
+	tests := []struct {
+		name string
+		db   *gorm.DB
+		idx  models.Index
+		want string
+	}{
+		{"MySQL 降序索引", mysqlDB, composite, "CREATE INDEX `idx_posts_status_published_at` ON `posts` (`status`, `published_at` DESC)"},
+		{"MySQL 前缀唯一索引", mysqlDB, prefix, "CREATE UNIQUE INDEX `uni_posts_slug` ON `posts` (`slug`(50))"},
+		{"MySQL 全文索引", mysqlDB, fullText, "CREATE FULLTEXT INDEX `ft_posts_title_content` ON `posts` (`title`, `content`) WITH PARSER ngram"},
+		{"SQLite 忽略前缀长度", sqliteDB, prefix, "CREATE UNIQUE INDEX `uni_posts_slug` ON `posts` (`slug`)"},
+		{"SQLite 跳过全文索引", sqliteDB, fullText, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createIndexSQL(tt.db, "posts", tt.idx); got != tt.want {
+				t.Errorf("createIndexSQL =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
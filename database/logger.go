@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// redactedValue 隐藏后的参数值
+const redactedValue = "[REDACTED]"
+
+// QueryLoggerOptions 查询日志选项
+type QueryLoggerOptions struct {
+	Level                     logger.LogLevel
+	SlowThreshold             time.Duration // 超过该耗时的查询以 warn 级别记录并标记 slow，0 表示不检测
+	RedactColumns             []string      // 这些列的参数值以 [REDACTED] 代替，包含 * 时隐藏全部参数
+	IgnoreRecordNotFoundError bool          // 不把 ErrRecordNotFound 当作错误记录
+}
+
+// QueryLogger 基于 log/slog 的GORM日志，输出JSON格式的SQL、影响行数、耗时和调用位置
+type QueryLogger struct {
+	slog      *slog.Logger
+	level     *atomic.Int32
+	slow      *atomic.Int64 // 慢查询阈值（纳秒）
+	redact    map[string]bool
+	redactAll bool
+	ignoreNF  bool
+}
+
+// NewQueryLogger 创建查询日志，w 为空时输出到标准输出
+func NewQueryLogger(w io.Writer, opts QueryLoggerOptions) *QueryLogger {
+	if w == nil {
+		w = os.Stdout
+	}
+	l := &QueryLogger{
+		slog:     slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		level:    new(atomic.Int32),
+		slow:     new(atomic.Int64),
+		redact:   make(map[string]bool),
+		ignoreNF: opts.IgnoreRecordNotFoundError,
+	}
+	l.level.Store(int32(opts.Level))
+	l.slow.Store(int64(opts.SlowThreshold))
+	for _, column := range opts.RedactColumns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "":
+		case "*":
+			l.redactAll = true
+		default:
+			l.redact[column] = true
+		}
+	}
+	return l
+}
+
+// ParseLogLevel 解析日志级别（silent/error/warn/info）
+func ParseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "silent":
+		return logger.Silent, nil
+	case "error":
+		return logger.Error, nil
+	case "warn":
+		return logger.Warn, nil
+	case "info":
+		return logger.Info, nil
+	default:
+		return 0, fmt.Errorf("未知的日志级别: %q", level)
+	}
+}
+
+// Level 返回当前日志级别
+func (l *QueryLogger) Level() logger.LogLevel {
+	return logger.LogLevel(l.level.Load())
+}
+
+// SetLevel 运行时切换日志级别，对共享该日志的所有连接立即生效
+func (l *QueryLogger) SetLevel(level logger.LogLevel) {
+	l.level.Store(int32(level))
+}
+
+// SetSlowThreshold 运行时调整慢查询阈值，0 表示不检测
+func (l *QueryLogger) SetSlowThreshold(threshold time.Duration) {
+	l.slow.Store(int64(threshold))
+}
+
+// LogMode 返回指定级别的副本（如 db.Debug()），不影响原日志的级别
+func (l *QueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = new(atomic.Int32)
+	copied.level.Store(int32(level))
+	return &copied
+}
+
+// Info 记录普通信息
+func (l *QueryLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level() >= logger.Info {
+		l.slog.InfoContext(ctx, fmt.Sprintf(msg, data...), "caller", utils.FileWithLineNum())
+	}
+}
+
+// Warn 记录警告
+func (l *QueryLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level() >= logger.Warn {
+		l.slog.WarnContext(ctx, fmt.Sprintf(msg, data...), "caller", utils.FileWithLineNum())
+	}
+}
+
+// Error 记录错误
+func (l *QueryLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.Level() >= logger.Error {
+		l.slog.ErrorContext(ctx, fmt.Sprintf(msg, data...), "caller", utils.FileWithLineNum())
+	}
+}
+
+// Trace 记录一次SQL执行
+func (l *QueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	level := l.Level()
+	if level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	caller := utils.FileWithLineNum()
+	isError := err != nil && !(l.ignoreNF && errors.Is(err, gorm.ErrRecordNotFound))
+	slow := time.Duration(l.slow.Load())
+	isSlow := slow > 0 && elapsed > slow
+
+	switch {
+	case isError && level >= logger.Error:
+		sql, rows := fc()
+		l.slog.ErrorContext(ctx, "query failed", l.attrs(sql, rows, elapsed, caller, slog.String("error", err.Error()))...)
+	case isSlow && level >= logger.Warn:
+		sql, rows := fc()
+		l.slog.WarnContext(ctx, "slow query", l.attrs(sql, rows, elapsed, caller,
+			slog.Bool("slow", true), slog.String("threshold", slow.String()))...)
+	case level >= logger.Info:
+		sql, rows := fc()
+		l.slog.InfoContext(ctx, "query", l.attrs(sql, rows, elapsed, caller)...)
+	}
+}
+
+// attrs 组装一条查询日志的字段
+func (l *QueryLogger) attrs(sql string, rows int64, elapsed time.Duration, caller string, extra ...slog.Attr) []any {
+	args := []any{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Nanoseconds())/1e6),
+		slog.String("caller", caller),
+	}
+	for _, a := range extra {
+		args = append(args, a)
+	}
+	return args
+}
+
+// ParamsFilter 在SQL写入日志前隐藏敏感列的参数值（由GORM调用）
+func (l *QueryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if len(params) == 0 || (!l.redactAll && len(l.redact) == 0) {
+		return sql, params
+	}
+
+	filtered := make([]interface{}, len(params))
+	copy(filtered, params)
+	for i, column := range placeholderColumns(sql) {
+		if i >= len(filtered) {
+			break
+		}
+		if l.redactAll || l.redact[column] {
+			filtered[i] = redactedValue
+		}
+	}
+	return sql, filtered
+}
+
+// insertColumnsPattern 匹配 INSERT INTO t (a,b,c) VALUES 的列清单
+var insertColumnsPattern = regexp.MustCompile("(?is)^\\s*INSERT\\s+INTO\\s+[^(]+\\(([^)]*)\\)\\s*VALUES")
+
+// placeholderColumns 推断SQL中每个 ? 占位符对应的列名（小写，无法推断时为空串）
+// INSERT 的值列表按列清单顺序对应，其余占位符取其左侧最近的列名（如 `password` = ?、email IN (?,?)）
+func placeholderColumns(sql string) []string {
+	var insertCols []string
+	valuesStart, valuesEnd := -1, -1
+	if m := insertColumnsPattern.FindStringSubmatchIndex(sql); m != nil {
+		for _, c := range strings.Split(sql[m[2]:m[3]], ",") {
+			insertCols = append(insertCols, lastIdentifier(c))
+		}
+		valuesStart, valuesEnd = m[1], len(sql)
+		if i := strings.Index(strings.ToUpper(sql[valuesStart:]), " ON "); i >= 0 {
+			valuesEnd = valuesStart + i
+		}
+	}
+
+	var columns []string
+	inValues := 0
+	inQuote := byte(0)
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		if inQuote != 0 {
+			if ch == inQuote {
+				inQuote = 0
+			}
+			continue
+		}
+		switch ch {
+		case '\'', '"':
+			inQuote = ch
+		case '?':
+			if len(insertCols) > 0 && i >= valuesStart && i < valuesEnd {
+				columns = append(columns, insertCols[inValues%len(insertCols)])
+				inValues++
+			} else {
+				columns = append(columns, columnBefore(sql[:i]))
+			}
+		}
+	}
+	return columns
+}
+
+// comparisonKeywords 列名与占位符之间可能出现的关键字
+var comparisonKeywords = []string{"LIKE", "NOT", "IN", "IS", "BETWEEN", "AND"}
+
+// columnBefore 返回占位符左侧最近的列名
+func columnBefore(sql string) string {
+	for {
+		trimmed := strings.TrimRight(sql, " \t\r\n=<>!(,?")
+		upper := strings.ToUpper(trimmed)
+		stripped := false
+		for _, kw := range comparisonKeywords {
+			if strings.HasSuffix(upper, " "+kw) {
+				trimmed = trimmed[:len(trimmed)-len(kw)]
+				stripped = true
+				break
+			}
+		}
+		sql = trimmed
+		if !stripped {
+			break
+		}
+	}
+	start := len(sql)
+	for start > 0 && isIdentifierChar(sql[start-1]) {
+		start--
+	}
+	return lastIdentifier(sql[start:])
+}
+
+// lastIdentifier 去掉表名前缀与引号，返回小写列名
+func lastIdentifier(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.ToLower(strings.Trim(s, "`\" "))
+}
+
+// isIdentifierChar 判断字符是否可能属于（带引号或表前缀的）列名
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '`' || c == '"' || c == '.' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// queryLogger 当前连接使用的查询日志，用于运行时调整级别
+var queryLogger *QueryLogger
+
+// SetLogLevel 运行时切换数据库日志级别（silent/error/warn/info）
+func SetLogLevel(level string) error {
+	if queryLogger == nil {
+		return errors.New("数据库日志未初始化")
+	}
+	l, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	queryLogger.SetLevel(l)
+	return nil
+}
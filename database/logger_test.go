@@ -0,0 +1,163 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// logEntries 解析查询日志输出的JSON行
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]logger.LogLevel{
+		"silent": logger.Silent,
+		"error":  logger.Error,
+		" Warn ": logger.Warn,
+		"INFO":   logger.Info,
+	}
+	for in, want := range tests {
+		if got, err := ParseLogLevel(in); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLogLevel("debug"); err == nil {
+		t.Error("ParseLogLevel(\"debug\") 未返回错误")
+	}
+}
+
+func TestQueryLoggerTrace(t *testing.T) {
+	query := func() (string, int64) { return "SELECT * FROM `users` WHERE id = 1", 1 }
+	fast := time.Now()
+	slow := time.Now().Add(-time.Second)
+	failed := errors.New("connection reset")
+
+	tests := []struct {
+		name      string
+		level     logger.LogLevel
+		begin     time.Time
+		err       error
+		wantLevel string
+		wantMsg   string
+	}{
+		{"warn 级别不记录普通查询", logger.Warn, fast, nil, "", ""},
+		{"warn 级别记录慢查询", logger.Warn, slow, nil, "WARN", "slow query"},
+		{"error 级别不记录慢查询", logger.Error, slow, nil, "", ""},
+		{"error 级别记录失败的查询", logger.Error, fast, failed, "ERROR", "query failed"},
+		{"不把记录不存在当作错误", logger.Error, fast, gorm.ErrRecordNotFound, "", ""},
+		{"info 级别记录全部查询", logger.Info, fast, nil, "INFO", "query"},
+		{"silent 不记录失败的查询", logger.Silent, fast, failed, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := NewQueryLogger(&buf, QueryLoggerOptions{Level: tt.level, SlowThreshold: 100 * time.Millisecond, IgnoreRecordNotFoundError: true})
+			l.Trace(context.Background(), tt.begin, query, tt.err)
+
+			entries := logEntries(t, &buf)
+			if tt.wantMsg == "" {
+				if len(entries) != 0 {
+					t.Errorf("不应记录日志: %v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("记录了 %d 条日志, want 1", len(entries))
+			}
+			entry := entries[0]
+			if entry["level"] != tt.wantLevel || entry["msg"] != tt.wantMsg {
+				t.Errorf("日志 = %v/%v, want %s/%s", entry["level"], entry["msg"], tt.wantLevel, tt.wantMsg)
+			}
+			if entry["sql"] != "SELECT * FROM `users` WHERE id = 1" || entry["rows"] != float64(1) {
+				t.Errorf("日志中的SQL和行数 = %v/%v", entry["sql"], entry["rows"])
+			}
+			if tt.wantMsg == "slow query" && entry["slow"] != true {
+				t.Errorf("慢查询未标记 slow: %v", entry)
+			}
+			if tt.err != nil && entry["error"] != tt.err.Error() {
+				t.Errorf("error = %v, want %v", entry["error"], tt.err)
+			}
+		})
+	}
+}
+
+func TestQueryLoggerLevelChanges(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewQueryLogger(&buf, QueryLoggerOptions{Level: logger.Warn})
+
+	// db.Debug() 得到的副本不影响原日志的级别
+	debug := l.LogMode(logger.Info)
+	if l.Level() != logger.Warn {
+		t.Errorf("LogMode 修改了原日志的级别: %v", l.Level())
+	}
+	debug.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	if n := len(logEntries(t, &buf)); n != 1 {
+		t.Errorf("副本记录了 %d 条日志, want 1", n)
+	}
+
+	l.SetLevel(logger.Info)
+	l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	if n := len(logEntries(t, &buf)); n != 1 {
+		t.Errorf("切换到 info 后记录了 %d 条日志, want 1", n)
+	}
+}
+
+func TestQueryLoggerRedactsParams(t *testing.T) {
+	l := NewQueryLogger(nil, QueryLoggerOptions{RedactColumns: []string{"Password", " email "}})
+	tests := []struct {
+		sql    string
+		params []interface{}
+		want   []interface{}
+	}{
+		{
+			"INSERT INTO `users` (`username`,`email`,`password`,`age`) VALUES (?,?,?,?),(?,?,?,?)",
+			[]interface{}{"alice", "a@example.com", "secret", 30, "bob", "b@example.com", "hunter2", 20},
+			[]interface{}{"alice", redactedValue, redactedValue, 30, "bob", redactedValue, redactedValue, 20},
+		},
+		{
+			"SELECT * FROM `users` WHERE `users`.`email` = ? AND age > ?",
+			[]interface{}{"a@example.com", 18},
+			[]interface{}{redactedValue, 18},
+		},
+		{
+			"UPDATE `users` SET `password`=?,`updated_at`=? WHERE username IN (?,?)",
+			[]interface{}{"secret", "2024-01-01", "alice", "bob"},
+			[]interface{}{redactedValue, "2024-01-01", "alice", "bob"},
+		},
+		{
+			"SELECT * FROM users WHERE bio = 'password = ?' OR email LIKE ?",
+			[]interface{}{"a%"},
+			[]interface{}{redactedValue},
+		},
+	}
+	for _, tt := range tests {
+		_, got := l.ParamsFilter(context.Background(), tt.sql, tt.params...)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParamsFilter(%s) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+
+	all := NewQueryLogger(nil, QueryLoggerOptions{RedactColumns: []string{"*"}})
+	if _, got := all.ParamsFilter(context.Background(), "SELECT ? + ?", 1, 2); !reflect.DeepEqual(got, []interface{}{redactedValue, redactedValue}) {
+		t.Errorf("隐藏全部参数 = %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// registeredModels 已注册的模型（按依赖顺序排列，被依赖的表在前）
+var registeredModels = []interface{}{
+	&models.User{},
+	&models.Profile{},
+	&models.Post{},
+	&models.Comment{},
+	&models.CommentLike{},
+	&models.Course{},
+	&models.AuditLog{},
+	&models.OutboxEvent{},
+	&models.Webhook{},
+	&models.WebhookDelivery{},
+	&models.WebhookDeliveryAttempt{},
+	&models.SchemaMigration{},
+}
+
+// SetupJoinTables 为带有额外列的多对多中间表指定模型（user_courses 有选课时间、状态和成绩），
+// 迁移、结构对比和关联操作都按该模型处理中间表
+func SetupJoinTables(db *gorm.DB) error {
+	if err := db.SetupJoinTable(&models.User{}, "Courses", &models.UserCourse{}); err != nil {
+		return fmt.Errorf("设置中间表 user_courses 失败: %v", err)
+	}
+	if err := db.SetupJoinTable(&models.Course{}, "Users", &models.UserCourse{}); err != nil {
+		return fmt.Errorf("设置中间表 user_courses 失败: %v", err)
+	}
+	return nil
+}
+
+// Models 返回已注册的模型列表（按依赖顺序）
+func Models() []interface{} {
+	result := make([]interface{}, len(registeredModels))
+	copy(result, registeredModels)
+	return result
+}
+
+// parseModel 通过反射解析模型的GORM结构
+func parseModel(db *gorm.DB, model interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, fmt.Errorf("解析模型 %T 失败: %v", model, err)
+	}
+	return stmt.Schema, nil
+}
+
+// tableTarget 需要检查的表（已注册模型或其多对多中间表）
+type tableTarget struct {
+	schema    *schema.Schema
+	model     interface{} // 迁移器使用的模型值
+	joinTable bool
+}
+
+// registeredTables 解析所有已注册模型及其中间表（中间表排在最后，同名只保留一次）
+func registeredTables(db *gorm.DB) ([]tableTarget, error) {
+	var targets, joins []tableTarget
+	seen := make(map[string]bool)
+
+	for _, model := range Models() {
+		s, err := parseModel(db, model)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, tableTarget{schema: s, model: model})
+		seen[s.Table] = true
+
+		for _, jt := range joinTables(s) {
+			if seen[jt.Table] {
+				continue
+			}
+			seen[jt.Table] = true
+			joins = append(joins, tableTarget{
+				schema:    jt,
+				model:     reflect.New(jt.ModelType).Interface(),
+				joinTable: true,
+			})
+		}
+	}
+
+	sort.Slice(joins, func(i, j int) bool {
+		return joins[i].schema.Table < joins[j].schema.Table
+	})
+	return append(targets, joins...), nil
+}
+
+// schemasOf 提取表的GORM结构
+func schemasOf(targets []tableTarget) []*schema.Schema {
+	schemas := make([]*schema.Schema, 0, len(targets))
+	for _, t := range targets {
+		schemas = append(schemas, t.schema)
+	}
+	return schemas
+}
+
+// joinTables 返回模型声明的多对多中间表
+func joinTables(s *schema.Schema) []*schema.Schema {
+	var tables []*schema.Schema
+	for _, rel := range s.Relationships.Relations {
+		if rel.JoinTable != nil {
+			tables = append(tables, rel.JoinTable)
+		}
+	}
+	return tables
+}
+
+// foreignKeyNames 返回各表上由外键约束生成的名称（MySQL会为外键自动创建同名索引）
+func foreignKeyNames(schemas []*schema.Schema) map[string]map[string]bool {
+	names := make(map[string]map[string]bool)
+	for _, s := range schemas {
+		for _, rel := range s.Relationships.Relations {
+			constraint := rel.ParseConstraint()
+			if constraint == nil {
+				continue
+			}
+			table := constraint.Schema.Table
+			if names[table] == nil {
+				names[table] = make(map[string]bool)
+			}
+			names[table][constraint.Name] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"exercise/config"
+
+	"github.com/fsnotify/fsnotify"
+	"gorm.io/gorm/logger"
+)
+
+// ErrRestartRequired 配置变更中包含无法热更新的项
+var ErrRestartRequired = errors.New("部分配置需重启后生效")
+
+// reloadDebounce 配置文件连续变更时的合并间隔（编辑器保存时常触发多次事件）
+const reloadDebounce = 300 * time.Millisecond
+
+// hotReloadable 可在运行时应用到连接池和日志的配置项
+var hotReloadable = map[string]bool{
+	"db.max_open_conns":    true,
+	"db.max_idle_conns":    true,
+	"db.conn_max_lifetime": true,
+	"db.log_mode":          true,
+	"db.log_level":         true,
+	"db.slow_threshold":    true,
+}
+
+var (
+	currentConfig *config.Config // 当前生效的配置
+	reloadMu      sync.Mutex
+)
+
+// CurrentConfig 返回当前生效的配置
+func CurrentConfig() *config.Config {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	return currentConfig
+}
+
+// ReloadConfig 重新加载配置并应用可热更新的项
+func ReloadConfig() error {
+	cfg := CurrentConfig()
+	if cfg == nil {
+		return errors.New("数据库未初始化")
+	}
+	next, err := cfg.Reload()
+	if err != nil {
+		return fmt.Errorf("重新加载配置失败: %v", err)
+	}
+	return ApplyConfig(next)
+}
+
+// ApplyConfig 将新配置中可热更新的项（连接池、日志）应用到当前连接
+// 其余变更（如主机、账号）不会生效，记录日志并返回 ErrRestartRequired
+func ApplyConfig(next *config.Config) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if DB == nil || currentConfig == nil {
+		return errors.New("数据库未初始化")
+	}
+
+	var applied, rejected []string
+	for _, key := range currentConfig.Changes(next) {
+		if hotReloadable[key] {
+			applied = append(applied, key)
+		} else {
+			rejected = append(rejected, key)
+		}
+	}
+	for _, key := range rejected {
+		log.Printf("⚠️  配置项 %s 已修改，但需重启后才能生效，本次忽略", key)
+	}
+	if len(applied) == 0 {
+		if len(rejected) > 0 {
+			return fmt.Errorf("%w: %v", ErrRestartRequired, rejected)
+		}
+		return nil
+	}
+
+	// 先计算日志级别，避免部分应用后才发现配置有误
+	level := logger.Silent
+	if next.DB.LogMode {
+		var err error
+		if level, err = ParseLogLevel(next.DB.LogLevel); err != nil {
+			return err
+		}
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("获取数据库连接池失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(next.DB.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(next.DB.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(next.DB.ConnMaxLifetime)
+	if queryLogger != nil {
+		queryLogger.SetLevel(level)
+		queryLogger.SetSlowThreshold(next.DB.SlowThreshold)
+	}
+
+	// 只记录已生效的项，未生效的项保持原值，下次重新加载时仍会提示
+	updated := *currentConfig
+	updated.DB.MaxOpenConns = next.DB.MaxOpenConns
+	updated.DB.MaxIdleConns = next.DB.MaxIdleConns
+	updated.DB.ConnMaxLifetime = next.DB.ConnMaxLifetime
+	updated.DB.LogMode = next.DB.LogMode
+	updated.DB.LogLevel = next.DB.LogLevel
+	updated.DB.SlowThreshold = next.DB.SlowThreshold
+	currentConfig = &updated
+
+	log.Printf("🔄 配置已热更新: %v", applied)
+	if len(rejected) > 0 {
+		return fmt.Errorf("%w: %v", ErrRestartRequired, rejected)
+	}
+	return nil
+}
+
+// WatchConfig 监听配置文件变更和 SIGHUP 信号，触发时重新加载配置，ctx 取消后停止
+func WatchConfig(ctx context.Context) error {
+	cfg := CurrentConfig()
+	if cfg == nil {
+		return errors.New("数据库未初始化")
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	// 监听所在目录而不是文件本身，编辑器通常以替换文件的方式保存
+	var events <-chan fsnotify.Event
+	var watchErrors <-chan error
+	var watcher *fsnotify.Watcher
+	if file := cfg.File(); file != "" {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			signal.Stop(hup)
+			return fmt.Errorf("创建配置文件监听失败: %v", err)
+		}
+		if err := w.Add(filepath.Dir(file)); err != nil {
+			w.Close()
+			signal.Stop(hup)
+			return fmt.Errorf("监听配置文件 %s 失败: %v", file, err)
+		}
+		watcher, events, watchErrors = w, w.Events, w.Errors
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Println("收到 SIGHUP，重新加载配置")
+				reload()
+			case ev := <-events:
+				if filepath.Clean(ev.Name) == filepath.Clean(cfg.File()) && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(reloadDebounce)
+				}
+			case <-debounce:
+				debounce = nil
+				log.Printf("配置文件 %s 已修改，重新加载配置", cfg.File())
+				reload()
+			case err := <-watchErrors:
+				log.Printf("配置文件监听出错: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reload 重新加载配置并记录结果
+func reload() {
+	if err := ReloadConfig(); err != nil && !errors.Is(err, ErrRestartRequired) {
+		log.Printf("❌ %v，继续使用原配置", err)
+	}
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"exercise/config"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useReloadDB 使用内存数据库和 cfg 替换全局连接，测试结束后恢复
+func useReloadDB(t *testing.T, cfg *config.Config) *gorm.DB {
+	t.Helper()
+	level, err := ParseLogLevel(cfg.DB.LogLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ql := NewQueryLogger(io.Discard, QueryLoggerOptions{Level: level})
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: ql})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+
+	reloadMu.Lock()
+	oldDB, oldConfig, oldLogger := DB, currentConfig, queryLogger
+	DB, currentConfig, queryLogger = db, cfg, ql
+	reloadMu.Unlock()
+	t.Cleanup(func() {
+		reloadMu.Lock()
+		DB, currentConfig, queryLogger = oldDB, oldConfig, oldLogger
+		reloadMu.Unlock()
+		sqlDB.Close()
+	})
+	return db
+}
+
+// waitFor 轮询直到 cond 成立或超时
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWatchConfigReloadsOnFileChange(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("db:\n  max_idle_conns: 2\n  max_open_conns: 5\n  log_level: warn\n")
+
+	cfg, err := config.Load(config.LoadOptions{ConfigFile: file, EnvFile: filepath.Join(dir, ".env")})
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	db := useReloadDB(t, cfg)
+	sqlDB, _ := db.DB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConfig(ctx); err != nil {
+		t.Fatalf("WatchConfig 失败: %v", err)
+	}
+
+	write("db:\n  max_idle_conns: 2\n  max_open_conns: 7\n  log_level: info\n")
+	waitFor(t, "配置文件热更新", func() bool {
+		return sqlDB.Stats().MaxOpenConnections == 7
+	})
+	if got := queryLogger.Level(); got != logger.Info {
+		t.Errorf("日志级别 = %v, want %v", got, logger.Info)
+	}
+	if got := CurrentConfig().DB.LogLevel; got != "info" {
+		t.Errorf("当前配置的 log_level = %s, want info", got)
+	}
+}
+
+func TestWatchConfigReloadsOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_MAX_IDLE_CONNS", "2")
+	t.Setenv("DB_MAX_OPEN_CONNS", "5")
+	t.Setenv("DB_LOG_LEVEL", "error")
+
+	cfg, err := config.Load(config.LoadOptions{EnvFile: filepath.Join(dir, ".env")})
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	db := useReloadDB(t, cfg)
+	sqlDB, _ := db.DB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConfig(ctx); err != nil {
+		t.Fatalf("WatchConfig 失败: %v", err)
+	}
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "9")
+	t.Setenv("DB_LOG_LEVEL", "warn")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "SIGHUP 热更新", func() bool {
+		return sqlDB.Stats().MaxOpenConnections == 9
+	})
+	if got := queryLogger.Level(); got != logger.Warn {
+		t.Errorf("日志级别 = %v, want %v", got, logger.Warn)
+	}
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrSchemaOutdated 数据库结构落后于模型定义
+var ErrSchemaOutdated = errors.New("数据库结构与模型定义不一致")
+
+// StatusReport 数据库状态报告
+type StatusReport struct {
+	CheckedAt         time.Time     `json:"checked_at"`
+	Database          string        `json:"database"`
+	ServerVersion     string        `json:"server_version"`
+	Tables            []TableStatus `json:"tables"`
+	PendingMigrations []string      `json:"pending_migrations"`
+	Pool              PoolStats     `json:"pool"`
+}
+
+// TableStatus 单个表的状态
+type TableStatus struct {
+	Name           string   `json:"name"`
+	Model          string   `json:"model"` // 对应的模型名，中间表为空
+	Exists         bool     `json:"exists"`
+	Rows           int64    `json:"rows"`
+	MissingColumns []string `json:"missing_columns,omitempty"`
+	MissingIndexes []string `json:"missing_indexes,omitempty"`
+	ExtraIndexes   []string `json:"extra_indexes,omitempty"`
+}
+
+// PoolStats 连接池统计
+type PoolStats struct {
+	MaxOpenConnections int           `json:"max_open_connections"`
+	OpenConnections    int           `json:"open_connections"`
+	InUse              int           `json:"in_use"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"wait_count"`
+	WaitDuration       time.Duration `json:"wait_duration"`
+	MaxIdleClosed      int64         `json:"max_idle_closed"`
+	MaxLifetimeClosed  int64         `json:"max_lifetime_closed"`
+}
+
+// Healthy 判断数据库结构是否与模型一致
+func (r *StatusReport) Healthy() bool {
+	return len(r.PendingMigrations) == 0
+}
+
+// JSON 以JSON格式输出报告
+func (r *StatusReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// String 以文本格式输出报告
+func (r *StatusReport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "数据库: %s (MySQL %s)\n", r.Database, r.ServerVersion)
+	fmt.Fprintf(&b, "检查时间: %s\n", r.CheckedAt.Format(time.RFC3339))
+
+	b.WriteString("\n表:\n")
+	for _, t := range r.Tables {
+		if !t.Exists {
+			fmt.Fprintf(&b, "  ⚠️ %-16s 不存在\n", t.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "  ✅ %-16s %d 行\n", t.Name, t.Rows)
+		for _, col := range t.MissingColumns {
+			fmt.Fprintf(&b, "     - 缺少列: %s\n", col)
+		}
+		for _, idx := range t.MissingIndexes {
+			fmt.Fprintf(&b, "     - 缺少索引: %s\n", idx)
+		}
+		for _, idx := range t.ExtraIndexes {
+			fmt.Fprintf(&b, "     + 多余索引: %s\n", idx)
+		}
+	}
+
+	b.WriteString("\n待执行迁移:\n")
+	if len(r.PendingMigrations) == 0 {
+		b.WriteString("  无\n")
+	}
+	for _, m := range r.PendingMigrations {
+		fmt.Fprintf(&b, "  - %s\n", m)
+	}
+
+	b.WriteString("\n连接池:\n")
+	fmt.Fprintf(&b, "  最大连接数: %d\n", r.Pool.MaxOpenConnections)
+	fmt.Fprintf(&b, "  打开连接: %d\n", r.Pool.OpenConnections)
+	fmt.Fprintf(&b, "  正在使用: %d\n", r.Pool.InUse)
+	fmt.Fprintf(&b, "  空闲连接: %d\n", r.Pool.Idle)
+	fmt.Fprintf(&b, "  等待次数: %d (共 %v)\n", r.Pool.WaitCount, r.Pool.WaitDuration)
+
+	return b.String()
+}
+
+// CheckStatus 检查数据库状态
+// 当数据库结构落后于模型定义时，返回报告的同时返回 ErrSchemaOutdated
+func CheckStatus() (*StatusReport, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+
+	// 检查连接
+	var result int
+	if err := db.Raw("SELECT 1").Scan(&result).Error; err != nil {
+		return nil, fmt.Errorf("数据库连接检查失败: %v", err)
+	}
+
+	report := &StatusReport{
+		CheckedAt: time.Now(),
+		Database:  db.Migrator().CurrentDatabase(),
+	}
+
+	if err := db.Raw("SELECT VERSION()").Scan(&report.ServerVersion).Error; err != nil {
+		return nil, fmt.Errorf("获取数据库版本失败: %v", err)
+	}
+
+	// 反射解析已注册模型
+	targets, err := registeredTables(db)
+	if err != nil {
+		return nil, err
+	}
+	schemas := schemasOf(targets)
+	expected := expectedIndexes(db, targets)
+	fkNames := foreignKeyNames(schemas)
+
+	for _, t := range targets {
+		modelName := t.schema.Name
+		if t.joinTable {
+			modelName = ""
+		}
+		status, pending, err := checkTable(db, t.schema, modelName, expected[t.schema.Table], fkNames[t.schema.Table])
+		if err != nil {
+			return nil, err
+		}
+		report.Tables = append(report.Tables, status)
+		report.PendingMigrations = append(report.PendingMigrations, pending...)
+	}
+
+	// 连接池状态
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接池失败: %v", err)
+	}
+	stats := sqlDB.Stats()
+	report.Pool = PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration,
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+
+	if !report.Healthy() {
+		return report, ErrSchemaOutdated
+	}
+	return report, nil
+}
+
+// checkTable 检查单个表的存在性、行数、列和索引
+func checkTable(db *gorm.DB, s *schema.Schema, modelName string, expected, fkNames map[string]bool) (TableStatus, []string, error) {
+	status := TableStatus{Name: s.Table, Model: modelName}
+	var pending []string
+
+	migrator := db.Migrator()
+	if !migrator.HasTable(s.Table) {
+		pending = append(pending, fmt.Sprintf("创建表 %s", s.Table))
+		return status, pending, nil
+	}
+	status.Exists = true
+
+	if err := db.Table(s.Table).Count(&status.Rows).Error; err != nil {
+		return status, nil, fmt.Errorf("统计表 %s 行数失败: %v", s.Table, err)
+	}
+
+	// 检查列
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.IgnoreMigration {
+			continue
+		}
+		if !migrator.HasColumn(s.Table, field.DBName) {
+			status.MissingColumns = append(status.MissingColumns, field.DBName)
+			pending = append(pending, fmt.Sprintf("添加列 %s.%s", s.Table, field.DBName))
+		}
+	}
+
+	// 检查索引
+	indexes, err := migrator.GetIndexes(s.Table)
+	if err != nil {
+		return status, nil, fmt.Errorf("获取表 %s 索引失败: %v", s.Table, err)
+	}
+	live := make(map[string]bool)
+	for _, idx := range indexes {
+		if pk, _ := idx.PrimaryKey(); pk {
+			continue
+		}
+		live[idx.Name()] = true
+		if !expected[idx.Name()] && !fkNames[idx.Name()] {
+			status.ExtraIndexes = append(status.ExtraIndexes, idx.Name())
+		}
+	}
+	for name := range expected {
+		if !live[name] {
+			status.MissingIndexes = append(status.MissingIndexes, name)
+		}
+	}
+	sort.Strings(status.MissingIndexes)
+	sort.Strings(status.ExtraIndexes)
+	for _, name := range status.MissingIndexes {
+		pending = append(pending, fmt.Sprintf("创建索引 %s.%s", s.Table, name))
+	}
+
+	return status, pending, nil
+}
+
+// expectedIndexes 汇总模型标签、唯一约束和模型声明的索引名称
+func expectedIndexes(db *gorm.DB, targets []tableTarget) map[string]map[string]bool {
+	expected := make(map[string]map[string]bool)
+	add := func(table, name string) {
+		if expected[table] == nil {
+			expected[table] = make(map[string]bool)
+		}
+		expected[table][name] = true
+	}
+
+	for _, t := range targets {
+		for _, idx := range t.schema.ParseIndexes() {
+			add(t.schema.Table, idx.Name)
+		}
+		for name := range t.schema.ParseUniqueConstraints() {
+			add(t.schema.Table, name)
+		}
+	}
+	for table, names := range declaredIndexNames(db, targets) {
+		for _, name := range names {
+			add(table, name)
+		}
+	}
+
+	return expected
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"exercise/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// statusDB 打开只有 users 表和一个用户的 SQLite 数据库，只检查表结构，不需要配置和全局连接
+func statusDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库的每个连接是独立的库
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	// 跳过注册事件，不需要 outbox 表
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "secret123", Age: 30}
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCheckTable(t *testing.T) {
+	// 迁移只建表，不建模型声明的索引
+	db := statusDB(t)
+	// SQLite 删除列时会重建表，之后需要补回标签索引
+	if err := db.Migrator().DropColumn(&models.User{}, "deactivation_reason"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"idx_users_deleted_at", "idx_users_deactivated_at"} {
+		if err := db.Migrator().CreateIndex(&models.User{}, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec("CREATE INDEX idx_users_legacy ON users (age)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := registeredTables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := expectedIndexes(db, targets)
+	fkNames := foreignKeyNames(schemasOf(targets))
+	byTable := make(map[string]tableTarget)
+	for _, target := range targets {
+		byTable[target.schema.Table] = target
+	}
+
+	users := byTable["users"].schema
+	status, pending, err := checkTable(db, users, users.Name, expected["users"], fkNames["users"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Exists || status.Rows != 1 || status.Model != "User" {
+		t.Errorf("users = %+v, want 存在、1 行、模型 User", status)
+	}
+	if !slices.Equal(status.MissingColumns, []string{"deactivation_reason"}) {
+		t.Errorf("缺少的列 = %v, want [deactivation_reason]", status.MissingColumns)
+	}
+	if want := []string{"uni_users_active_email", "uni_users_active_username"}; !slices.Equal(status.MissingIndexes, want) {
+		t.Errorf("缺少的索引 = %v, want %v", status.MissingIndexes, want)
+	}
+	if !slices.Equal(status.ExtraIndexes, []string{"idx_users_legacy"}) {
+		t.Errorf("多余的索引 = %v, want [idx_users_legacy]", status.ExtraIndexes)
+	}
+	for _, want := range []string{"添加列 users.deactivation_reason", "创建索引 users.uni_users_active_email"} {
+		if !slices.Contains(pending, want) {
+			t.Errorf("待执行迁移缺少 %q: %v", want, pending)
+		}
+	}
+
+	posts := byTable["posts"].schema
+	status, pending, err = checkTable(db, posts, posts.Name, expected["posts"], fkNames["posts"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Exists || !slices.Equal(pending, []string{"创建表 posts"}) {
+		t.Errorf("posts = %+v, 待执行 %v, want 不存在、创建表", status, pending)
+	}
+}
+
+func TestStatusReportOutput(t *testing.T) {
+	report := &StatusReport{
+		Database:      "gorm_learning_db",
+		ServerVersion: "8.0.36",
+		Tables: []TableStatus{
+			{Name: "users", Model: "User", Exists: true, Rows: 3, MissingIndexes: []string{"uni_users_active_email"}},
+			{Name: "posts", Model: "Post"},
+		},
+		PendingMigrations: []string{"创建索引 users.uni_users_active_email", "创建表 posts"},
+		Pool:              PoolStats{MaxOpenConnections: 10, OpenConnections: 2},
+	}
+	if report.Healthy() {
+		t.Error("有待执行迁移时 Healthy() = true")
+	}
+
+	text := report.String()
+	for _, want := range []string{"gorm_learning_db (MySQL 8.0.36)", "users", "3 行", "缺少索引: uni_users_active_email", "posts", "不存在", "- 创建表 posts", "最大连接数: 10"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("文本报告缺少 %q:\n%s", want, text)
+		}
+	}
+
+	data, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded StatusReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Tables) != 2 || decoded.Tables[1].Exists || len(decoded.PendingMigrations) != 2 || decoded.Pool.MaxOpenConnections != 10 {
+		t.Errorf("JSON 报告 = %s", data)
+	}
+
+	if !(&StatusReport{}).Healthy() {
+		t.Error("没有待执行迁移时 Healthy() = false")
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// 可以通过重试整个事务解决的 MySQL 错误码
+const (
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+// 事务重试策略
+const (
+	defaultTxRetries = 3
+	txRetryBackoff   = 20 * time.Millisecond
+)
+
+// txContextKey 当前事务在 context 中的键
+type txContextKey struct{}
+
+// txState WithinTx 开启的事务（嵌套事务与外层共享 retryable）
+type txState struct {
+	tx        *gorm.DB
+	retryable *atomic.Bool // 事务中出现过死锁或锁等待超时
+}
+
+// txOptions 事务选项
+type txOptions struct {
+	sql        sql.TxOptions
+	maxRetries int
+}
+
+// TxOption 事务选项，只对最外层事务生效
+type TxOption func(*txOptions)
+
+// WithIsolation 指定事务隔离级别（默认使用数据库的设置，MySQL 为 REPEATABLE READ）
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) { o.sql.Isolation = level }
+}
+
+// ReadOnly 以只读事务执行
+func ReadOnly() TxOption {
+	return func(o *txOptions) { o.sql.ReadOnly = true }
+}
+
+// WithRetries 指定死锁、锁等待超时时的最大重试次数（默认 3，0 表示不重试）
+func WithRetries(n int) TxOption {
+	return func(o *txOptions) { o.maxRetries = n }
+}
+
+// TxManager 事务管理器：在 fn 中使用 WithContext(ctx) 的仓储都加入同一事务
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
+
+// txManager 事务管理器实现
+type txManager struct {
+	db *gorm.DB
+}
+
+// NewTxManager 创建事务管理器
+func NewTxManager() TxManager {
+	return &txManager{db: GetDB()}
+}
+
+// WithinTx 在事务中执行 fn，fn 返回错误或 panic 时回滚
+// ctx 中已有事务时以保存点嵌套执行，fn 失败只回滚到保存点，外层事务可以继续；
+// 最外层事务遇到死锁（1213）或锁等待超时（1205）时整体回滚并重新执行 fn，
+// 因此 fn 除数据库操作外不应有其他副作用
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if outer := txFrom(ctx); outer != nil {
+		return outer.tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txContextKey{}, &txState{tx: tx, retryable: outer.retryable}))
+		})
+	}
+
+	if m.db == nil {
+		return fmt.Errorf("数据库连接未初始化")
+	}
+	o := txOptions{maxRetries: defaultTxRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	for attempt := 1; ; attempt++ {
+		state := &txState{retryable: new(atomic.Bool)}
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			state.tx = tx
+			return fn(context.WithValue(ctx, txContextKey{}, state))
+		}, &o.sql)
+		if err == nil || attempt > o.maxRetries || !(state.retryable.Load() || retryableTxError(err)) {
+			return err
+		}
+
+		log.Printf("🔁 事务遇到死锁或锁等待超时，第 %d 次重试: %v", attempt, err)
+		backoff := time.Duration(attempt)*txRetryBackoff + time.Duration(rand.Int63n(int64(txRetryBackoff)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// Conn 返回 ctx 中由 WithinTx 开启的事务，没有事务时返回 db，两者都使用 ctx
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if state := txFrom(ctx); state != nil {
+		return state.tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// InTx 判断 ctx 中是否有 WithinTx 开启的事务
+func InTx(ctx context.Context) bool {
+	return txFrom(ctx) != nil
+}
+
+// txFrom 取出 ctx 中的事务
+func txFrom(ctx context.Context) *txState {
+	if ctx == nil {
+		return nil
+	}
+	state, _ := ctx.Value(txContextKey{}).(*txState)
+	return state
+}
+
+// retryableTxError 判断是否为死锁或锁等待超时
+func retryableTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout)
+}
+
+// RegisterTxCallbacks 注册事务回调：记录 WithinTx 中的语句遇到的死锁和锁等待超时，
+// 调用方把错误包装成其他错误返回时，事务管理器仍能判断是否应当重试
+func RegisterTxCallbacks(db *gorm.DB) error {
+	track := func(db *gorm.DB) {
+		if db.Error == nil || !retryableTxError(db.Error) {
+			return
+		}
+		if state := txFrom(db.Statement.Context); state != nil {
+			state.retryable.Store(true)
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("tx:track_create", track); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("tx:track_query", track); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("tx:track_update", track); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("tx:track_delete", track); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("tx:track_row", track); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("tx:track_raw", track)
+}
@@ -0,0 +1,87 @@
+// Package testdb 为测试提供独立的 SQLite 数据库
+//
+// 表按已注册的模型创建，并注册与 InitDatabaseWithConfig 相同的回调；
+// MySQL 特有的语法（全文索引、JSON_TABLE 等）由各仓储按方言退化处理
+package testdb
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exercise/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// dialector SQLite 方言，把 MySQL 的 enum 类型建为 text
+type dialector struct {
+	*sqlite.Dialector
+}
+
+// DataTypeOf 返回列类型
+func (d dialector) DataTypeOf(field *schema.Field) string {
+	if strings.HasPrefix(strings.ToLower(string(field.DataType)), "enum(") {
+		return "text"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+// Migrator 返回使用本方言建表的迁移器
+func (d dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return sqlite.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+// Open 创建临时的 SQLite 数据库，建好全部表后设为 database.DB，测试结束时恢复并关闭
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(dialector{&sqlite.Dialector{DSN: dsn}}, &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := database.SetupJoinTables(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.RegisterAuditCallbacks(db); err != nil {
+		t.Fatalf("注册审计回调失败: %v", err)
+	}
+	if err := database.RegisterTxCallbacks(db); err != nil {
+		t.Fatalf("注册事务回调失败: %v", err)
+	}
+	if err := database.RegisterErrorCallbacks(db); err != nil {
+		t.Fatalf("注册错误翻译回调失败: %v", err)
+	}
+
+	for _, model := range database.Models() {
+		if err := db.AutoMigrate(model); err != nil {
+			t.Fatalf("创建表 %T 失败: %v", model, err)
+		}
+	}
+	// 与 Migrate 使用同一套语句创建声明的索引（SQLite 跳过全文索引）
+	if err := database.CreateIndexes(db); err != nil {
+		t.Fatalf("创建索引失败: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 审计动作
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionErase  = "erase" // 按用户要求擦除个人数据
+)
+
+// AuditLog 审计日志：记录谁在何时修改了哪条数据
+type AuditLog struct {
+	ID         uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Actor      string         `gorm:"type:varchar(100);not null;default:''" json:"actor"`           // 操作人，未知时为空
+	EntityType string         `gorm:"type:varchar(50);not null" json:"entity_type"`                 // 实体类型（表名），如 users
+	EntityID   uint           `gorm:"not null" json:"entity_id"`                                    // 实体主键
+	Action     string         `gorm:"type:varchar(10);not null" json:"action"`                      // create/update/delete/erase
+	Changes    datatypes.JSON `gorm:"type:json" json:"changes"`                                     // 变更字段：{"字段": {"before": 旧值, "after": 新值}}
+	RequestID  string         `gorm:"type:varchar(64);not null;default:'';index" json:"request_id"` // 请求ID，用于关联同一请求的多次变更
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName 自定义表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// Indexes 额外索引
+func (AuditLog) Indexes() []Index {
+	return []Index{
+		{Name: "idx_audit_logs_entity", Columns: Columns("entity_type", "entity_id", "created_at")},
+		{Name: "idx_audit_logs_actor_created_at", Columns: Columns("actor", "created_at")},
+	}
+}
+
+// FieldChange 单个字段的变更
+type FieldChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
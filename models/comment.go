@@ -11,7 +11,7 @@ type Comment struct {
 	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
 	Content   string         `gorm:"type:text;not null" json:"content"`
 	Rating    int            `gorm:"default:5;check:rating>=1 AND rating<=5" json:"rating"` // 评分，约束1-5
-	Likes     int            `gorm:"default:0" json:"likes"`                                // 点赞数
+	Likes     int            `gorm:"default:0" json:"likes"`                                // 点赞数（comment_likes 的冗余计数）
 	PostID    uint           `gorm:"index;not null" json:"post_id"`                         // 外键，指向文章
 	UserID    uint           `gorm:"index;not null" json:"user_id"`                         // 外键，指向用户
 	ParentID  *uint          `gorm:"index" json:"parent_id,omitempty"`                      // 父评论ID，支持嵌套评论
@@ -31,6 +31,14 @@ func (Comment) TableName() string {
 	return "comments"
 }
 
+// Indexes 额外索引
+func (Comment) Indexes() []Index {
+	return []Index{
+		// 查询某用户在某文章下的评论
+		{Name: "idx_comments_user_id_post_id", Columns: Columns("user_id", "post_id")},
+	}
+}
+
 // BeforeCreate 创建前的钩子
 func (c *Comment) BeforeCreate(tx *gorm.DB) error {
 	// 验证评分范围
@@ -42,6 +50,11 @@ func (c *Comment) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// AfterCreate 创建后的钩子：在同一事务中记录 CommentAdded 事件
+func (c *Comment) AfterCreate(tx *gorm.DB) error {
+	return RecordEvent(tx, CommentAdded{CommentID: c.ID, PostID: c.PostID, UserID: c.UserID, ParentID: c.ParentID})
+}
+
 // IsReply 判断是否为回复评论
 func (c *Comment) IsReply() bool {
 	return c.ParentID != nil
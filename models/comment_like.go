@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// CommentLike 评论点赞记录（复合主键，每个用户对同一评论只能点赞一次）
+// Comment.Likes 为冗余计数，与本表在同一事务中增减
+type CommentLike struct {
+	CommentID uint      `gorm:"primaryKey;autoIncrement:false" json:"comment_id"`
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false;index" json:"user_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// 关联关系
+	Comment Comment `gorm:"foreignKey:CommentID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	User    User    `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 自定义表名
+func (CommentLike) TableName() string {
+	return "comment_likes"
+}
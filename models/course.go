@@ -10,21 +10,22 @@ import (
 
 // Course 课程模型（多对多关联）
 type Course struct {
-	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name        string         `gorm:"type:varchar(255);not null" json:"name"` // 课程名称
-	Title       string         `gorm:"type:varchar(255);unique;not null" json:"title"`
-	Description string         `gorm:"type:text" json:"description"`
-	Code        string         `gorm:"type:varchar(20);unique;not null" json:"code"` // 课程代码
-	Category    string         `gorm:"type:varchar(50)" json:"category"`             // 课程分类
-	Price       float64        `gorm:"type:decimal(10,2);default:0.00;check:price>=0" json:"price"`
-	Duration    int            `gorm:"default:40" json:"duration"` // 课时数
-	IsActive    bool           `gorm:"default:true" json:"is_active"`
-	Tags        datatypes.JSON `gorm:"type:json" json:"tags"`     // JSON存储标签
-	Schedule    datatypes.JSON `gorm:"type:json" json:"schedule"` // JSON存储课程安排
-	StartDate   time.Time      `gorm:"index" json:"start_date"`
-	EndDate     time.Time      `gorm:"index" json:"end_date"`
-	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	ID          uint                         `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string                       `gorm:"type:varchar(255);not null" json:"name"` // 课程名称
+	Title       string                       `gorm:"type:varchar(255);unique;not null" json:"title"`
+	Description string                       `gorm:"type:text" json:"description"`
+	Code        string                       `gorm:"type:varchar(20);unique;not null" json:"code"` // 课程代码
+	Category    string                       `gorm:"type:varchar(50)" json:"category"`             // 课程分类
+	Price       float64                      `gorm:"type:decimal(10,2);default:0.00;check:price>=0" json:"price"`
+	Duration    int                          `gorm:"default:40" json:"duration"` // 课时数
+	IsActive    bool                         `gorm:"default:true" json:"is_active"`
+	Tags        datatypes.JSON               `gorm:"type:json" json:"tags"`     // JSON存储标签
+	Schedule    datatypes.JSONType[Schedule] `gorm:"type:json" json:"schedule"` // JSON存储课程安排
+	StartDate   time.Time                    `gorm:"index" json:"start_date"`
+	EndDate     time.Time                    `gorm:"index" json:"end_date"`
+	CreatedAt   time.Time                    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time                    `gorm:"autoUpdateTime" json:"updated_at"`
+	Version     uint                         `gorm:"not null;default:1" json:"version"` // 乐观锁版本号，每次更新加一
 
 	// 多对多关联
 	Users    []User `gorm:"many2many:user_courses;" json:"users,omitempty"`
@@ -42,7 +43,14 @@ func (c *Course) BeforeSave(tx *gorm.DB) error {
 	if !c.EndDate.IsZero() && c.StartDate.After(c.EndDate) {
 		return fmt.Errorf("开始日期不能晚于结束日期")
 	}
-	return nil
+
+	// 验证标签格式
+	if err := ValidateTags(c.Tags); err != nil {
+		return err
+	}
+
+	// 验证课程安排
+	return c.Schedule.Data().Validate(c.StartDate, c.EndDate)
 }
 
 // IsOngoing 检查课程是否正在进行中
@@ -51,6 +59,18 @@ func (c *Course) IsOngoing() bool {
 	return c.IsActive && now.After(c.StartDate) && (c.EndDate.IsZero() || now.Before(c.EndDate))
 }
 
+// Occurrences 展开 [from, to) 区间内的全部课次
+func (c *Course) Occurrences(from, to time.Time) ([]Occurrence, error) {
+	return c.Schedule.Data().occurrences(c.StartDate, c.EndDate, from, to)
+}
+
+// 选课状态
+const (
+	EnrollmentEnrolled  = "enrolled"  // 正在选修
+	EnrollmentCompleted = "completed" // 已结课
+	EnrollmentDropped   = "dropped"   // 已退选
+)
+
 // UserCourse 用户选课中间表模型
 type UserCourse struct {
 	UserID     uint      `gorm:"primaryKey" json:"user_id"`
@@ -68,3 +88,8 @@ type UserCourse struct {
 func (UserCourse) TableName() string {
 	return "user_courses"
 }
+
+// AfterCreate 创建后的钩子：在同一事务中记录 Enrolled 事件
+func (uc *UserCourse) AfterCreate(tx *gorm.DB) error {
+	return RecordEvent(tx, Enrolled{UserID: uc.UserID, CourseID: uc.CourseID, EnrolledAt: uc.EnrolledAt})
+}
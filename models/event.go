@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 领域事件类型
+const (
+	EventUserRegistered = "user.registered"
+	EventPostPublished  = "post.published"
+	EventCommentAdded   = "comment.added"
+	EventEnrolled       = "course.enrolled"
+)
+
+// EventTypes 返回全部领域事件类型
+func EventTypes() []string {
+	return []string{EventUserRegistered, EventPostPublished, EventCommentAdded, EventEnrolled}
+}
+
+// Event 领域事件
+// 载荷只包含ID等非个人数据，订阅者需要详细信息时按ID查询，擦除个人数据后 outbox 中不会有残留
+type Event interface {
+	EventType() string
+}
+
+// UserRegistered 用户注册
+type UserRegistered struct {
+	UserID       uint      `json:"user_id"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// EventType 实现 Event 接口
+func (UserRegistered) EventType() string { return EventUserRegistered }
+
+// PostPublished 文章发布
+type PostPublished struct {
+	PostID      uint      `json:"post_id"`
+	AuthorID    uint      `json:"author_id"`
+	Slug        string    `json:"slug"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// EventType 实现 Event 接口
+func (PostPublished) EventType() string { return EventPostPublished }
+
+// CommentAdded 发表评论
+type CommentAdded struct {
+	CommentID uint  `json:"comment_id"`
+	PostID    uint  `json:"post_id"`
+	UserID    uint  `json:"user_id"`
+	ParentID  *uint `json:"parent_id,omitempty"`
+}
+
+// EventType 实现 Event 接口
+func (CommentAdded) EventType() string { return EventCommentAdded }
+
+// Enrolled 用户选课
+type Enrolled struct {
+	UserID     uint      `json:"user_id"`
+	CourseID   uint      `json:"course_id"`
+	EnrolledAt time.Time `json:"enrolled_at"`
+}
+
+// EventType 实现 Event 接口
+func (Enrolled) EventType() string { return EventEnrolled }
+
+// outbox 中事件的状态
+const (
+	OutboxPending   = "pending"   // 等待投递（包括失败后等待重试）
+	OutboxDelivered = "delivered" // 所有订阅者都已处理
+	OutboxDead      = "dead"      // 重试次数用尽，需要人工处理
+)
+
+// OutboxEvent outbox 表中的事件：与业务数据在同一事务中写入，由分发器投递给订阅者
+type OutboxEvent struct {
+	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventType     string         `gorm:"type:varchar(100);not null;index" json:"event_type"`        // 事件类型，如 user.registered
+	Payload       datatypes.JSON `gorm:"type:json;not null" json:"payload"`                         // 事件内容（JSON）
+	Status        string         `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending/delivered/dead
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`                        // 已开始投递的次数
+	LastError     string         `gorm:"type:text" json:"last_error,omitempty"`                     // 最近一次投递失败的原因
+	NextAttemptAt time.Time      `gorm:"not null" json:"next_attempt_at"`                           // 最早可以（再次）投递的时间
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+}
+
+// TableName 自定义表名
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+// Indexes 额外索引
+func (OutboxEvent) Indexes() []Index {
+	return []Index{
+		// 分发器按状态和时间取待投递的事件
+		{Name: "idx_outbox_status_next_attempt_at", Columns: Columns("status", "next_attempt_at")},
+	}
+}
+
+// Decode 将载荷解析到 v（对应事件类型的结构体指针）
+func (e *OutboxEvent) Decode(v Event) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("解析事件 %d（%s）失败: %v", e.ID, e.EventType, err)
+	}
+	return nil
+}
+
+// eventSkipKey 不记录事件的标记在 context 中的键
+type eventSkipKey struct{}
+
+// WithoutEvents 返回不记录领域事件的 context，用于生成示例数据、恢复备份等不代表真实业务动作的写入
+func WithoutEvents(ctx context.Context) context.Context {
+	return context.WithValue(ctx, eventSkipKey{}, true)
+}
+
+// RecordEvent 在 tx 所在的事务中把事件写入 outbox，事务回滚时事件一并丢弃
+// 模型钩子中传入钩子的 tx 即可；tx 的 context 带有 WithoutEvents 标记时不记录
+func RecordEvent(tx *gorm.DB, event Event) error {
+	if ctx := tx.Statement.Context; ctx != nil {
+		if skip, _ := ctx.Value(eventSkipKey{}).(bool); skip {
+			return nil
+		}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化事件 %s 失败: %v", event.EventType(), err)
+	}
+	// 钩子中的 tx 正在执行其他语句，使用新的会话（仍在同一事务中）
+	err = tx.Session(&gorm.Session{NewDB: true}).Create(&OutboxEvent{
+		EventType:     event.EventType(),
+		Payload:       datatypes.JSON(payload),
+		Status:        OutboxPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("记录事件 %s 失败: %v", event.EventType(), err)
+	}
+	return nil
+}
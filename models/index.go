@@ -0,0 +1,32 @@
+package models
+
+// Index 模型声明的额外索引
+// 单列的普通索引和唯一约束仍使用GORM标签，这里用于复合、前缀、降序、全文等索引
+type Index struct {
+	Name     string
+	Columns  []IndexColumn
+	Unique   bool
+	FullText bool   // 全文索引（仅MySQL支持，其他方言跳过）
+	Parser   string // 全文索引分词器，如 ngram
+}
+
+// IndexColumn 索引列
+type IndexColumn struct {
+	Name   string
+	Length int  // 前缀长度（仅MySQL支持）
+	Desc   bool // 降序
+}
+
+// Indexer 声明了额外索引的模型
+type Indexer interface {
+	Indexes() []Index
+}
+
+// Columns 按列名构造索引列
+func Columns(names ...string) []IndexColumn {
+	columns := make([]IndexColumn, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, IndexColumn{Name: name})
+	}
+	return columns
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// textColumnBytes MySQL TEXT 列的最大字节数
+const textColumnBytes = 65535
+
+// columnLimit 字符串列的长度上限
+type columnLimit struct {
+	name  string // json 字段名
+	field string // 结构体字段名
+	runes int    // varchar(n) 按字符计
+	bytes int    // text 按字节计
+}
+
+var (
+	varcharType = regexp.MustCompile(`(?i)\btype:varchar\((\d+)\)`)
+	textType    = regexp.MustCompile(`(?i)\btype:text\b`)
+	limitsCache sync.Map // reflect.Type -> []columnLimit
+)
+
+// columnLimits 从 gorm 标签中解析字符串字段的长度上限（按字段声明顺序）
+func columnLimits(t reflect.Type) []columnLimit {
+	if cached, ok := limitsCache.Load(t); ok {
+		return cached.([]columnLimit)
+	}
+
+	var limits []columnLimit
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() != reflect.String {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		tag := f.Tag.Get("gorm")
+		if m := varcharType.FindStringSubmatch(tag); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			limits = append(limits, columnLimit{name: name, field: f.Name, runes: n})
+		} else if textType.MatchString(tag) {
+			limits = append(limits, columnLimit{name: name, field: f.Name, bytes: textColumnBytes})
+		}
+	}
+
+	limitsCache.Store(t, limits)
+	return limits
+}
+
+// validateLengths 校验模型字符串字段不超过列长度
+func validateLengths(model interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	for _, limit := range columnLimits(v.Type()) {
+		value := v.FieldByName(limit.field).String()
+		if limit.runes > 0 && utf8.RuneCountInString(value) > limit.runes {
+			return fmt.Errorf("%s 不能超过%d个字符", limit.name, limit.runes)
+		}
+		if limit.bytes > 0 && len(value) > limit.bytes {
+			return fmt.Errorf("%s 不能超过%d字节", limit.name, limit.bytes)
+		}
+	}
+	return nil
+}
+
+// validateHTTPURL 校验 http/https 地址，空值视为有效
+func validateHTTPURL(name, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s %q 不是有效的http(s)地址", name, raw)
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ type Post struct {
 	AuthorID    uint           `gorm:"index" json:"author_id"`                                                  // 外键，指向作者
 	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	Version     uint           `gorm:"not null;default:1" json:"version"` // 乐观锁版本号，每次更新加一
 
 	// 关联关系
 	Author   User      `gorm:"foreignKey:AuthorID;references:ID" json:"author,omitempty"`
@@ -31,8 +32,26 @@ func (Post) TableName() string {
 	return "posts"
 }
 
+// Indexes 额外索引
+func (Post) Indexes() []Index {
+	return []Index{
+		// 按状态列出最新文章
+		{Name: "idx_posts_status_published_at", Columns: []IndexColumn{
+			{Name: "status"},
+			{Name: "published_at", Desc: true},
+		}},
+		// 标题和正文全文检索，ngram分词支持中文
+		{Name: "ft_posts_title_content", Columns: Columns("title", "content"), FullText: true, Parser: "ngram"},
+	}
+}
+
 // BeforeSave 保存前的钩子
 func (p *Post) BeforeSave(tx *gorm.DB) error {
+	// 验证标签格式
+	if err := ValidateTags(p.Tags); err != nil {
+		return err
+	}
+
 	// 自动设置发布时间
 	if p.Status == "published" && p.PublishedAt == nil {
 		now := time.Now()
@@ -41,9 +60,23 @@ func (p *Post) BeforeSave(tx *gorm.DB) error {
 	return nil
 }
 
-// IncrementViews 增加浏览量
-func (p *Post) IncrementViews() {
-	p.Views++
+// AfterCreate 创建后的钩子：直接以发布状态创建的文章记录 PostPublished 事件
+func (p *Post) AfterCreate(tx *gorm.DB) error {
+	if p.Status != "published" || p.PublishedAt == nil {
+		return nil
+	}
+	return RecordEvent(tx, PostPublished{PostID: p.ID, AuthorID: p.AuthorID, Slug: p.Slug, PublishedAt: *p.PublishedAt})
+}
+
+// IncrementViews 原子地增加浏览量（UPDATE ... SET views = views + n），成功后同步内存中的值
+// 不修改 updated_at 和版本号：浏览不算对文章的编辑，也不应使正在进行的编辑冲突
+func (p *Post) IncrementViews(tx *gorm.DB, n int) error {
+	err := tx.Model(p).UpdateColumn("views", gorm.Expr("views + ?", n)).Error
+	if err != nil {
+		return err
+	}
+	p.Views += n
+	return nil
 }
 
 // IsPublished 检查文章是否已发布
@@ -1,9 +1,16 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// ErrInvalidProfile 用户资料校验失败
+var ErrInvalidProfile = errors.New("用户资料无效")
+
 // Profile 用户资料模型（一对一关联）
 type Profile struct {
 	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -16,6 +23,7 @@ type Profile struct {
 	Website   string    `gorm:"type:varchar(255)" json:"website"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	Version   uint      `gorm:"not null;default:1" json:"version"` // 乐观锁版本号，每次更新加一
 
 	// 关联关系
 	User User `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
@@ -26,6 +34,33 @@ func (Profile) TableName() string {
 	return "profiles"
 }
 
+// Indexes 额外索引
+func (Profile) Indexes() []Index {
+	return []Index{
+		// 个人简介全文检索，ngram分词支持中文
+		{Name: "ft_profiles_bio", Columns: Columns("bio"), FullText: true, Parser: "ngram"},
+	}
+}
+
+// Validate 校验字段长度（与列定义一致）和网址格式
+func (p *Profile) Validate() error {
+	if err := validateLengths(p); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	if err := validateHTTPURL("avatar_url", p.AvatarURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	if err := validateHTTPURL("website", p.Website); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	return nil
+}
+
+// BeforeSave 保存前校验
+func (p *Profile) BeforeSave(tx *gorm.DB) error {
+	return p.Validate()
+}
+
 // FullName 计算全名
 func (p *Profile) FullName() string {
 	return p.FirstName + " " + p.LastName
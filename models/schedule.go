@@ -0,0 +1,233 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// 课程安排中使用的日期、时间格式
+const (
+	ScheduleDateLayout = "2006-01-02"
+	ScheduleTimeLayout = "15:04"
+)
+
+// ErrInvalidSchedule 课程安排校验失败
+var ErrInvalidSchedule = errors.New("课程安排无效")
+
+// Schedule 课程安排（以JSON存储在 courses.schedule）
+type Schedule struct {
+	TimeZone string          `json:"time_zone,omitempty"` // IANA时区，如 Asia/Shanghai，为空时使用本地时区
+	Weekly   []WeeklySession `json:"weekly,omitempty"`    // 每周固定课次
+	Sessions []Session       `json:"sessions,omitempty"`  // 单次课次（补课、讲座等）
+	Holidays []string        `json:"holidays,omitempty"`  // 停课日期（YYYY-MM-DD），当天不安排每周课次
+}
+
+// WeeklySession 每周固定课次
+type WeeklySession struct {
+	Weekday time.Weekday `json:"weekday"` // 0=周日
+	Start   string       `json:"start"`   // 开始时间 HH:MM
+	End     string       `json:"end"`     // 结束时间 HH:MM
+	Venue
+}
+
+// Session 单次课次
+type Session struct {
+	Title string    `json:"title,omitempty"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Venue
+}
+
+// Venue 上课地点：线下教室或线上链接
+type Venue struct {
+	Room string `json:"room,omitempty"`
+	Link string `json:"link,omitempty"`
+}
+
+// Occurrence 展开后的一次具体上课时间
+type Occurrence struct {
+	Title string    `json:"title,omitempty"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Venue
+}
+
+// Location 返回安排所在时区
+func (s Schedule) Location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 未知时区 %s", ErrInvalidSchedule, s.TimeZone)
+	}
+	return loc, nil
+}
+
+// IsEmpty 是否没有任何课次
+func (s Schedule) IsEmpty() bool {
+	return len(s.Weekly) == 0 && len(s.Sessions) == 0
+}
+
+// SessionSpan 返回单次课次的时间范围 [最早开始, 最晚结束)，没有单次课次时均为零值
+func (s Schedule) SessionSpan() (time.Time, time.Time) {
+	var first, last time.Time
+	for _, session := range s.Sessions {
+		if first.IsZero() || session.Start.Before(first) {
+			first = session.Start
+		}
+		if session.End.After(last) {
+			last = session.End
+		}
+	}
+	return first, last
+}
+
+// Validate 校验课程安排，所有课次须落在课程起止日期之间（end为零值表示不限）
+func (s Schedule) Validate(start, end time.Time) error {
+	loc, err := s.Location()
+	if err != nil {
+		return err
+	}
+
+	for i, w := range s.Weekly {
+		if w.Weekday < time.Sunday || w.Weekday > time.Saturday {
+			return fmt.Errorf("%w: 第%d个每周课次的星期无效", ErrInvalidSchedule, i+1)
+		}
+		from, to, err := w.clock()
+		if err != nil {
+			return fmt.Errorf("%w: 第%d个每周课次%v", ErrInvalidSchedule, i+1, err)
+		}
+		if to <= from {
+			return fmt.Errorf("%w: 第%d个每周课次结束时间须晚于开始时间", ErrInvalidSchedule, i+1)
+		}
+		if err := w.Venue.validate(); err != nil {
+			return fmt.Errorf("%w: 第%d个每周课次%v", ErrInvalidSchedule, i+1, err)
+		}
+	}
+	if len(s.Weekly) > 0 && start.IsZero() {
+		return fmt.Errorf("%w: 每周课次需要设置课程开始日期", ErrInvalidSchedule)
+	}
+
+	first, last := dayBounds(start, end, loc)
+	for i, session := range s.Sessions {
+		if !session.End.After(session.Start) {
+			return fmt.Errorf("%w: 第%d个单次课次结束时间须晚于开始时间", ErrInvalidSchedule, i+1)
+		}
+		if (!start.IsZero() && session.Start.Before(first)) || (!end.IsZero() && session.End.After(last)) {
+			return fmt.Errorf("%w: 第%d个单次课次不在课程起止日期内", ErrInvalidSchedule, i+1)
+		}
+		if err := session.Venue.validate(); err != nil {
+			return fmt.Errorf("%w: 第%d个单次课次%v", ErrInvalidSchedule, i+1, err)
+		}
+	}
+
+	for _, day := range s.Holidays {
+		if _, err := time.ParseInLocation(ScheduleDateLayout, day, loc); err != nil {
+			return fmt.Errorf("%w: 停课日期 %s 格式错误", ErrInvalidSchedule, day)
+		}
+	}
+
+	return nil
+}
+
+// occurrences 展开 [from, to) 区间内、课程起止日期之间的全部课次，按开始时间排序
+func (s Schedule) occurrences(start, end, from, to time.Time) ([]Occurrence, error) {
+	loc, err := s.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := dayBounds(start, end, loc)
+	if !start.IsZero() && from.Before(first) {
+		from = first
+	}
+	if !end.IsZero() && to.After(last) {
+		to = last
+	}
+
+	var result []Occurrence
+	for _, session := range s.Sessions {
+		if session.Start.Before(to) && session.End.After(from) {
+			result = append(result, Occurrence{Title: session.Title, Start: session.Start, End: session.End, Venue: session.Venue})
+		}
+	}
+
+	holidays := make(map[string]bool, len(s.Holidays))
+	for _, day := range s.Holidays {
+		holidays[day] = true
+	}
+
+	if len(s.Weekly) > 0 && from.Before(to) {
+		day := time.Date(from.In(loc).Year(), from.In(loc).Month(), from.In(loc).Day(), 0, 0, 0, 0, loc)
+		for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+			if holidays[day.Format(ScheduleDateLayout)] {
+				continue
+			}
+			for _, w := range s.Weekly {
+				if day.Weekday() != w.Weekday {
+					continue
+				}
+				startMin, endMin, err := w.clock()
+				if err != nil {
+					return nil, err
+				}
+				occ := Occurrence{
+					Start: time.Date(day.Year(), day.Month(), day.Day(), startMin/60, startMin%60, 0, 0, loc),
+					End:   time.Date(day.Year(), day.Month(), day.Day(), endMin/60, endMin%60, 0, 0, loc),
+					Venue: w.Venue,
+				}
+				if occ.Start.Before(to) && occ.End.After(from) {
+					result = append(result, occ)
+				}
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result, nil
+}
+
+// clock 解析每周课次的开始、结束时间（距零点的分钟数）
+func (w WeeklySession) clock() (int, int, error) {
+	from, err := time.Parse(ScheduleTimeLayout, w.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("开始时间 %q 格式错误", w.Start)
+	}
+	to, err := time.Parse(ScheduleTimeLayout, w.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("结束时间 %q 格式错误", w.End)
+	}
+	return from.Hour()*60 + from.Minute(), to.Hour()*60 + to.Minute(), nil
+}
+
+// validate 校验上课地点
+func (v Venue) validate() error {
+	if v.Link == "" {
+		return nil
+	}
+	u, err := url.Parse(v.Link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("上课链接 %q 无效", v.Link)
+	}
+	return nil
+}
+
+// dayBounds 将课程起止日期换算为时区内的 [开始日零点, 结束日次日零点)
+func dayBounds(start, end time.Time, loc *time.Location) (time.Time, time.Time) {
+	var first, last time.Time
+	if !start.IsZero() {
+		s := start.In(loc)
+		first = time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, loc)
+	}
+	if !end.IsZero() {
+		e := end.In(loc)
+		last = time.Date(e.Year(), e.Month(), e.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	}
+	return first, last
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// SchemaMigration 迁移记录：每次 Migrate 对数据库结构的新增，用于回滚
+type SchemaMigration struct {
+	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Changes   datatypes.JSON `gorm:"type:json" json:"changes"` // 本次迁移前的结构差异（[]database.SchemaChange）
+	AppliedAt time.Time      `gorm:"autoCreateTime" json:"applied_at"`
+}
+
+// TableName 自定义表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
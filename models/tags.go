@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// ErrInvalidTags 标签格式错误
+var ErrInvalidTags = errors.New("标签必须是字符串数组")
+
+// NewTags 将标签列表编码为JSON列值
+func NewTags(tags ...string) datatypes.JSON {
+	if tags == nil {
+		tags = []string{}
+	}
+	data, _ := json.Marshal(tags)
+	return datatypes.JSON(data)
+}
+
+// ParseTags 解析JSON列中的标签，空值返回空列表
+func ParseTags(data datatypes.JSON) ([]string, error) {
+	raw := strings.TrimSpace(string(data))
+	if raw == "" || raw == "null" {
+		return []string{}, nil
+	}
+
+	var tags []string
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+		return nil, ErrInvalidTags
+	}
+	return tags, nil
+}
+
+// ValidateTags 校验标签为非空字符串组成的JSON数组
+func ValidateTags(data datatypes.JSON) error {
+	tags, err := ParseTags(data)
+	if err != nil {
+		return err
+	}
+	for i, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("%w: 第%d个标签为空", ErrInvalidTags, i+1)
+		}
+	}
+	return nil
+}
@@ -8,15 +8,25 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`               // 主键，自增
-	Username  string         `gorm:"type:varchar(50);unique;not null" json:"username"` // 用户名，唯一，非空
-	Email     string         `gorm:"type:varchar(100);unique;not null" json:"email"`   // 邮箱，唯一，非空
-	Password  string         `gorm:"type:varchar(255);not null" json:"-"`              // 密码，不返回JSON
-	Age       int            `gorm:"default:18;check:age>=0" json:"age"`               // 年龄，默认18，约束>=0
-	IsActive  bool           `gorm:"default:true" json:"is_active"`                    // 是否活跃，默认true
-	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`                 // 创建时间，自动设置
-	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`                 // 更新时间，自动更新
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`                // 软删除时间戳
+	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`        // 主键，自增
+	Username  string         `gorm:"type:varchar(50);not null" json:"username"` // 用户名，未删除用户中唯一，非空
+	Email     string         `gorm:"type:varchar(100);not null" json:"email"`   // 邮箱，未删除用户中唯一，非空
+	Password  string         `gorm:"type:varchar(255);not null" json:"-"`       // 密码，不返回JSON
+	Age       int            `gorm:"default:18;check:age>=0" json:"age"`        // 年龄，默认18，约束>=0
+	IsActive  bool           `gorm:"default:true" json:"is_active"`             // 是否活跃，默认true
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`          // 创建时间，自动设置
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`          // 更新时间，自动更新
+	Version   uint           `gorm:"not null;default:1" json:"version"`         // 乐观锁版本号，每次更新加一
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`         // 软删除时间戳
+
+	// 停用信息（IsActive为false时有效，重新启用后清空）
+	DeactivatedAt      *time.Time `gorm:"index" json:"deactivated_at,omitempty"`                                      // 停用时间
+	DeactivatedBy      string     `gorm:"type:varchar(100);not null;default:''" json:"deactivated_by,omitempty"`      // 停用操作人
+	DeactivationReason string     `gorm:"type:varchar(255);not null;default:''" json:"deactivation_reason,omitempty"` // 停用原因
+
+	// 唯一约束只作用于未删除的用户：删除后生成列为NULL，用户名和邮箱可被重新注册
+	ActiveUsername *string `gorm:"->;type:varchar(50) GENERATED ALWAYS AS (CASE WHEN deleted_at IS NULL THEN username END) VIRTUAL" json:"-"`
+	ActiveEmail    *string `gorm:"->;type:varchar(100) GENERATED ALWAYS AS (CASE WHEN deleted_at IS NULL THEN email END) VIRTUAL" json:"-"`
 
 	// 关联关系
 	Profile *Profile `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"profile,omitempty"`
@@ -29,6 +39,14 @@ func (User) TableName() string {
 	return "users"
 }
 
+// Indexes 额外索引
+func (User) Indexes() []Index {
+	return []Index{
+		{Name: "uni_users_active_username", Columns: Columns("active_username"), Unique: true},
+		{Name: "uni_users_active_email", Columns: Columns("active_email"), Unique: true},
+	}
+}
+
 // BeforeCreate 创建前的钩子
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	// 示例：创建前自动设置默认值或验证
@@ -38,16 +56,14 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// AfterCreate 创建后的钩子
+// AfterCreate 创建后的钩子：在同一事务中记录 UserRegistered 事件
 func (u *User) AfterCreate(tx *gorm.DB) error {
-	// 示例：创建后的钩子（已禁用自动创建Profile，避免重复插入）
-	// 如需自动创建Profile，请在业务代码中手动处理
-	return nil
+	// 不自动创建Profile，避免重复插入；如需创建请在业务代码中手动处理
+	return RecordEvent(tx, UserRegistered{UserID: u.ID, RegisteredAt: u.CreatedAt})
 }
 
 // BeforeUpdate 更新前的钩子
 func (u *User) BeforeUpdate(tx *gorm.DB) error {
-	// 示例：更新前记录变更日志
-	// log.Printf("更新用户: %s (ID: %d)", u.Username, u.ID)
+	// 变更记录由 database 包的审计回调写入 audit_logs
 	return nil
 }
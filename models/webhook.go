@@ -0,0 +1,93 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Webhook 订阅：指定类型的领域事件发生后向 URL 发送签名的 POST 请求
+type Webhook struct {
+	ID          uint                        `gorm:"primaryKey;autoIncrement" json:"id"`
+	URL         string                      `gorm:"type:varchar(2048);not null" json:"url"`                   // 接收地址（http/https）
+	EventTypes  datatypes.JSONSlice[string] `gorm:"type:json;not null" json:"event_types"`                    // 订阅的事件类型
+	Secret      string                      `gorm:"type:varchar(255);not null" json:"-"`                      // HMAC-SHA256 签名密钥，不返回JSON
+	Description string                      `gorm:"type:varchar(255);not null;default:''" json:"description"` // 备注
+	IsActive    bool                        `gorm:"default:true" json:"is_active"`                            // 停用后不再产生新的投递
+	CreatedAt   time.Time                   `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time                   `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName 自定义表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes 判断是否订阅了事件类型
+func (w *Webhook) Subscribes(eventType string) bool {
+	return slices.Contains(w.EventTypes, eventType)
+}
+
+// Webhook 投递状态
+const (
+	DeliveryPending   = "pending"   // 等待发送（包括失败后等待重试）
+	DeliverySucceeded = "succeeded" // 接收方返回 2xx
+	DeliveryFailed    = "failed"    // 重试次数用尽，可手动重新投递
+)
+
+// WebhookDelivery 一个事件向一个 Webhook 的投递，同时作为投递日志（记录最近一次请求的结果）
+type WebhookDelivery struct {
+	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	WebhookID     uint           `gorm:"not null" json:"webhook_id"`
+	EventID       uint           `gorm:"not null" json:"event_id"` // outbox 中的事件ID，同一事件只投递一次
+	EventType     string         `gorm:"type:varchar(100);not null" json:"event_type"`
+	Payload       datatypes.JSON `gorm:"type:json;not null" json:"payload"`                         // 请求体（事件可能已被清理，重新投递时使用）
+	Status        string         `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending/succeeded/failed
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`                        // 已发送的次数
+	ResponseCode  int            `gorm:"not null;default:0" json:"response_code"`                   // 最近一次请求的 HTTP 状态码，未收到响应时为0
+	ResponseBody  string         `gorm:"type:text" json:"response_body,omitempty"`                  // 最近一次响应的开头部分
+	LastError     string         `gorm:"type:text" json:"last_error,omitempty"`                     // 最近一次失败的原因
+	DurationMs    int64          `gorm:"not null;default:0" json:"duration_ms"`                     // 最近一次请求的耗时
+	NextAttemptAt time.Time      `gorm:"not null" json:"next_attempt_at"`                           // 最早可以（再次）发送的时间
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// 关联关系
+	Webhook Webhook `gorm:"foreignKey:WebhookID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 自定义表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// Indexes 额外索引
+func (WebhookDelivery) Indexes() []Index {
+	return []Index{
+		{Name: "uni_webhook_deliveries_webhook_id_event_id", Columns: Columns("webhook_id", "event_id"), Unique: true},
+		// 发送任务按状态和时间取待发送的投递
+		{Name: "idx_webhook_deliveries_status_next_attempt_at", Columns: Columns("status", "next_attempt_at")},
+	}
+}
+
+// WebhookDeliveryAttempt 投递的一次请求记录，每次发送写入一行；WebhookDelivery 只保留最近一次的结果
+type WebhookDeliveryAttempt struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DeliveryID   uint      `gorm:"index;not null" json:"delivery_id"`
+	Attempt      int       `gorm:"not null" json:"attempt"`                  // 第几次发送，重新投递后从1重新计数
+	ResponseCode int       `gorm:"not null;default:0" json:"response_code"`  // HTTP 状态码，未收到响应时为0
+	ResponseBody string    `gorm:"type:text" json:"response_body,omitempty"` // 响应的开头部分
+	Error        string    `gorm:"type:text" json:"error,omitempty"`         // 失败原因，成功时为空
+	DurationMs   int64     `gorm:"not null;default:0" json:"duration_ms"`    // 请求耗时
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`         // 记录结果的时间
+
+	// 关联关系
+	Delivery WebhookDelivery `gorm:"foreignKey:DeliveryID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 自定义表名
+func (WebhookDeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditFilter 审计日志查询条件
+type AuditFilter struct {
+	EntityType string
+	EntityID   uint
+	Actor      string
+	Action     string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+}
+
+// AuditRepository 审计日志仓储接口
+type AuditRepository interface {
+	WithContext(ctx context.Context) AuditRepository
+	FindByEntity(entityType string, entityID uint, page, pageSize int) ([]models.AuditLog, int64, error)
+	Find(filter AuditFilter, page, pageSize int) ([]models.AuditLog, int64, error)
+}
+
+// auditRepository 审计日志仓储实现
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository 创建新的审计日志仓储实例
+func NewAuditRepository() AuditRepository {
+	return &auditRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *auditRepository) WithContext(ctx context.Context) AuditRepository {
+	return &auditRepository{db: database.Conn(ctx, r.db)}
+}
+
+// FindByEntity 按时间倒序获取某条数据的变更历史
+func (r *auditRepository) FindByEntity(entityType string, entityID uint, page, pageSize int) ([]models.AuditLog, int64, error) {
+	return r.Find(AuditFilter{EntityType: entityType, EntityID: entityID}, page, pageSize)
+}
+
+// Find 按条件分页查询审计日志
+func (r *auditRepository) Find(filter AuditFilter, page, pageSize int) ([]models.AuditLog, int64, error) {
+	var logs []models.AuditLog
+	var total int64
+
+	query := r.db.Model(&models.AuditLog{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Order("id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,226 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CommentRepository 评论仓储接口
+type CommentRepository interface {
+	WithContext(ctx context.Context) CommentRepository
+	FindByID(id uint) (*models.Comment, error)
+	Delete(id uint) error
+	Restore(id uint) error
+	ListDeleted(postID uint, page, pageSize int) ([]models.Comment, int64, error)
+	Purge(id uint) error
+	PurgeDeletedBefore(cutoff time.Time, batchSize int) (int64, error)
+	Like(commentID, userID uint) (bool, error)
+	Unlike(commentID, userID uint) (bool, error)
+	RecountLikes() (int64, error)
+}
+
+// commentRepository 评论仓储实现
+type commentRepository struct {
+	db *gorm.DB
+}
+
+// NewCommentRepository 创建新的评论仓储实例
+func NewCommentRepository() CommentRepository {
+	return &commentRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *commentRepository) WithContext(ctx context.Context) CommentRepository {
+	return &commentRepository{db: database.Conn(ctx, r.db)}
+}
+
+// FindByID 根据ID查找评论
+func (r *commentRepository) FindByID(id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.First(&comment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// Delete 软删除评论
+func (r *commentRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Comment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Restore 恢复已软删除的评论
+func (r *commentRepository) Restore(id uint) error {
+	result := r.db.Unscoped().Model(&models.Comment{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListDeleted 分页查找已软删除的评论，postID 为0时不限文章
+func (r *commentRepository) ListDeleted(postID uint, page, pageSize int) ([]models.Comment, int64, error) {
+	var comments []models.Comment
+	var total int64
+
+	query := r.db.Unscoped().Model(&models.Comment{}).Where("deleted_at IS NOT NULL")
+	if postID != 0 {
+		query = query.Where("post_id = ?", postID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("deleted_at DESC").Offset(offset).Limit(pageSize).Find(&comments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// Purge 彻底删除已软删除的评论，其回复变为顶级评论
+func (r *commentRepository) Purge(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Unscoped().Model(&models.Comment{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return ErrNotFound
+		}
+		return purgeComments(tx, ids)
+	})
+}
+
+// PurgeDeletedBefore 分批彻底删除在 cutoff 之前软删除的评论，返回删除的数量
+func (r *commentRepository) PurgeDeletedBefore(cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		err := r.db.Unscoped().Model(&models.Comment{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("id").Limit(batchSize).Pluck("id", &ids).Error
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		if err := r.db.Transaction(func(tx *gorm.DB) error {
+			return purgeComments(tx, ids)
+		}); err != nil {
+			return total, fmt.Errorf("彻底删除评论失败: %v", err)
+		}
+		total += int64(len(ids))
+
+		if len(ids) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// Like 用户点赞评论（评论须未删除），已点赞过时返回 false 且不重复计数
+// 点赞记录与评论的点赞数在同一事务中写入
+func (r *commentRepository) Like(commentID, userID uint) (bool, error) {
+	liked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Comment{}).Where("id = ?", commentID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.CommentLike{CommentID: commentID, UserID: userID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		liked = true
+		return tx.Model(&models.Comment{}).Where("id = ?", commentID).
+			UpdateColumn("likes", gorm.Expr("likes + 1")).Error
+	})
+	return liked, err
+}
+
+// Unlike 取消点赞，未点赞过时返回 false
+func (r *commentRepository) Unlike(commentID, userID uint) (bool, error) {
+	unliked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("comment_id = ? AND user_id = ?", commentID, userID).Delete(&models.CommentLike{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		unliked = true
+		return tx.Unscoped().Model(&models.Comment{}).Where("id = ? AND likes > 0", commentID).
+			UpdateColumn("likes", gorm.Expr("likes - 1")).Error
+	})
+	return unliked, err
+}
+
+// RecountLikes 按点赞记录重新计算所有评论的点赞数，返回修正的评论数
+func (r *commentRepository) RecountLikes() (int64, error) {
+	result := r.db.Exec(`UPDATE comments SET likes = (
+		SELECT COUNT(*) FROM comment_likes WHERE comment_likes.comment_id = comments.id
+	) WHERE likes <> (
+		SELECT COUNT(*) FROM comment_likes WHERE comment_likes.comment_id = comments.id
+	)`)
+	return result.RowsAffected, result.Error
+}
+
+// purgeComments 在事务中彻底删除评论及其点赞记录，先解除回复对它们的引用
+func purgeComments(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := tx.Unscoped().Model(&models.Comment{}).
+		Where("parent_id IN ?", ids).
+		UpdateColumn("parent_id", nil).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&models.CommentLike{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Comment{}).Error
+}
+
+// removeLikesByUsers 删除用户的点赞记录，并从被点赞评论的点赞数中扣除
+func removeLikesByUsers(tx *gorm.DB, userIDs []uint) error {
+	// 用 CASE 而不是 GREATEST 防止计数变为负数，SQLite 等方言没有 GREATEST
+	const removed = `(SELECT COUNT(*) FROM comment_likes WHERE comment_likes.comment_id = comments.id AND comment_likes.user_id IN ?)`
+	err := tx.Exec(`UPDATE comments SET likes = CASE WHEN likes > `+removed+` THEN likes - `+removed+` ELSE 0 END
+		WHERE id IN (SELECT comment_id FROM comment_likes WHERE user_id IN ?)`, userIDs, userIDs, userIDs).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("user_id IN ?", userIDs).Delete(&models.CommentLike{}).Error
+}
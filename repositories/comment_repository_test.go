@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// createLikers 创建评论和 n 个用户
+func createLikers(t *testing.T, db *gorm.DB, n int) (*models.Comment, []models.User) {
+	t.Helper()
+	users := make([]models.User, n)
+	for i := range users {
+		name := string(rune('a' + i))
+		users[i] = models.User{Username: "user-" + name, Email: name + "@example.com", Password: "secret123", IsActive: true}
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	post := models.Post{Title: "文章", Content: "正文", Slug: "post", AuthorID: users[0].ID}
+	if err := db.Omit("Author", "Comments").Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	comment := models.Comment{Content: "评论", PostID: post.ID, UserID: users[0].ID}
+	if err := db.Omit(clause.Associations).Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &comment, users
+}
+
+// checkLikes 检查评论的点赞数与点赞记录一致且等于 want
+func checkLikes(t *testing.T, db *gorm.DB, commentID uint, want int) {
+	t.Helper()
+	var comment models.Comment
+	if err := db.First(&comment, commentID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var records int64
+	if err := db.Model(&models.CommentLike{}).Where("comment_id = ?", commentID).Count(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+	if comment.Likes != want || records != int64(want) {
+		t.Errorf("点赞数 = %d，点赞记录 %d 条, want 都为 %d", comment.Likes, records, want)
+	}
+}
+
+func TestLikeCountsOncePerUser(t *testing.T) {
+	db := testdb.Open(t)
+	comment, users := createLikers(t, db, 2)
+	repo := NewCommentRepository()
+
+	for i, want := range []bool{true, false, false} {
+		liked, err := repo.Like(comment.ID, users[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if liked != want {
+			t.Errorf("第 %d 次点赞 = %v, want %v", i+1, liked, want)
+		}
+	}
+	checkLikes(t, db, comment.ID, 1)
+
+	if liked, err := repo.Like(comment.ID, users[1].ID); err != nil || !liked {
+		t.Fatalf("另一用户点赞 = %v, %v", liked, err)
+	}
+	checkLikes(t, db, comment.ID, 2)
+
+	if _, err := repo.Like(comment.ID+100, users[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("点赞不存在的评论 = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUnlike(t *testing.T) {
+	db := testdb.Open(t)
+	comment, users := createLikers(t, db, 2)
+	repo := NewCommentRepository()
+	if _, err := repo.Like(comment.ID, users[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未点赞过的用户取消点赞不影响计数
+	if unliked, err := repo.Unlike(comment.ID, users[1].ID); err != nil || unliked {
+		t.Errorf("取消不存在的点赞 = %v, %v, want false", unliked, err)
+	}
+	checkLikes(t, db, comment.ID, 1)
+
+	if unliked, err := repo.Unlike(comment.ID, users[0].ID); err != nil || !unliked {
+		t.Fatalf("取消点赞 = %v, %v, want true", unliked, err)
+	}
+	checkLikes(t, db, comment.ID, 0)
+
+	// 重复取消不会使计数变为负数
+	if unliked, err := repo.Unlike(comment.ID, users[0].ID); err != nil || unliked {
+		t.Errorf("重复取消点赞 = %v, %v, want false", unliked, err)
+	}
+	checkLikes(t, db, comment.ID, 0)
+
+	// 取消后可以重新点赞
+	if liked, err := repo.Like(comment.ID, users[0].ID); err != nil || !liked {
+		t.Errorf("重新点赞 = %v, %v, want true", liked, err)
+	}
+	checkLikes(t, db, comment.ID, 1)
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CourseRepository 课程仓储接口
+type CourseRepository interface {
+	WithContext(ctx context.Context) CourseRepository
+	FindByID(id uint) (*models.Course, error)
+	FindEnrolledByUser(userID uint) ([]models.Course, error)
+	LockEnrollments(userID uint) ([]models.Course, error)
+	Update(course *models.Course) error
+	Enroll(userID, courseID uint) (*models.UserCourse, error)
+}
+
+// courseRepository 课程仓储实现
+type courseRepository struct {
+	db *gorm.DB
+}
+
+// NewCourseRepository 创建新的课程仓储实例
+func NewCourseRepository() CourseRepository {
+	return &courseRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *courseRepository) WithContext(ctx context.Context) CourseRepository {
+	return &courseRepository{db: database.Conn(ctx, r.db)}
+}
+
+// FindByID 根据ID查找课程
+func (r *courseRepository) FindByID(id uint) (*models.Course, error) {
+	var course models.Course
+	err := r.db.First(&course, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// FindEnrolledByUser 查找用户正在选修的课程（不含已退选和已结课的）
+func (r *courseRepository) FindEnrolledByUser(userID uint) ([]models.Course, error) {
+	var courses []models.Course
+	err := enrolledBy(r.db, userID).Find(&courses).Error
+	if err != nil {
+		return nil, err
+	}
+	return courses, nil
+}
+
+// LockEnrollments 在事务中锁定用户及其正在选修的课程（FOR UPDATE）并返回这些课程，
+// 同一用户并发的选课请求依次检查时间冲突；锁定用户行使得用户还没有选课时同样互斥
+// 用户不存在时返回 ErrNotFound
+func (r *courseRepository) LockEnrollments(userID uint) ([]models.Course, error) {
+	var user models.User
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var courses []models.Course
+	err = enrolledBy(r.db, userID).Clauses(clause.Locking{Strength: "UPDATE"}).Find(&courses).Error
+	if err != nil {
+		return nil, err
+	}
+	return courses, nil
+}
+
+// enrolledBy 查询用户正在选修的课程（按开始日期排序）
+func enrolledBy(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.Course{}).
+		Joins("JOIN user_courses ON user_courses.course_id = courses.id").
+		Where("user_courses.user_id = ? AND user_courses.status = ?", userID, models.EnrollmentEnrolled).
+		Order("courses.start_date").Order("courses.id")
+}
+
+// Update 更新课程的全部字段（不含教师和学员），课程在读取后已被修改时返回 ErrConflict
+func (r *courseRepository) Update(course *models.Course) error {
+	return saveVersioned(r.db, course, &course.Version)
+}
+
+// Enroll 为用户添加选课记录，并在同一事务中记录 Enrolled 事件（见 UserCourse.AfterCreate）
+// 已选过该课程时返回 ErrDuplicate，用户或课程不存在时返回 ErrInvalidReference
+func (r *courseRepository) Enroll(userID, courseID uint) (*models.UserCourse, error) {
+	enrollment := &models.UserCourse{UserID: userID, CourseID: courseID, Status: models.EnrollmentEnrolled}
+	if err := r.db.Omit(clause.Associations).Create(enrollment).Error; err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
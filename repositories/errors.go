@@ -0,0 +1,18 @@
+package repositories
+
+import "exercise/database"
+
+// 仓储返回的领域错误（由 database 的错误翻译回调产生），调用方用 errors.Is 判断，
+// 用 errors.As 取出 *DBError 获得约束名和字段名
+var (
+	ErrNotFound         = database.ErrNotFound
+	ErrDuplicate        = database.ErrDuplicate
+	ErrReferenced       = database.ErrReferenced
+	ErrInvalidReference = database.ErrInvalidReference
+	ErrCheckViolation   = database.ErrCheckViolation
+	ErrDeadlock         = database.ErrDeadlock
+	ErrLockTimeout      = database.ErrLockTimeout
+)
+
+// DBError 带有约束名和字段名的数据库错误
+type DBError = database.DBError
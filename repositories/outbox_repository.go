@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository 领域事件 outbox 仓储接口
+type OutboxRepository interface {
+	WithContext(ctx context.Context) OutboxRepository
+	Claim(limit int, lease time.Duration, now time.Time) ([]models.OutboxEvent, error)
+	MarkDelivered(id uint, at time.Time) error
+	MarkFailed(id uint, reason string, next time.Time) error
+	MarkDead(id uint, reason string) error
+	ListByStatus(status string, page, pageSize int) ([]models.OutboxEvent, int64, error)
+	Requeue(id uint, now time.Time) error
+	RequeueDead(now time.Time) (int64, error)
+	PurgeDeliveredBefore(cutoff time.Time, batchSize int) (int64, error)
+}
+
+// outboxRepository 领域事件 outbox 仓储实现
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository 创建新的 outbox 仓储实例
+func NewOutboxRepository() OutboxRepository {
+	return &outboxRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *outboxRepository) WithContext(ctx context.Context) OutboxRepository {
+	return &outboxRepository{db: database.Conn(ctx, r.db)}
+}
+
+// Claim 领取最多 limit 个到期的待投递事件（按ID顺序），投递次数加一并把下次投递时间推迟 lease，
+// 在此期间其他分发器不会领取；分发器在 lease 内没有标记结果（如进程退出）时事件会被再次领取
+// 使用 SKIP LOCKED，多个分发器并发领取时互不等待
+func (r *outboxRepository) Claim(limit int, lease time.Duration, now time.Time) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+			Order("id").Limit(limit).Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(events))
+		for i := range events {
+			ids[i] = events[i].ID
+			events[i].Attempts++
+			events[i].NextAttemptAt = now.Add(lease)
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(lease),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// MarkDelivered 标记事件已投递
+func (r *outboxRepository) MarkDelivered(id uint, at time.Time) error {
+	return r.db.Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.OutboxDelivered,
+		"delivered_at": at,
+		"last_error":   "",
+	}).Error
+}
+
+// MarkFailed 记录投递失败的原因，事件在 next 之后重试
+func (r *outboxRepository) MarkFailed(id uint, reason string, next time.Time) error {
+	return r.db.Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_error":      reason,
+		"next_attempt_at": next,
+	}).Error
+}
+
+// MarkDead 重试次数用尽，将事件移入死信（不再自动投递）
+func (r *outboxRepository) MarkDead(id uint, reason string) error {
+	return r.db.Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.OutboxDead,
+		"last_error": reason,
+	}).Error
+}
+
+// ListByStatus 按状态分页查找事件（按ID倒序）
+func (r *outboxRepository) ListByStatus(status string, page, pageSize int) ([]models.OutboxEvent, int64, error) {
+	var events []models.OutboxEvent
+	var total int64
+
+	query := r.db.Model(&models.OutboxEvent{}).Where("status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// Requeue 将死信事件重新放回待投递队列（投递次数清零），事件不存在或不是死信时返回 ErrNotFound
+func (r *outboxRepository) Requeue(id uint, now time.Time) error {
+	result := r.db.Model(&models.OutboxEvent{}).Where("id = ? AND status = ?", id, models.OutboxDead).
+		Updates(requeueColumns(now))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RequeueDead 将全部死信事件重新放回待投递队列，返回数量
+func (r *outboxRepository) RequeueDead(now time.Time) (int64, error) {
+	result := r.db.Model(&models.OutboxEvent{}).Where("status = ?", models.OutboxDead).
+		Updates(requeueColumns(now))
+	return result.RowsAffected, result.Error
+}
+
+// requeueColumns 重新投递时需要重置的列
+func requeueColumns(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"status":          models.OutboxPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+	}
+}
+
+// PurgeDeliveredBefore 分批删除在 cutoff 之前已投递的事件，返回删除的数量
+func (r *outboxRepository) PurgeDeliveredBefore(cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		result := r.db.Where("status = ? AND delivered_at < ?", models.OutboxDelivered, cutoff).
+			Limit(batchSize).Delete(&models.OutboxEvent{})
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
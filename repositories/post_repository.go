@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// viewsBatchSize 批量增加浏览量时每条语句包含的文章数
+const viewsBatchSize = 500
+
+// PostRepository 文章仓储接口
+type PostRepository interface {
+	WithContext(ctx context.Context) PostRepository
+	FindByID(id uint) (*models.Post, error)
+	Update(post *models.Post) error
+	Publish(id uint, at time.Time) (*models.Post, error)
+	IncrementViews(id uint, n int) error
+	AddViews(counts map[uint]int64) error
+}
+
+// postRepository 文章仓储实现
+type postRepository struct {
+	db *gorm.DB
+}
+
+// NewPostRepository 创建新的文章仓储实例
+func NewPostRepository() PostRepository {
+	return &postRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *postRepository) WithContext(ctx context.Context) PostRepository {
+	return &postRepository{db: database.Conn(ctx, r.db)}
+}
+
+// FindByID 根据ID查找文章
+func (r *postRepository) FindByID(id uint) (*models.Post, error) {
+	var post models.Post
+	err := r.db.First(&post, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// Update 更新文章的全部字段（不含作者和评论），文章在读取后已被修改时返回 ErrConflict
+// 浏览量由 IncrementViews/AddViews 原子维护，不随文章一起覆盖
+func (r *postRepository) Update(post *models.Post) error {
+	return saveVersioned(r.db.Omit("views"), post, &post.Version)
+}
+
+// Publish 发布文章并在同一事务中记录 PostPublished 事件，已发布的文章不做修改
+// 草稿已设置发布时间（定时发布）时保留该时间，否则使用 at
+func (r *postRepository) Publish(id uint, at time.Time) (*models.Post, error) {
+	var post models.Post
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, id).Error; err != nil {
+			return err
+		}
+		if post.Status == "published" {
+			return nil
+		}
+
+		if post.PublishedAt == nil {
+			post.PublishedAt = &at
+		}
+		err := tx.Model(&post).Updates(map[string]interface{}{
+			"status":       "published",
+			"published_at": *post.PublishedAt,
+			versionColumn:  nextVersion(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		post.Status = "published"
+		post.Version++
+		return models.RecordEvent(tx, models.PostPublished{
+			PostID: post.ID, AuthorID: post.AuthorID, Slug: post.Slug, PublishedAt: *post.PublishedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// counters 更新计数器使用的连接（计数变化不写审计日志）
+func (r *postRepository) counters() *gorm.DB {
+	return r.db.WithContext(database.WithoutAudit(r.db.Statement.Context))
+}
+
+// IncrementViews 原子地增加一篇文章的浏览量
+func (r *postRepository) IncrementViews(id uint, n int) error {
+	result := r.counters().Model(&models.Post{}).Where("id = ?", id).
+		UpdateColumn("views", gorm.Expr("views + ?", n))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddViews 批量增加多篇文章的浏览量（文章ID -> 增量），每批一条 UPDATE ... CASE 语句
+// 按ID顺序加锁，避免并发写入时死锁；已删除的文章忽略
+func (r *postRepository) AddViews(counts map[uint]int64) error {
+	ids := make([]uint, 0, len(counts))
+	for id, n := range counts {
+		if n != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for start := 0; start < len(ids); start += viewsBatchSize {
+		batch := ids[start:min(start+viewsBatchSize, len(ids))]
+		var expr strings.Builder
+		args := make([]interface{}, 0, len(batch)*2)
+		expr.WriteString("views + CASE id")
+		for _, id := range batch {
+			expr.WriteString(" WHEN ? THEN ?")
+			args = append(args, id, counts[id])
+		}
+		expr.WriteString(" ELSE 0 END")
+
+		err := r.counters().Model(&models.Post{}).Where("id IN ?", batch).
+			UpdateColumn("views", gorm.Expr(expr.String(), args...)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+)
+
+// ErasedPlaceholder 擦除后替换个人信息和内容的占位文本
+const ErasedPlaceholder = "[已删除]"
+
+// UserData 用户的全部个人数据
+type UserData struct {
+	User          models.User          `json:"user"`
+	Profile       *models.Profile      `json:"profile,omitempty"`
+	Posts         []models.Post        `json:"posts"`
+	Comments      []models.Comment     `json:"comments"`
+	CommentLikes  []models.CommentLike `json:"comment_likes"`  // 点赞过的评论
+	Courses       []models.Course      `json:"courses"`        // 选修的课程
+	TaughtCourses []models.Course      `json:"taught_courses"` // 任教的课程
+}
+
+// ErasureOptions 擦除选项
+type ErasureOptions struct {
+	KeepContent *bool  // 保留文章和评论内容，只替换其中的个人信息；为nil时保留
+	Actor       string // 执行擦除的操作人，写入审计日志
+	RequestID   string
+}
+
+// ErasureResult 擦除结果
+type ErasureResult struct {
+	UserID             uint  `json:"user_id"`
+	PostsAnonymized    int64 `json:"posts_anonymized"`
+	CommentsAnonymized int64 `json:"comments_anonymized"`
+	ProfileRemoved     bool  `json:"profile_removed"`
+	EnrollmentsRemoved int64 `json:"enrollments_removed"`
+}
+
+// PrivacyRepository 个人数据仓储接口
+type PrivacyRepository interface {
+	WithContext(ctx context.Context) PrivacyRepository
+	LoadUserData(id uint) (*UserData, error)
+	EraseUser(id uint, opts ErasureOptions) (*ErasureResult, error)
+}
+
+// privacyRepository 个人数据仓储实现
+type privacyRepository struct {
+	db *gorm.DB
+}
+
+// NewPrivacyRepository 创建新的个人数据仓储实例
+func NewPrivacyRepository() PrivacyRepository {
+	return &privacyRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *privacyRepository) WithContext(ctx context.Context) PrivacyRepository {
+	return &privacyRepository{db: database.Conn(ctx, r.db)}
+}
+
+// LoadUserData 加载用户的资料、文章、评论、点赞和选课记录
+func (r *privacyRepository) LoadUserData(id uint) (*UserData, error) {
+	var data UserData
+	if err := r.db.Unscoped().First(&data.User, id).Error; err != nil {
+		return nil, err
+	}
+
+	var profile models.Profile
+	err := r.db.Where("user_id = ?", id).Limit(1).Find(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	if profile.ID != 0 {
+		data.Profile = &profile
+	}
+
+	if err := r.db.Where("author_id = ?", id).Order("id").Find(&data.Posts).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Order("id").Find(&data.Comments).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Order("comment_id").Find(&data.CommentLikes).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&data.User).Order("id").Association("Courses").Find(&data.Courses); err != nil {
+		return nil, err
+	}
+	err = r.db.Joins("JOIN course_teachers ON course_teachers.course_id = courses.id").
+		Where("course_teachers.user_id = ?", id).Order("courses.id").Find(&data.TaughtCourses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// EraseUser 在一个事务中擦除用户的个人数据：
+// 匿名化账户（含已软删除的账户），删除资料和选课记录，去除其文章、评论中的个人信息（或清空内容），
+// 清除审计日志中该账户、资料及其文章的个人信息，并写入一条擦除记录
+func (r *privacyRepository) EraseUser(id uint, opts ErasureOptions) (*ErasureResult, error) {
+	result := &ErasureResult{UserID: id}
+	keepContent := opts.KeepContent == nil || *opts.KeepContent
+
+	// 擦除过程不经过审计回调，避免把个人信息作为旧值写入审计日志
+	ctx := database.WithoutAudit(r.db.Statement.Context)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Unscoped().First(&user, id).Error; err != nil {
+			return err
+		}
+
+		var profile models.Profile
+		if err := tx.Where("user_id = ?", id).Limit(1).Find(&profile).Error; err != nil {
+			return err
+		}
+		pii := piiPattern(user, profile)
+
+		// 文章
+		var posts []models.Post
+		if err := tx.Where("author_id = ?", id).Find(&posts).Error; err != nil {
+			return err
+		}
+		for _, post := range posts {
+			title, content := scrubText(post.Title, pii), scrubText(post.Content, pii)
+			if !keepContent {
+				title, content = ErasedPlaceholder, ErasedPlaceholder
+			}
+			if title == post.Title && content == post.Content {
+				continue
+			}
+			err := tx.Model(&models.Post{ID: post.ID}).
+				UpdateColumns(map[string]interface{}{"title": title, "content": content, versionColumn: nextVersion()}).Error
+			if err != nil {
+				return fmt.Errorf("匿名化文章 %d 失败: %v", post.ID, err)
+			}
+			result.PostsAnonymized++
+		}
+
+		// 评论（含已软删除的）
+		var comments []models.Comment
+		if err := tx.Unscoped().Where("user_id = ?", id).Find(&comments).Error; err != nil {
+			return err
+		}
+		for _, comment := range comments {
+			content := scrubText(comment.Content, pii)
+			if !keepContent {
+				content = ErasedPlaceholder
+			}
+			if content == comment.Content {
+				continue
+			}
+			err := tx.Unscoped().Model(&models.Comment{ID: comment.ID}).UpdateColumn("content", content).Error
+			if err != nil {
+				return fmt.Errorf("匿名化评论 %d 失败: %v", comment.ID, err)
+			}
+			result.CommentsAnonymized++
+		}
+
+		// 资料与选课记录
+		if profile.ID != 0 {
+			if err := tx.Delete(&models.Profile{}, profile.ID).Error; err != nil {
+				return fmt.Errorf("删除资料失败: %v", err)
+			}
+			result.ProfileRemoved = true
+		}
+		for _, table := range []string{"user_courses", "course_teachers"} {
+			res := tx.Exec("DELETE FROM "+table+" WHERE user_id = ?", id)
+			if res.Error != nil {
+				return fmt.Errorf("删除选课记录失败: %v", res.Error)
+			}
+			result.EnrollmentsRemoved += res.RowsAffected
+		}
+
+		// 匿名化账户（保留主键，文章作者关系不变），并停用
+		now := time.Now()
+		err := tx.Unscoped().Model(&models.User{ID: id}).UpdateColumns(map[string]interface{}{
+			"username":            fmt.Sprintf("deleted_%d", id),
+			"email":               fmt.Sprintf("deleted_%d@invalid", id),
+			"password":            "",
+			"age":                 0,
+			"is_active":           false,
+			"deactivated_at":      now,
+			"deactivated_by":      opts.Actor,
+			"deactivation_reason": "个人数据已擦除",
+			"updated_at":          now,
+			versionColumn:         nextVersion(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("匿名化账户失败: %v", err)
+		}
+
+		// 审计日志中该账户的历史取值同样属于个人数据
+		err = tx.Model(&models.AuditLog{}).
+			Where("entity_type = ? AND entity_id = ?", user.TableName(), id).
+			Update("changes", nil).Error
+		if err != nil {
+			return fmt.Errorf("清除审计历史失败: %v", err)
+		}
+
+		// 资料和文章的历史取值中也可能含有个人信息：保留内容时只替换其中的个人信息，否则一并清除
+		postIDs := make([]uint, 0, len(posts))
+		for _, post := range posts {
+			postIDs = append(postIDs, post.ID)
+		}
+		if err := redactAuditLogs(tx, "posts", postIDs, pii, keepContent); err != nil {
+			return err
+		}
+		if profile.ID != 0 {
+			if err := redactAuditLogs(tx, profile.TableName(), []uint{profile.ID}, pii, false); err != nil {
+				return err
+			}
+		}
+
+		summary, _ := json.Marshal(result)
+		return tx.Create(&models.AuditLog{
+			Actor:      opts.Actor,
+			EntityType: user.TableName(),
+			EntityID:   id,
+			Action:     models.AuditActionErase,
+			Changes:    summary,
+			RequestID:  opts.RequestID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// redactAuditLogs 去除实体审计日志中的个人信息；scrub 为 false 或替换后不再是合法JSON时清空变更内容
+func redactAuditLogs(tx *gorm.DB, entityType string, ids []uint, pii *regexp.Regexp, scrub bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := tx.Model(&models.AuditLog{}).Where("entity_type = ? AND entity_id IN ? AND changes IS NOT NULL", entityType, ids)
+	if !scrub {
+		if err := query.Update("changes", nil).Error; err != nil {
+			return fmt.Errorf("清除 %s 审计历史失败: %v", entityType, err)
+		}
+		return nil
+	}
+	if pii == nil {
+		return nil
+	}
+
+	var logs []models.AuditLog
+	if err := query.Select("id", "changes").Find(&logs).Error; err != nil {
+		return fmt.Errorf("读取 %s 审计历史失败: %v", entityType, err)
+	}
+	for _, entry := range logs {
+		changes := scrubText(string(entry.Changes), pii)
+		if changes == string(entry.Changes) {
+			continue
+		}
+		var value interface{} = changes
+		if !json.Valid([]byte(changes)) {
+			value = nil
+		}
+		if err := tx.Model(&models.AuditLog{}).Where("id = ?", entry.ID).Update("changes", value).Error; err != nil {
+			return fmt.Errorf("清除 %s 审计历史失败: %v", entityType, err)
+		}
+	}
+	return nil
+}
+
+// piiPattern 构造匹配用户个人信息（用户名、邮箱、姓名）的正则，不区分大小写
+func piiPattern(user models.User, profile models.Profile) *regexp.Regexp {
+	var parts []string
+	for _, v := range []string{user.Email, user.Username, profile.FirstName + profile.LastName, profile.FirstName, profile.LastName} {
+		// 过短的值容易误伤正文，不做替换
+		if v = strings.TrimSpace(v); len([]rune(v)) >= 2 {
+			parts = append(parts, regexp.QuoteMeta(v))
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(parts, "|"))
+}
+
+// scrubText 将文本中的个人信息替换为占位文本
+func scrubText(text string, pii *regexp.Regexp) string {
+	if pii == nil {
+		return text
+	}
+	return pii.ReplaceAllLiteralString(text, ErasedPlaceholder)
+}
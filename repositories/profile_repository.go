@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProfileRepository 用户资料仓储接口
+type ProfileRepository interface {
+	WithContext(ctx context.Context) ProfileRepository
+	FindByUserID(userID uint) (*models.Profile, error)
+	Upsert(profile *models.Profile, columns []string) error
+	UpdateVersioned(profile *models.Profile, columns []string, version uint) error
+}
+
+// profileRepository 用户资料仓储实现
+type profileRepository struct {
+	db *gorm.DB
+}
+
+// NewProfileRepository 创建新的用户资料仓储实例
+func NewProfileRepository() ProfileRepository {
+	return &profileRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *profileRepository) WithContext(ctx context.Context) ProfileRepository {
+	return &profileRepository{db: database.Conn(ctx, r.db)}
+}
+
+// FindByUserID 根据用户ID查找资料
+func (r *profileRepository) FindByUserID(userID uint) (*models.Profile, error) {
+	var profile models.Profile
+	err := r.db.Where("user_id = ?", userID).First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Upsert 一条语句插入或更新资料：不存在时插入，存在时只更新 columns 指定的列并将版本号加一
+func (r *profileRepository) Upsert(profile *models.Profile, columns []string) error {
+	updates := append(append([]string{}, columns...), "updated_at")
+	set := append(clause.AssignmentColumns(updates), clause.Assignment{
+		Column: clause.Column{Name: versionColumn},
+		Value:  nextVersion(),
+	})
+	return r.db.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: set,
+	}).Create(profile).Error
+}
+
+// UpdateVersioned 只更新 columns 指定的列，仅当资料版本号仍为 version 时生效；
+// 资料在读取后已被修改或已不存在时返回 ErrConflict
+func (r *profileRepository) UpdateVersioned(profile *models.Profile, columns []string, version uint) error {
+	profile.Version = version
+	return saveVersioned(r.db.Where("user_id = ?", profile.UserID), profile, &profile.Version, columns...)
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+)
+
+// PostSearchFilter 文章搜索条件
+type PostSearchFilter struct {
+	Keyword       string
+	Status        string // 为空时只搜索已发布的文章
+	IncludeDrafts bool   // Status 为空时不限状态（包括草稿和已归档），需要调用方显式开启
+	AuthorID      uint
+	Tags          []string   // 需要同时包含的标签
+	From          *time.Time // 发布时间下限（含）
+	To            *time.Time // 发布时间上限（不含）
+}
+
+// PostSearchResult 文章搜索结果
+type PostSearchResult struct {
+	Post  models.Post
+	Score float64
+}
+
+// UserSearchResult 用户搜索结果
+type UserSearchResult struct {
+	User  models.User
+	Score float64
+}
+
+// SearchRepository 全文搜索仓储接口
+type SearchRepository interface {
+	WithContext(ctx context.Context) SearchRepository
+	SearchPosts(filter PostSearchFilter, page, pageSize int) ([]PostSearchResult, int64, error)
+	SearchUsers(keyword string, active ActiveFilter, page, pageSize int) ([]UserSearchResult, int64, error)
+}
+
+// searchRepository 全文搜索仓储实现
+type searchRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository 创建新的搜索仓储实例
+func NewSearchRepository() SearchRepository {
+	return &searchRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *searchRepository) WithContext(ctx context.Context) SearchRepository {
+	return &searchRepository{db: database.Conn(ctx, r.db)}
+}
+
+// fullText 当前方言是否支持FULLTEXT索引
+func (r *searchRepository) fullText() bool {
+	return r.db.Dialector.Name() == "mysql"
+}
+
+// scoredID 按相关度排序的主键
+type scoredID struct {
+	ID    uint
+	Score float64
+}
+
+// SearchPosts 搜索文章，按相关度排序
+func (r *searchRepository) SearchPosts(filter PostSearchFilter, page, pageSize int) ([]PostSearchResult, int64, error) {
+	query := r.db.Model(&models.Post{})
+
+	switch {
+	case filter.Status != "":
+		query = query.Where("status = ?", filter.Status)
+	case !filter.IncludeDrafts:
+		query = query.Where("status = ?", "published")
+	}
+	if filter.AuthorID != 0 {
+		query = query.Where("author_id = ?", filter.AuthorID)
+	}
+	query = whereTags(query, filter.Tags, true)
+	if filter.From != nil {
+		query = query.Where("published_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("published_at < ?", *filter.To)
+	}
+
+	score := gorm.Expr("0")
+	if filter.Keyword != "" {
+		if r.fullText() {
+			match := "MATCH(title, content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+			query = query.Where(match, filter.Keyword)
+			score = gorm.Expr(match, filter.Keyword)
+		} else {
+			// 不支持全文索引时退化为LIKE，标题命中权重更高
+			pattern := "%" + escapeLike(filter.Keyword) + "%"
+			query = query.Where("title LIKE ? OR content LIKE ?", pattern, pattern)
+			score = gorm.Expr("(CASE WHEN title LIKE ? THEN 2 ELSE 0 END) + (CASE WHEN content LIKE ? THEN 1 ELSE 0 END)", pattern, pattern)
+		}
+	}
+
+	// 获取总数
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 先取出当前页的ID和得分，再加载完整数据
+	var ids []scoredID
+	err := query.Select("id, ? AS score", score).
+		Order("score DESC").Order("published_at DESC").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return []PostSearchResult{}, total, nil
+	}
+
+	var posts []models.Post
+	if err := r.db.Preload("Author").Where("id IN ?", idsOf(ids)).Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[uint]models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	results := make([]PostSearchResult, 0, len(ids))
+	for _, item := range ids {
+		if post, ok := byID[item.ID]; ok {
+			results = append(results, PostSearchResult{Post: post, Score: item.Score})
+		}
+	}
+
+	return results, total, nil
+}
+
+// SearchUsers 按用户名、邮箱前缀和个人简介搜索用户，按相关度排序；keyword 为空时按注册时间倒序列出
+func (r *searchRepository) SearchUsers(keyword string, active ActiveFilter, page, pageSize int) ([]UserSearchResult, int64, error) {
+	prefix := escapeLike(keyword) + "%"
+	query := r.db.Model(&models.User{}).Joins("LEFT JOIN profiles ON profiles.user_id = users.id")
+	query = active.apply(query, "users")
+
+	var score interface{}
+	switch {
+	case keyword == "":
+		score = gorm.Expr("0")
+	case r.fullText():
+		match := "MATCH(profiles.bio) AGAINST (? IN NATURAL LANGUAGE MODE)"
+		query = query.Where("users.username LIKE ? OR users.email LIKE ? OR "+match, prefix, prefix, keyword)
+		score = gorm.Expr("(CASE WHEN users.username LIKE ? THEN 2 ELSE 0 END) + (CASE WHEN users.email LIKE ? THEN 1 ELSE 0 END) + "+match,
+			prefix, prefix, keyword)
+	default:
+		pattern := "%" + escapeLike(keyword) + "%"
+		query = query.Where("users.username LIKE ? OR users.email LIKE ? OR profiles.bio LIKE ?", prefix, prefix, pattern)
+		score = gorm.Expr("(CASE WHEN users.username LIKE ? THEN 2 ELSE 0 END) + (CASE WHEN users.email LIKE ? THEN 1 ELSE 0 END) + (CASE WHEN profiles.bio LIKE ? THEN 1 ELSE 0 END)",
+			prefix, prefix, pattern)
+	}
+
+	// 获取总数
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var ids []scoredID
+	err := query.Select("users.id AS id, ? AS score", score).
+		Order("score DESC").Order("users.created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return []UserSearchResult{}, total, nil
+	}
+
+	var users []models.User
+	if err := r.db.Preload("Profile").Where("id IN ?", idsOf(ids)).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	results := make([]UserSearchResult, 0, len(ids))
+	for _, item := range ids {
+		if user, ok := byID[item.ID]; ok {
+			results = append(results, UserSearchResult{User: user, Score: item.Score})
+		}
+	}
+
+	return results, total, nil
+}
+
+// idsOf 提取主键列表
+func idsOf(items []scoredID) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+// likeEscaper 转义LIKE通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike 转义关键字中的LIKE通配符
+func escapeLike(keyword string) string {
+	return likeEscaper.Replace(keyword)
+}
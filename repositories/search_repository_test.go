@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+)
+
+func TestSearchPostsDefaultsToPublished(t *testing.T) {
+	db := testdb.Open(t)
+	author := models.User{Username: "author", Email: "author@example.com", Password: "secret123"}
+	if err := db.Create(&author).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []models.Post{
+		{Title: "Go 并发", Content: "已发布的文章", Slug: "published", Status: "published"},
+		{Title: "Go 草稿", Content: "还没写完", Slug: "draft", Status: "draft"},
+		{Title: "Go 旧文", Content: "已归档", Slug: "archived", Status: "archived"},
+	} {
+		p.AuthorID = author.ID
+		if err := db.Omit("Author", "Comments").Create(&p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := NewSearchRepository()
+	tests := []struct {
+		name   string
+		filter PostSearchFilter
+		want   []string
+	}{
+		{"默认只搜索已发布", PostSearchFilter{Keyword: "Go"}, []string{"published"}},
+		{"显式指定草稿", PostSearchFilter{Keyword: "Go", Status: "draft"}, []string{"draft"}},
+		{"显式包含草稿", PostSearchFilter{Keyword: "Go", IncludeDrafts: true}, []string{"archived", "draft", "published"}},
+		{"无关键字也只列出已发布", PostSearchFilter{}, []string{"published"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, total, err := repo.SearchPosts(tt.filter, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]bool)
+			for _, r := range results {
+				got[r.Post.Slug] = true
+			}
+			if int(total) != len(tt.want) || len(got) != len(tt.want) {
+				t.Fatalf("total = %d, results = %v, want %v", total, got, tt.want)
+			}
+			for _, slug := range tt.want {
+				if !got[slug] {
+					t.Errorf("结果中缺少 %s: %v", slug, got)
+				}
+			}
+		})
+	}
+}
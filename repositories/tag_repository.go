@@ -0,0 +1,271 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// TagCount 标签及其使用次数
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// TagRepository 标签仓储接口（文章和课程的JSON标签列）
+type TagRepository interface {
+	WithContext(ctx context.Context) TagRepository
+	FindPostsByTags(tags []string, matchAll bool, page, pageSize int) ([]models.Post, int64, error)
+	FindCoursesByTags(tags []string, matchAll bool, page, pageSize int) ([]models.Course, int64, error)
+	PostTagCloud() ([]TagCount, error)
+	CourseTagCloud() ([]TagCount, error)
+	RenameTag(oldTag, newTag string) (int64, error)
+	MergeTags(sources []string, target string) (int64, error)
+}
+
+// tagRepository 标签仓储实现
+type tagRepository struct {
+	db *gorm.DB
+}
+
+// NewTagRepository 创建新的标签仓储实例
+func NewTagRepository() TagRepository {
+	return &tagRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *tagRepository) WithContext(ctx context.Context) TagRepository {
+	return &tagRepository{db: database.Conn(ctx, r.db)}
+}
+
+// taggedTables 带有标签列的表
+var taggedTables = []interface{}{&models.Post{}, &models.Course{}}
+
+// whereTags 添加标签过滤条件，matchAll为true时需包含全部标签，否则包含任一即可
+func whereTags(query *gorm.DB, tags []string, matchAll bool) *gorm.DB {
+	if len(tags) == 0 {
+		return query
+	}
+	if matchAll {
+		for _, tag := range tags {
+			query = query.Where(datatypes.JSONArrayQuery("tags").Contains(tag))
+		}
+		return query
+	}
+
+	group := query.Session(&gorm.Session{NewDB: true}).Where(datatypes.JSONArrayQuery("tags").Contains(tags[0]))
+	for _, tag := range tags[1:] {
+		group = group.Or(datatypes.JSONArrayQuery("tags").Contains(tag))
+	}
+	return query.Where(group)
+}
+
+// FindPostsByTags 按标签查找文章
+func (r *tagRepository) FindPostsByTags(tags []string, matchAll bool, page, pageSize int) ([]models.Post, int64, error) {
+	var posts []models.Post
+	var total int64
+
+	query := whereTags(r.db.Model(&models.Post{}), tags, matchAll)
+
+	// 获取总数
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("created_at DESC").Find(&posts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return posts, total, nil
+}
+
+// FindCoursesByTags 按标签查找课程
+func (r *tagRepository) FindCoursesByTags(tags []string, matchAll bool, page, pageSize int) ([]models.Course, int64, error) {
+	var courses []models.Course
+	var total int64
+
+	query := whereTags(r.db.Model(&models.Course{}), tags, matchAll)
+
+	// 获取总数
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err := query.Offset((page - 1) * pageSize).Limit(pageSize).Order("start_date DESC").Find(&courses).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return courses, total, nil
+}
+
+// PostTagCloud 统计文章标签使用次数
+func (r *tagRepository) PostTagCloud() ([]TagCount, error) {
+	return r.tagCloud(&models.Post{}, "posts")
+}
+
+// CourseTagCloud 统计课程标签使用次数
+func (r *tagRepository) CourseTagCloud() ([]TagCount, error) {
+	return r.tagCloud(&models.Course{}, "courses")
+}
+
+// tagCloudSQL 在 MySQL 中用 JSON_TABLE 展开标签数组并分组计数；
+// 按二进制排序规则分组，与 Go 中的字符串比较一致（大小写不同视为不同标签）
+const tagCloudSQL = `
+	SELECT jt.tag COLLATE utf8mb4_bin AS tag, COUNT(DISTINCT t.id) AS count
+	FROM %s AS t,
+		JSON_TABLE(t.tags, '$[*]' COLUMNS(tag VARCHAR(255) PATH '$', value JSON PATH '$')) AS jt
+	WHERE JSON_TYPE(jt.value) = 'STRING'
+	GROUP BY jt.tag COLLATE utf8mb4_bin
+	ORDER BY count DESC, tag`
+
+// tagCloud 统计标签使用次数（按次数降序，次数相同按标签名排序），同一行中重复的标签只计一次
+// MySQL 在数据库中聚合；其他方言逐行读取标签列在内存中计数
+func (r *tagRepository) tagCloud(model interface{}, table string) ([]TagCount, error) {
+	if r.db.Dialector.Name() == "mysql" {
+		cloud := []TagCount{}
+		if err := r.db.Raw(fmt.Sprintf(tagCloudSQL, r.db.Statement.Quote(table))).Scan(&cloud).Error; err != nil {
+			return nil, err
+		}
+		return cloud, nil
+	}
+
+	rows, err := r.db.Model(model).Select("tags").Where("tags IS NOT NULL").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var data datatypes.JSON
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		tags, err := models.ParseTags(data)
+		if err != nil {
+			continue // 跳过历史遗留的非法数据
+		}
+		for _, tag := range dedupeTags(tags) {
+			counts[tag]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cloud := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		cloud = append(cloud, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(cloud, func(i, j int) bool {
+		if cloud[i].Count != cloud[j].Count {
+			return cloud[i].Count > cloud[j].Count
+		}
+		return cloud[i].Tag < cloud[j].Tag
+	})
+
+	return cloud, nil
+}
+
+// RenameTag 在所有文章和课程中重命名标签，返回修改的行数
+func (r *tagRepository) RenameTag(oldTag, newTag string) (int64, error) {
+	return r.MergeTags([]string{oldTag}, newTag)
+}
+
+// MergeTags 将多个标签合并为目标标签（所有文章和课程），返回修改的行数
+func (r *tagRepository) MergeTags(sources []string, target string) (int64, error) {
+	if target == "" {
+		return 0, models.ErrInvalidTags
+	}
+
+	replace := make(map[string]bool, len(sources))
+	for _, tag := range sources {
+		if tag != target {
+			replace[tag] = true
+		}
+	}
+	if len(replace) == 0 {
+		return 0, nil
+	}
+
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range taggedTables {
+			n, err := rewriteTags(tx, model, sources, replace, target)
+			if err != nil {
+				return err
+			}
+			affected += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, nil
+}
+
+// taggedRow 标签改写时读取的行
+type taggedRow struct {
+	ID   uint
+	Tags datatypes.JSON
+}
+
+// rewriteTags 改写包含待替换标签的行
+func rewriteTags(tx *gorm.DB, model interface{}, sources []string, replace map[string]bool, target string) (int64, error) {
+	var rows []taggedRow
+	query := tx.Model(model).Select("id, tags")
+	query = whereTags(query, sources, false)
+	if err := query.Find(&rows).Error; err != nil {
+		return 0, fmt.Errorf("查询待改写标签失败: %v", err)
+	}
+
+	var affected int64
+	for _, row := range rows {
+		tags, err := models.ParseTags(row.Tags)
+		if err != nil {
+			continue
+		}
+		for i, tag := range tags {
+			if replace[tag] {
+				tags[i] = target
+			}
+		}
+		err = tx.Model(model).Where("id = ?", row.ID).UpdateColumns(map[string]interface{}{
+			"tags":        models.NewTags(dedupeTags(tags)...),
+			versionColumn: nextVersion(),
+		}).Error
+		if err != nil {
+			return 0, fmt.Errorf("更新标签失败 (ID: %d): %v", row.ID, err)
+		}
+		affected++
+	}
+
+	return affected, nil
+}
+
+// dedupeTags 去除重复标签（保留首次出现的顺序）
+func dedupeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+)
+
+func TestPostTagCloud(t *testing.T) {
+	db := testdb.Open(t)
+	for i, tags := range [][]string{
+		{"go", "db", "go"}, // 同一行中重复的标签只计一次
+		{"go", "Go"},       // 大小写不同视为不同标签
+		{"db"},
+		nil,
+	} {
+		post := models.Post{Title: "t", Content: "c", Slug: string(rune('a' + i)), Status: "published"}
+		if tags != nil {
+			post.Tags = models.NewTags(tags...)
+		}
+		if err := db.Omit("Author", "Comments").Create(&post).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cloud, err := NewTagRepository().PostTagCloud()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TagCount{{"db", 2}, {"go", 2}, {"Go", 1}}
+	if !reflect.DeepEqual(cloud, want) {
+		t.Errorf("PostTagCloud() = %v, want %v", cloud, want)
+	}
+}
@@ -1,25 +1,58 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"exercise/database"
 	"exercise/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ActiveFilter 按账户状态过滤（零值只包含活跃账户）
+type ActiveFilter int
+
+const (
+	ActiveOnly     ActiveFilter = iota // 只包含活跃账户
+	InactiveOnly                       // 只包含已停用账户
+	AnyActiveState                     // 不限账户状态
+)
+
+// apply 为查询添加账户状态条件
+func (f ActiveFilter) apply(query *gorm.DB, table string) *gorm.DB {
+	switch f {
+	case ActiveOnly:
+		return query.Where(table+".is_active = ?", true)
+	case InactiveOnly:
+		return query.Where(table+".is_active = ?", false)
+	}
+	return query
+}
+
 // UserRepository 用户仓储接口
 type UserRepository interface {
+	WithContext(ctx context.Context) UserRepository
 	Create(user *models.User) error
+	BatchCreate(users []models.User) error
 	FindByID(id uint) (*models.User, error)
+	FindByIDForUpdate(id uint) (*models.User, error)
 	FindByEmail(email string) (*models.User, error)
 	FindByUsername(username string) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id uint) error
-	FindAll(page, pageSize int) ([]models.User, int64, error)
-	Search(keyword string, page, pageSize int) ([]models.User, int64, error)
+	FindAll(active ActiveFilter, page, pageSize int) ([]models.User, int64, error)
 	Count() (int64, error)
+	Exists(id uint) (bool, error)
+	Deactivate(id, version uint, by, reason string, at time.Time) error
+	Reactivate(id, version uint) error
+	FindDeletedByID(id uint) (*models.User, error)
+	Restore(id uint) error
+	ListDeleted(page, pageSize int) ([]models.User, int64, error)
+	Purge(id uint) error
+	PurgeDeletedBefore(cutoff time.Time, batchSize int) (int64, error)
 }
 
 // userRepository 用户仓储实现
@@ -34,11 +67,28 @@ func NewUserRepository() UserRepository {
 	}
 }
 
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *userRepository) WithContext(ctx context.Context) UserRepository {
+	return &userRepository{db: database.Conn(ctx, r.db)}
+}
+
 // Create 创建用户
 func (r *userRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
+// BatchCreate 在一个事务中批量创建用户，任一用户失败则全部回滚；成功后 users 中的ID已回填
+func (r *userRepository) BatchCreate(users []models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range users {
+			if err := tx.Create(&users[i]).Error; err != nil {
+				return fmt.Errorf("创建用户 %s 失败: %w", users[i].Username, err)
+			}
+		}
+		return nil
+	})
+}
+
 // FindByID 根据ID查找用户（包含关联数据）
 func (r *userRepository) FindByID(id uint) (*models.User, error) {
 	var user models.User
@@ -49,6 +99,17 @@ func (r *userRepository) FindByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByIDForUpdate 只读取用户表的列（不加载资料和文章），用于修改账户状态前读取当前状态和版本号；
+// 在事务中调用时锁定该行直到事务结束
+func (r *userRepository) FindByIDForUpdate(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // FindByEmail 根据邮箱查找用户
 func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 	var user models.User
@@ -69,9 +130,9 @@ func (r *userRepository) FindByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
-// Update 更新用户
+// Update 更新用户的全部字段，用户在读取后已被修改时返回 ErrConflict
 func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+	return saveVersioned(r.db, user, &user.Version)
 }
 
 // Delete 软删除用户
@@ -79,21 +140,22 @@ func (r *userRepository) Delete(id uint) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
-// FindAll 分页查找所有用户
-func (r *userRepository) FindAll(page, pageSize int) ([]models.User, int64, error) {
+// FindAll 按账户状态分页查找用户
+func (r *userRepository) FindAll(active ActiveFilter, page, pageSize int) ([]models.User, int64, error) {
 	var users []models.User
 	var total int64
 
 	// 计算偏移量
 	offset := (page - 1) * pageSize
+	query := active.apply(r.db.Model(&models.User{}), "users")
 
 	// 获取总数
-	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 获取分页数据
-	err := r.db.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error
+	err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -101,24 +163,76 @@ func (r *userRepository) FindAll(page, pageSize int) ([]models.User, int64, erro
 	return users, total, nil
 }
 
-// Search 搜索用户
-func (r *userRepository) Search(keyword string, page, pageSize int) ([]models.User, int64, error) {
-	var users []models.User
-	var total int64
+// Count 统计用户数量
+func (r *userRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Count(&count).Error
+	return count, err
+}
 
-	offset := (page - 1) * pageSize
-	searchPattern := "%" + keyword + "%"
+// Exists 判断用户是否存在（不含已软删除的）
+func (r *userRepository) Exists(id uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
 
-	// 构建查询条件
-	query := r.db.Where("username LIKE ? OR email LIKE ?", searchPattern, searchPattern)
+// Deactivate 停用用户并记录停用时间、操作人和原因，版本号不是 version 时返回 ErrConflict
+func (r *userRepository) Deactivate(id, version uint, by, reason string, at time.Time) error {
+	return updateVersioned(r.db, &models.User{ID: id}, version, map[string]interface{}{
+		"is_active":           false,
+		"deactivated_at":      at,
+		"deactivated_by":      by,
+		"deactivation_reason": reason,
+	})
+}
 
-	// 获取总数
-	if err := query.Model(&models.User{}).Count(&total).Error; err != nil {
+// Reactivate 重新启用用户并清空停用信息，版本号不是 version 时返回 ErrConflict
+func (r *userRepository) Reactivate(id, version uint) error {
+	return updateVersioned(r.db, &models.User{ID: id}, version, map[string]interface{}{
+		"is_active":           true,
+		"deactivated_at":      nil,
+		"deactivated_by":      "",
+		"deactivation_reason": "",
+	})
+}
+
+// FindDeletedByID 根据ID查找已软删除的用户
+func (r *userRepository) FindDeletedByID(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore 恢复已软删除的用户
+func (r *userRepository) Restore(id uint) error {
+	result := r.db.Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListDeleted 分页查找已软删除的用户（按删除时间倒序）
+func (r *userRepository) ListDeleted(page, pageSize int) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := r.db.Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL")
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// 获取分页数据
-	err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error
+	offset := (page - 1) * pageSize
+	err := query.Order("deleted_at DESC").Offset(offset).Limit(pageSize).Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -126,21 +240,71 @@ func (r *userRepository) Search(keyword string, page, pageSize int) ([]models.Us
 	return users, total, nil
 }
 
-// Count 统计用户数量
-func (r *userRepository) Count() (int64, error) {
-	var count int64
-	err := r.db.Model(&models.User{}).Count(&count).Error
-	return count, err
-}
-
-// 事务示例：批量创建用户
-func (r *userRepository) BatchCreate(users []models.User) error {
+// Purge 彻底删除已软删除的用户及其资料、评论、点赞和选课记录
+func (r *userRepository) Purge(id uint) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		for _, user := range users {
-			if err := tx.Create(&user).Error; err != nil {
-				return fmt.Errorf("创建用户失败: %v", err)
-			}
+		var ids []uint
+		err := tx.Unscoped().Model(&models.User{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
 		}
-		return nil
+		if len(ids) == 0 {
+			return ErrNotFound
+		}
+		return purgeUsers(tx, ids)
 	})
 }
+
+// PurgeDeletedBefore 分批彻底删除在 cutoff 之前软删除的用户，返回删除的数量
+func (r *userRepository) PurgeDeletedBefore(cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		err := r.db.Unscoped().Model(&models.User{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Order("id").Limit(batchSize).Pluck("id", &ids).Error
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		if err := r.db.Transaction(func(tx *gorm.DB) error {
+			return purgeUsers(tx, ids)
+		}); err != nil {
+			return total, fmt.Errorf("彻底删除用户失败: %v", err)
+		}
+		total += int64(len(ids))
+
+		if len(ids) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// purgeUsers 在事务中彻底删除用户及依赖数据（文章作者由外键置空）
+func purgeUsers(tx *gorm.DB, ids []uint) error {
+	if err := removeLikesByUsers(tx, ids); err != nil {
+		return err
+	}
+	var commentIDs []uint
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("user_id IN ?", ids).Pluck("id", &commentIDs).Error; err != nil {
+		return err
+	}
+	if err := purgeComments(tx, commentIDs); err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM user_courses WHERE user_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM course_teachers WHERE user_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id IN ?", ids).Delete(&models.Profile{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.User{}).Error
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+)
+
+func TestFindByIDForUpdateSkipsAssociations(t *testing.T) {
+	db := testdb.Open(t)
+	user := models.User{Username: "author", Email: "author@example.com", Password: "secret123", IsActive: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	post := models.Post{Title: "文章", Content: "正文", Slug: "post", AuthorID: user.ID}
+	if err := db.Omit("Author", "Comments").Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewUserRepository()
+	got, err := repo.FindByIDForUpdate(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID || !got.IsActive || got.Version != user.Version {
+		t.Errorf("FindByIDForUpdate = {ID:%d IsActive:%v Version:%d}, want {ID:%d IsActive:true Version:%d}",
+			got.ID, got.IsActive, got.Version, user.ID, user.Version)
+	}
+	if got.Posts != nil || got.Profile != nil {
+		t.Errorf("FindByIDForUpdate 不应加载关联: Posts=%v Profile=%v", got.Posts, got.Profile)
+	}
+
+	if _, err := repo.FindByIDForUpdate(user.ID + 100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的用户返回 %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrConflict 数据在读取后已被其他操作修改（乐观锁版本号不一致）
+var ErrConflict = errors.New("数据已被其他操作修改")
+
+// versionColumn 乐观锁版本号列
+// User、Post、Course、Profile 的 Version 是普通的 uint 列，作用同 optimisticlock.Version：
+// 经仓储的写入都通过 saveVersioned/updateVersioned 检查并递增版本号，其他直接修改使用 nextVersion()
+const versionColumn = "version"
+
+// nextVersion 将版本号加一的表达式，绕过乐观锁直接修改数据时也应使用，使持有旧版本的更新失败
+func nextVersion() clause.Expr {
+	return gorm.Expr(versionColumn + " + 1")
+}
+
+// saveVersioned 按条件更新模型的字段（不含关联），仅当数据库中的版本号仍为 *version 时生效；
+// columns 为空时更新全部字段（db 上已 Omit 的列除外），否则只更新 columns 指定的列。
+// 成功后 *version 加一，版本号不一致或行已删除时返回 ErrConflict
+func saveVersioned(db *gorm.DB, model interface{}, version *uint, columns ...string) error {
+	current := *version
+	*version = current + 1
+	omits := append(append([]string{}, db.Statement.Omits...), clause.Associations)
+	query := db.Model(model)
+	if len(columns) == 0 {
+		query = query.Select("*").Omit(append(omits, "created_at")...)
+	} else {
+		query = query.Select(append(append([]string{}, columns...), versionColumn)).Omit(omits...)
+	}
+	result := query.Where(versionColumn+" = ?", current).Updates(model)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrConflict
+	}
+	if result.Error != nil {
+		*version = current
+	}
+	return result.Error
+}
+
+// updateVersioned 更新 columns 指定的列并将版本号加一，仅当版本号仍为 version 时生效，否则返回 ErrConflict
+// model 用于定位行（如 &models.User{ID: id}）
+func updateVersioned(db *gorm.DB, model interface{}, version uint, columns map[string]interface{}) error {
+	columns[versionColumn] = nextVersion()
+	result := db.Model(model).Where(versionColumn+" = ?", version).Updates(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
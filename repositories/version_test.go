@@ -0,0 +1,209 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// createVersionedUser 创建用户并返回两份独立读取的副本
+func createVersionedUser(t *testing.T, db *gorm.DB) (*models.User, *models.User) {
+	t.Helper()
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "secret123", Age: 30, IsActive: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	var a, b models.User
+	if err := db.First(&a, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&b, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &a, &b
+}
+
+func TestStaleWriterConflicts(t *testing.T) {
+	db := testdb.Open(t)
+	a, b := createVersionedUser(t, db)
+	repo := NewUserRepository()
+	version := a.Version
+
+	a.Age = 40
+	if err := repo.Update(a); err != nil {
+		t.Fatalf("第一个写入失败: %v", err)
+	}
+	if a.Version != version+1 {
+		t.Errorf("写入后版本号 = %d, want %d", a.Version, version+1)
+	}
+
+	b.Age = 50
+	if err := repo.Update(b); !errors.Is(err, ErrConflict) {
+		t.Fatalf("持有旧版本的写入返回 %v, want ErrConflict", err)
+	}
+	if b.Version != version {
+		t.Errorf("冲突后版本号 = %d, want 恢复为 %d", b.Version, version)
+	}
+	if err := repo.Deactivate(b.ID, b.Version, "admin", "", b.UpdatedAt); !errors.Is(err, ErrConflict) {
+		t.Errorf("持有旧版本停用返回 %v, want ErrConflict", err)
+	}
+
+	var stored models.User
+	if err := db.First(&stored, a.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Age != 40 || stored.Version != version+1 || !stored.IsActive {
+		t.Errorf("数据库中 = {Age:%d Version:%d IsActive:%v}, want {Age:40 Version:%d IsActive:true}",
+			stored.Age, stored.Version, stored.IsActive, version+1)
+	}
+
+	// 绕过乐观锁的直接修改同样递增版本号，使持有旧版本的写入失败
+	err := db.Model(&models.User{ID: a.ID}).UpdateColumns(map[string]interface{}{"age": 41, versionColumn: nextVersion()}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Age = 42
+	if err := repo.Update(a); !errors.Is(err, ErrConflict) {
+		t.Errorf("直接修改后旧版本写入返回 %v, want ErrConflict", err)
+	}
+}
+
+func TestConcurrentStaleWritersOnlyOneWins(t *testing.T) {
+	db := testdb.Open(t)
+	first, _ := createVersionedUser(t, db)
+	repo := NewUserRepository()
+
+	const writers = 8
+	copies := make([]models.User, writers)
+	for i := range copies {
+		if err := db.First(&copies[i], first.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		copies[i].Age = 40 + i
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, writers)
+	for i := range copies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = repo.Update(&copies[i])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if winner >= 0 {
+				t.Errorf("写入 %d 和 %d 都成功了", winner, i)
+			}
+			winner = i
+		case !errors.Is(err, ErrConflict):
+			t.Errorf("写入 %d 返回 %v, want ErrConflict", i, err)
+		}
+	}
+	if winner < 0 {
+		t.Fatal("没有写入成功")
+	}
+
+	var stored models.User
+	if err := db.First(&stored, first.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Age != 40+winner || stored.Version != first.Version+1 {
+		t.Errorf("数据库中 = {Age:%d Version:%d}, want {Age:%d Version:%d}", stored.Age, stored.Version, 40+winner, first.Version+1)
+	}
+}
+
+func TestVersionedUpdatesConflict(t *testing.T) {
+	db := testdb.Open(t)
+	author, _ := createVersionedUser(t, db)
+	post := &models.Post{Title: "标题", Content: "正文", Slug: "versioned", AuthorID: author.ID}
+	course := &models.Course{Name: "Go", Title: "Go", Code: "GO101", Schedule: datatypes.NewJSONType(models.Schedule{TimeZone: "UTC"})}
+	profile := &models.Profile{UserID: author.ID, FirstName: "A", LastName: "Lice"}
+	for _, row := range []interface{}{post, course, profile} {
+		if err := db.Omit("Author", "User", "Users", "Teachers").Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// load 读取一份独立的副本及其版本号，update 修改副本并通过仓储写入
+	tests := []struct {
+		name   string
+		load   func() (interface{}, *uint)
+		update func(row interface{}, n int) error
+	}{
+		{"文章", func() (interface{}, *uint) {
+			var p models.Post
+			db.First(&p, post.ID)
+			return &p, &p.Version
+		}, func(row interface{}, n int) error {
+			p := row.(*models.Post)
+			p.Views = 1000 // 浏览量不随文章覆盖
+			p.Title = fmt.Sprintf("标题 %d", n)
+			return NewPostRepository().Update(p)
+		}},
+		{"课程", func() (interface{}, *uint) {
+			var c models.Course
+			db.First(&c, course.ID)
+			return &c, &c.Version
+		}, func(row interface{}, n int) error {
+			c := row.(*models.Course)
+			c.Duration = n
+			return NewCourseRepository().Update(c)
+		}},
+		{"资料", func() (interface{}, *uint) {
+			var p models.Profile
+			db.First(&p, profile.ID)
+			return &p, &p.Version
+		}, func(row interface{}, n int) error {
+			p := row.(*models.Profile)
+			p.Bio = fmt.Sprintf("简介 %d", n)
+			return NewProfileRepository().UpdateVersioned(p, []string{"bio"}, p.Version)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, aVersion := tt.load()
+			b, bVersion := tt.load()
+			version := *aVersion
+
+			if err := tt.update(a, 1); err != nil {
+				t.Fatalf("第一个写入失败: %v", err)
+			}
+			if *aVersion != version+1 {
+				t.Errorf("写入后版本号 = %d, want %d", *aVersion, version+1)
+			}
+			if err := tt.update(b, 2); !errors.Is(err, ErrConflict) {
+				t.Fatalf("持有旧版本的写入返回 %v, want ErrConflict", err)
+			}
+			if *bVersion != version {
+				t.Errorf("冲突后版本号 = %d, want 恢复为 %d", *bVersion, version)
+			}
+			if stored, storedVersion := tt.load(); *storedVersion != version+1 {
+				t.Errorf("数据库中 = %+v, want 版本号 %d", stored, version+1)
+			}
+		})
+	}
+
+	var stored models.Post
+	if err := db.First(&stored, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "标题 1" || stored.Views != 0 {
+		t.Errorf("文章 = {Title:%q Views:%d}, want {Title:\"标题 1\" Views:0}", stored.Title, stored.Views)
+	}
+}
@@ -0,0 +1,301 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeliveryResult 一次 Webhook 请求的结果
+type DeliveryResult struct {
+	Attempt      int    // 第几次发送
+	ResponseCode int    // HTTP 状态码，未收到响应时为0
+	ResponseBody string // 响应的开头部分
+	Error        string // 失败原因，成功时为空
+	Duration     time.Duration
+}
+
+// columns 记录到投递日志中的列
+func (r DeliveryResult) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"response_code": r.ResponseCode,
+		"response_body": r.ResponseBody,
+		"last_error":    r.Error,
+		"duration_ms":   r.Duration.Milliseconds(),
+	}
+}
+
+// attempt 投递的请求记录
+func (r DeliveryResult) attempt(deliveryID uint) *models.WebhookDeliveryAttempt {
+	return &models.WebhookDeliveryAttempt{
+		DeliveryID:   deliveryID,
+		Attempt:      r.Attempt,
+		ResponseCode: r.ResponseCode,
+		ResponseBody: r.ResponseBody,
+		Error:        r.Error,
+		DurationMs:   r.Duration.Milliseconds(),
+	}
+}
+
+// WebhookRepository Webhook 订阅与投递仓储接口
+type WebhookRepository interface {
+	WithContext(ctx context.Context) WebhookRepository
+	Create(webhook *models.Webhook) error
+	FindByID(id uint) (*models.Webhook, error)
+	FindAll() ([]models.Webhook, error)
+	FindSubscribed(eventType string) ([]models.Webhook, error)
+	Delete(id uint) error
+	EnqueueDeliveries(deliveries []models.WebhookDelivery) (int64, error)
+	ClaimDeliveries(limit int, lease time.Duration, now time.Time) ([]models.WebhookDelivery, error)
+	MarkSucceeded(id uint, result DeliveryResult, at time.Time) error
+	MarkFailed(id uint, result DeliveryResult, next time.Time) error
+	MarkDead(id uint, result DeliveryResult) error
+	ListDeliveries(webhookID uint, status string, page, pageSize int) ([]models.WebhookDelivery, int64, error)
+	ListAttempts(deliveryID uint) ([]models.WebhookDeliveryAttempt, error)
+	Redeliver(id uint, now time.Time) error
+	PurgeSucceededBefore(cutoff time.Time, batchSize int) (int64, error)
+}
+
+// webhookRepository Webhook 订阅与投递仓储实现
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository 创建新的 Webhook 仓储实例
+func NewWebhookRepository() WebhookRepository {
+	return &webhookRepository{
+		db: database.GetDB(),
+	}
+}
+
+// WithContext 返回使用 ctx 的仓储，ctx 中有 TxManager 开启的事务时加入该事务
+func (r *webhookRepository) WithContext(ctx context.Context) WebhookRepository {
+	return &webhookRepository{db: database.Conn(ctx, r.db)}
+}
+
+// Create 创建 Webhook 订阅
+func (r *webhookRepository) Create(webhook *models.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// FindByID 根据ID查找 Webhook 订阅
+func (r *webhookRepository) FindByID(id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.First(&webhook, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// FindAll 查找全部 Webhook 订阅
+func (r *webhookRepository) FindAll() ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// FindSubscribed 查找订阅了事件类型的启用中的 Webhook
+// 订阅数量很少，在内存中按事件类型过滤，不依赖数据库的 JSON 函数
+func (r *webhookRepository) FindSubscribed(eventType string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.db.Where("is_active = ?", true).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	result := webhooks[:0]
+	for _, w := range webhooks {
+		if w.Subscribes(eventType) {
+			result = append(result, w)
+		}
+	}
+	return result, nil
+}
+
+// Delete 删除 Webhook 订阅及其投递记录
+func (r *webhookRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		deliveries := tx.Model(&models.WebhookDelivery{}).Select("id").Where("webhook_id = ?", id)
+		if err := tx.Where("delivery_id IN (?)", deliveries).Delete(&models.WebhookDeliveryAttempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// EnqueueDeliveries 添加待发送的投递，同一事件向同一 Webhook 已有投递时忽略（事件重复投递时保持幂等）
+// 返回新增的数量
+func (r *webhookRepository) EnqueueDeliveries(deliveries []models.WebhookDelivery) (int64, error) {
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	result := r.db.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries)
+	return result.RowsAffected, result.Error
+}
+
+// ClaimDeliveries 领取最多 limit 个到期的待发送投递（按ID顺序），发送次数加一并把下次发送时间推迟 lease，
+// 在此期间其他发送任务不会领取；与 OutboxRepository.Claim 相同，使用 SKIP LOCKED
+func (r *webhookRepository) ClaimDeliveries(limit int, lease time.Duration, now time.Time) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Order("id").Limit(limit).Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+			deliveries[i].Attempts++
+			deliveries[i].NextAttemptAt = now.Add(lease)
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(lease),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// MarkSucceeded 记录投递成功
+func (r *webhookRepository) MarkSucceeded(id uint, result DeliveryResult, at time.Time) error {
+	columns := result.columns()
+	columns["status"] = models.DeliverySucceeded
+	columns["delivered_at"] = at
+	return r.recordResult(id, result, columns)
+}
+
+// MarkFailed 记录投递失败，在 next 之后重试
+func (r *webhookRepository) MarkFailed(id uint, result DeliveryResult, next time.Time) error {
+	columns := result.columns()
+	columns["next_attempt_at"] = next
+	return r.recordResult(id, result, columns)
+}
+
+// MarkDead 重试次数用尽，记录最终失败（不再自动重试）
+func (r *webhookRepository) MarkDead(id uint, result DeliveryResult) error {
+	columns := result.columns()
+	columns["status"] = models.DeliveryFailed
+	return r.recordResult(id, result, columns)
+}
+
+// recordResult 在一个事务中更新投递的最近结果并写入这次请求的记录
+func (r *webhookRepository) recordResult(id uint, result DeliveryResult, columns map[string]interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(columns).Error; err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Create(result.attempt(id)).Error
+	})
+}
+
+// ListDeliveries 分页查找投递记录（按ID倒序），webhookID 为0、status 为空时不按该条件过滤
+func (r *webhookRepository) ListDeliveries(webhookID uint, status string, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	query := r.db.Model(&models.WebhookDelivery{})
+	if webhookID != 0 {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// ListAttempts 查找投递的全部请求记录（按发送顺序），投递不存在时返回 ErrNotFound
+func (r *webhookRepository) ListAttempts(deliveryID uint) ([]models.WebhookDeliveryAttempt, error) {
+	var count int64
+	if err := r.db.Model(&models.WebhookDelivery{}).Where("id = ?", deliveryID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNotFound
+	}
+
+	var attempts []models.WebhookDeliveryAttempt
+	err := r.db.Where("delivery_id = ?", deliveryID).Order("id").Find(&attempts).Error
+	return attempts, err
+}
+
+// Redeliver 将投递（无论当前状态）重新放回待发送队列，发送次数清零；投递不存在时返回 ErrNotFound
+func (r *webhookRepository) Redeliver(id uint, now time.Time) error {
+	result := r.db.Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"delivered_at":    nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeSucceededBefore 分批删除在 cutoff 之前成功的投递记录及其请求记录，返回删除的数量
+func (r *webhookRepository) PurgeSucceededBefore(cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		err := r.db.Model(&models.WebhookDelivery{}).
+			Where("status = ? AND delivered_at < ?", models.DeliverySucceeded, cutoff).
+			Order("id").Limit(batchSize).Pluck("id", &ids).Error
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		var deleted int64
+		err = r.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("delivery_id IN ?", ids).Delete(&models.WebhookDeliveryAttempt{}).Error; err != nil {
+				return err
+			}
+			result := tx.Where("id IN ?", ids).Delete(&models.WebhookDelivery{})
+			deleted = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+
+		if len(ids) < batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package seed
+
+// 生成数据使用的词库
+
+var surnames = []string{
+	"Wang", "Li", "Zhang", "Liu", "Chen", "Yang", "Zhao", "Huang", "Zhou", "Wu",
+	"Xu", "Sun", "Hu", "Zhu", "Gao", "Lin", "He", "Guo", "Ma", "Luo",
+}
+
+var givenNames = []string{
+	"Wei", "Fang", "Na", "Min", "Jing", "Li", "Qiang", "Lei", "Jun", "Yang",
+	"Yong", "Yan", "Jie", "Tao", "Ming", "Chao", "Xiu", "Xia", "Ping", "Gang",
+}
+
+var emailDomains = []string{"example.com", "mail.example.org", "campus.example.edu", "dev.example.net"}
+
+var cities = []string{"北京", "上海", "广州", "深圳", "杭州", "成都", "武汉", "南京", "西安", "苏州"}
+
+var bios = []string{
+	"后端工程师，关注数据库和分布式系统",
+	"前端开发者，喜欢设计和交互",
+	"在校学生，正在学习Go语言",
+	"数据分析师，每天和SQL打交道",
+	"运维工程师，负责线上稳定性",
+	"产品经理，偶尔写点代码",
+	"开源爱好者",
+	"",
+}
+
+// topic 文章主题
+type topic struct {
+	name string
+	slug string
+	tags []string
+}
+
+var topics = []topic{
+	{"GORM关联查询", "gorm-associations", []string{"go", "gorm", "database"}},
+	{"MySQL索引优化", "mysql-indexes", []string{"mysql", "database", "performance"}},
+	{"Go并发模式", "go-concurrency", []string{"go", "concurrency"}},
+	{"数据库事务", "database-transactions", []string{"database", "transaction"}},
+	{"Redis缓存设计", "redis-cache", []string{"redis", "cache", "performance"}},
+	{"微服务拆分", "microservices", []string{"architecture", "microservices"}},
+	{"单元测试实践", "unit-testing", []string{"go", "testing"}},
+	{"Docker部署", "docker-deploy", []string{"docker", "devops"}},
+	{"REST接口设计", "rest-api-design", []string{"api", "architecture"}},
+	{"日志与监控", "logging-monitoring", []string{"devops", "observability"}},
+}
+
+var titleTemplates = []string{"%s入门", "%s实战笔记", "深入理解%s", "%s常见问题", "%s踩坑记录"}
+
+var paragraphs = []string{
+	"这篇文章记录了我在项目中的一些实践经验，希望对大家有所帮助。",
+	"首先需要明确问题的边界，再选择合适的工具和方案。",
+	"在数据量较小时这种写法没有问题，但数据增长后需要重新评估。",
+	"建议先在测试环境验证，再逐步推广到生产环境。",
+	"官方文档对这部分的描述比较简略，下面结合示例详细说明。",
+	"总结一下：保持简单，度量先行，按需优化。",
+}
+
+var commentTexts = []string{
+	"写得很清楚，受益匪浅！",
+	"请问这个方案在高并发下表现如何？",
+	"我们项目也遇到了同样的问题，感谢分享。",
+	"有没有完整的示例代码？",
+	"第二部分没太看懂，能再展开讲讲吗？",
+	"赞同，实践中确实如此。",
+	"补充一点：记得加上超时控制。",
+	"收藏了，周末试试。",
+}
+
+// subject 课程科目
+type subject struct {
+	name     string
+	code     string
+	category string
+	tags     []string
+}
+
+var subjects = []subject{
+	{"Go语言程序设计", "GO", "编程语言", []string{"go", "programming"}},
+	{"数据库系统原理", "DB", "数据库", []string{"database", "mysql"}},
+	{"Web后端开发", "WEB", "后端开发", []string{"web", "api"}},
+	{"数据结构与算法", "DSA", "计算机基础", []string{"algorithm"}},
+	{"云原生运维", "OPS", "运维", []string{"devops", "docker"}},
+	{"软件工程实践", "SE", "软件工程", []string{"testing", "architecture"}},
+}
+
+var rooms = []string{"A101", "A203", "B305", "C102", "实验楼402"}
@@ -0,0 +1,227 @@
+package seed
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"time"
+
+	"exercise/models"
+
+	"gorm.io/datatypes"
+)
+
+// baseTime 生成数据的起始时间，固定取值保证同一种子生成相同的数据
+var baseTime = time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local)
+
+// generator 可复现的数据生成器
+// 每个实体使用由（种子, 类型, 序号）派生的独立随机源，调整数量不会改变已有实体的数据
+type generator struct {
+	seed int64
+	opts Options
+}
+
+// rand 返回某个实体的随机源
+func (g *generator) rand(kind string, index ...int) *rand.Rand {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%s/%v", g.seed, kind, index)
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// user 生成第 i 个用户及其资料，inactive 表示该用户应为停用状态
+func (g *generator) user(i int) (user models.User, profile models.Profile, inactive bool) {
+	r := g.rand("user", i)
+	first, last := pick(r, givenNames), pick(r, surnames)
+	username := fmt.Sprintf("%s.%s%d", strings.ToLower(first), strings.ToLower(last), i+1)
+	created := baseTime.Add(time.Duration(r.Intn(365*24)) * time.Hour)
+
+	user = models.User{
+		Username:  username,
+		Email:     username + "@" + pick(r, emailDomains),
+		Password:  fmt.Sprintf("Seed%06dPass", r.Intn(1000000)),
+		Age:       18 + r.Intn(48),
+		IsActive:  true,
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	profile = models.Profile{
+		FirstName: first,
+		LastName:  last,
+		Bio:       pick(r, bios),
+		Location:  pick(r, cities),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	if r.Intn(3) == 0 {
+		profile.Website = "https://" + strings.ReplaceAll(username, ".", "-") + ".example.com"
+	}
+	return user, profile, r.Intn(10) == 0
+}
+
+// postCount 第 i 个用户的文章数
+func (g *generator) postCount(i int) int {
+	return g.rand("posts", i).Intn(g.opts.PostsPerUser + 1)
+}
+
+// post 生成第 i 个用户的第 k 篇文章
+func (g *generator) post(i, k int, authorCreated time.Time) models.Post {
+	r := g.rand("post", i, k)
+	t := topics[r.Intn(len(topics))]
+	created := authorCreated.Add(time.Duration(1+r.Intn(90*24)) * time.Hour)
+
+	paras := make([]string, 2+r.Intn(3))
+	for j := range paras {
+		paras[j] = pick(r, paragraphs)
+	}
+	tags := append([]string{}, t.tags[:1+r.Intn(len(t.tags))]...)
+
+	post := models.Post{
+		Title:     fmt.Sprintf(pick(r, titleTemplates), t.name),
+		Content:   strings.Join(paras, "\n\n"),
+		Slug:      fmt.Sprintf("%s-%d-%d", t.slug, i+1, k+1),
+		Status:    "published",
+		Views:     r.Intn(5000),
+		Tags:      models.NewTags(tags...),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	switch n := r.Intn(10); {
+	case n < 2:
+		post.Status, post.Views = "draft", 0
+	case n < 3:
+		post.Status = "archived"
+	}
+	if post.Status != "draft" {
+		published := created.Add(time.Duration(r.Intn(48)) * time.Hour)
+		post.PublishedAt = &published
+	}
+	return post
+}
+
+// commentPlan 一条待插入的评论，parent 为同一文章中父评论的下标（-1 表示顶层评论）
+type commentPlan struct {
+	comment models.Comment
+	author  int   // 作者的用户序号
+	likers  []int // 点赞用户的序号（不重复）
+	parent  int
+	depth   int
+}
+
+// comments 生成第 i 个用户第 k 篇文章下的评论（最多三层嵌套）
+func (g *generator) comments(i, k int, postCreated time.Time) []commentPlan {
+	r := g.rand("comments", i, k)
+	plans := make([]commentPlan, r.Intn(g.opts.CommentsPerPost+1))
+	for j := range plans {
+		plan := commentPlan{author: r.Intn(g.opts.Users), parent: -1}
+		if j > 0 && r.Intn(5) < 2 {
+			if parent := r.Intn(j); plans[parent].depth < 2 {
+				plan.parent, plan.depth = parent, plans[parent].depth+1
+			}
+		}
+		created := postCreated.Add(time.Duration(1+j*6+r.Intn(6)) * time.Hour)
+		plan.likers = sample(r, g.opts.Users, r.Intn(50))
+		plan.comment = models.Comment{
+			Content:   pick(r, commentTexts),
+			Rating:    1 + r.Intn(5),
+			Likes:     len(plan.likers),
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+		plans[j] = plan
+	}
+	return plans
+}
+
+// course 生成第 c 门课程，teachers 为任课教师的用户序号
+func (g *generator) course(c int) (course models.Course, teachers []int) {
+	r := g.rand("course", c)
+	s := subjects[c%len(subjects)]
+	term := c/len(subjects) + 1
+	start := baseTime.AddDate(0, 0, 7*r.Intn(52))
+	end := start.AddDate(0, 0, 7*(8+r.Intn(9)))
+
+	var schedule models.Schedule
+	for _, day := range r.Perm(5)[:1+r.Intn(2)] {
+		hour := 8 + 2*r.Intn(6)
+		session := models.WeeklySession{
+			Weekday: time.Weekday(day + 1),
+			Start:   fmt.Sprintf("%02d:00", hour),
+			End:     fmt.Sprintf("%02d:45", hour+1),
+		}
+		if r.Intn(4) == 0 {
+			session.Link = fmt.Sprintf("https://meeting.example.com/%s%03d", strings.ToLower(s.code), c+1)
+		} else {
+			session.Room = pick(r, rooms)
+		}
+		schedule.Weekly = append(schedule.Weekly, session)
+	}
+
+	course = models.Course{
+		Name:        s.name,
+		Title:       fmt.Sprintf("%s（第%d期）", s.name, term),
+		Description: fmt.Sprintf("%s课程，共%d周。", s.name, int(end.Sub(start).Hours()/24/7)),
+		Code:        fmt.Sprintf("%s%03d", s.code, c+1),
+		Category:    s.category,
+		Price:       float64(r.Intn(20)) * 50,
+		Duration:    16 + 8*r.Intn(6),
+		IsActive:    true,
+		Tags:        models.NewTags(s.tags...),
+		Schedule:    datatypes.NewJSONType(schedule),
+		StartDate:   start,
+		EndDate:     end,
+	}
+
+	if g.opts.Users > 0 {
+		first := r.Intn(g.opts.Users)
+		teachers = append(teachers, first)
+		if g.opts.Users > 1 && r.Intn(2) == 0 {
+			teachers = append(teachers, (first+1+r.Intn(g.opts.Users-1))%g.opts.Users)
+		}
+	}
+	return course, teachers
+}
+
+// enrollments 生成第 i 个用户的选课记录（课程序号不重复）
+func (g *generator) enrollments(i int, userCreated time.Time) (courses []int, rows []models.UserCourse) {
+	if g.opts.Courses == 0 {
+		return nil, nil
+	}
+	r := g.rand("enrollments", i)
+	n := min(g.opts.Courses, r.Intn(g.opts.EnrollmentsPerUser+1))
+	for _, c := range r.Perm(g.opts.Courses)[:n] {
+		row := models.UserCourse{
+			EnrolledAt: userCreated.Add(time.Duration(1+r.Intn(30*24)) * time.Hour),
+			Status:     models.EnrollmentEnrolled,
+		}
+		switch n := r.Intn(10); {
+		case n < 4:
+			grade := float64(60 + r.Intn(41))
+			row.Status, row.Grade = models.EnrollmentCompleted, &grade
+		case n < 5:
+			row.Status = models.EnrollmentDropped
+		}
+		courses = append(courses, c)
+		rows = append(rows, row)
+	}
+	return courses, rows
+}
+
+// sample 从 [0, n) 中随机取 k 个不重复的数（k 超过 n 时取 n 个）
+func sample(r *rand.Rand, n, k int) []int {
+	k = min(k, n)
+	seen := make(map[int]bool, k)
+	result := make([]int, 0, k)
+	for len(result) < k {
+		if v := r.Intn(n); !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// pick 随机取一个元素
+func pick(r *rand.Rand, items []string) string {
+	return items[r.Intn(len(items))]
+}
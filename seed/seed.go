@@ -0,0 +1,385 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Options 填充选项
+type Options struct {
+	Seed               int64 // 随机数种子，相同种子生成相同的数据
+	Users              int   // 用户数
+	PostsPerUser       int   // 每个用户最多的文章数
+	CommentsPerPost    int   // 每篇文章最多的评论数
+	Courses            int   // 课程数
+	EnrollmentsPerUser int   // 每个用户最多选修的课程数
+	BatchSize          int   // 每批插入的行数
+}
+
+// DefaultOptions 默认填充规模
+func DefaultOptions() Options {
+	return Options{
+		Seed:               1,
+		Users:              50,
+		PostsPerUser:       3,
+		CommentsPerPost:    5,
+		Courses:            12,
+		EnrollmentsPerUser: 3,
+		BatchSize:          200,
+	}
+}
+
+// Report 填充结果（只统计本次新增的数据）
+type Report struct {
+	Users       int `json:"users"`
+	Profiles    int `json:"profiles"`
+	Posts       int `json:"posts"`
+	Comments    int `json:"comments"`
+	Likes       int `json:"likes"`
+	Courses     int `json:"courses"`
+	Teachers    int `json:"teachers"`
+	Enrollments int `json:"enrollments"`
+	Skipped     int `json:"skipped"` // 已存在而跳过的用户、文章和课程
+}
+
+// Run 按选项生成并插入数据
+// 用户名、文章 slug、课程代码相同的数据视为已存在并跳过，因此可以重复执行；
+// 评论、任课和选课记录只为本次新增的文章、课程和用户生成，增加数量时不会改动已有数据
+func Run(opts Options) (*Report, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+	if cfg := database.CurrentConfig(); cfg != nil && cfg.App.IsProduction() {
+		return nil, errors.New("生产环境不能填充示例数据")
+	}
+	if opts.Users <= 0 {
+		return nil, errors.New("用户数必须大于0")
+	}
+	if opts.PostsPerUser < 0 || opts.CommentsPerPost < 0 || opts.Courses < 0 || opts.EnrollmentsPerUser < 0 {
+		return nil, errors.New("数量不能为负数")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultOptions().BatchSize
+	}
+
+	s := &seeder{gen: &generator{seed: opts.Seed, opts: opts}, opts: opts, report: &Report{}}
+
+	// 填充数据不写审计日志，也不产生领域事件
+	ctx := models.WithoutEvents(database.WithoutAudit(context.Background()))
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		s.tx = tx
+		if err := s.seedUsers(); err != nil {
+			return fmt.Errorf("填充用户失败: %v", err)
+		}
+		if err := s.seedPosts(); err != nil {
+			return fmt.Errorf("填充文章失败: %v", err)
+		}
+		if err := s.seedCourses(); err != nil {
+			return fmt.Errorf("填充课程失败: %v", err)
+		}
+		if err := s.seedEnrollments(); err != nil {
+			return fmt.Errorf("填充选课记录失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.report, nil
+}
+
+// seeder 一次填充的状态
+type seeder struct {
+	tx     *gorm.DB
+	gen    *generator
+	opts   Options
+	report *Report
+
+	userIDs     []uint      // 按用户序号
+	userCreated []time.Time // 按用户序号
+	newUsers    []bool      // 按用户序号，是否为本次新增
+	courseIDs   []uint      // 按课程序号
+}
+
+// seedUsers 插入用户和资料，并记录全部用户（含已存在的）的ID
+func (s *seeder) seedUsers() error {
+	users := make([]models.User, s.opts.Users)
+	profiles := make([]models.Profile, s.opts.Users)
+	inactive := make([]bool, s.opts.Users)
+	usernames := make([]string, s.opts.Users)
+	for i := range users {
+		users[i], profiles[i], inactive[i] = s.gen.user(i)
+		usernames[i] = users[i].Username
+	}
+
+	existing, err := s.existingIDs(&models.User{}, "username", usernames)
+	if err != nil {
+		return err
+	}
+
+	var newUsers []models.User
+	var newIdx []int
+	for i, u := range users {
+		if _, ok := existing[u.Username]; ok {
+			s.report.Skipped++
+			continue
+		}
+		newUsers = append(newUsers, u)
+		newIdx = append(newIdx, i)
+	}
+	if len(newUsers) > 0 {
+		if err := s.tx.CreateInBatches(&newUsers, s.opts.BatchSize).Error; err != nil {
+			return err
+		}
+	}
+
+	var newProfiles []models.Profile
+	var deactivated []uint
+	for j, u := range newUsers {
+		i := newIdx[j]
+		existing[u.Username] = u.ID
+		profiles[i].UserID = u.ID
+		newProfiles = append(newProfiles, profiles[i])
+		if inactive[i] {
+			deactivated = append(deactivated, u.ID)
+		}
+	}
+	if len(newProfiles) > 0 {
+		if err := s.tx.CreateInBatches(&newProfiles, s.opts.BatchSize).Error; err != nil {
+			return err
+		}
+	}
+
+	// is_active 有默认值，插入 false 会被忽略，停用状态单独更新
+	if len(deactivated) > 0 {
+		err := s.tx.Model(&models.User{}).Where("id IN ?", deactivated).UpdateColumns(map[string]interface{}{
+			"is_active":           false,
+			"deactivated_at":      baseTime.AddDate(1, 0, 0),
+			"deactivated_by":      "seed",
+			"deactivation_reason": "示例数据",
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	s.userIDs = make([]uint, s.opts.Users)
+	s.userCreated = make([]time.Time, s.opts.Users)
+	s.newUsers = make([]bool, s.opts.Users)
+	for _, i := range newIdx {
+		s.newUsers[i] = true
+	}
+	for i, u := range users {
+		s.userIDs[i] = existing[u.Username]
+		s.userCreated[i] = u.CreatedAt
+	}
+	s.report.Users += len(newUsers)
+	s.report.Profiles += len(newProfiles)
+	return nil
+}
+
+// seedPosts 插入文章，并为新插入的文章生成评论和点赞
+func (s *seeder) seedPosts() error {
+	type postKey struct{ user, index int }
+	var posts []models.Post
+	var keys []postKey
+	var slugs []string
+	for i := 0; i < s.opts.Users; i++ {
+		for k := 0; k < s.gen.postCount(i); k++ {
+			post := s.gen.post(i, k, s.userCreated[i])
+			post.AuthorID = s.userIDs[i]
+			posts = append(posts, post)
+			keys = append(keys, postKey{i, k})
+			slugs = append(slugs, post.Slug)
+		}
+	}
+
+	existing, err := s.existingIDs(&models.Post{}, "slug", slugs)
+	if err != nil {
+		return err
+	}
+	var newPosts []models.Post
+	var newKeys []postKey
+	for j, p := range posts {
+		if _, ok := existing[p.Slug]; ok {
+			s.report.Skipped++
+			continue
+		}
+		newPosts = append(newPosts, p)
+		newKeys = append(newKeys, keys[j])
+	}
+	if len(newPosts) == 0 {
+		return nil
+	}
+	if err := s.tx.Omit(clause.Associations).CreateInBatches(&newPosts, s.opts.BatchSize).Error; err != nil {
+		return err
+	}
+	s.report.Posts += len(newPosts)
+
+	// 评论按层级插入，父评论先插入以获得ID
+	var plans [][]commentPlan
+	for j, p := range newPosts {
+		comments := s.gen.comments(newKeys[j].user, newKeys[j].index, p.CreatedAt)
+		for c := range comments {
+			comments[c].comment.PostID = p.ID
+			comments[c].comment.UserID = s.userIDs[comments[c].author]
+		}
+		plans = append(plans, comments)
+	}
+	for depth := 0; ; depth++ {
+		var batch []*models.Comment
+		for _, comments := range plans {
+			for c := range comments {
+				plan := &comments[c]
+				if plan.depth != depth {
+					continue
+				}
+				if plan.parent >= 0 {
+					parentID := comments[plan.parent].comment.ID
+					plan.comment.ParentID = &parentID
+				}
+				batch = append(batch, &plan.comment)
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := s.tx.Omit(clause.Associations).CreateInBatches(batch, s.opts.BatchSize).Error; err != nil {
+			return err
+		}
+		s.report.Comments += len(batch)
+	}
+
+	// 点赞记录与评论的点赞数一致
+	var likes []map[string]interface{}
+	for _, comments := range plans {
+		for _, plan := range comments {
+			for j, liker := range plan.likers {
+				likes = append(likes, map[string]interface{}{
+					"comment_id": plan.comment.ID,
+					"user_id":    s.userIDs[liker],
+					"created_at": plan.comment.CreatedAt.Add(time.Duration(j+1) * time.Minute),
+				})
+			}
+		}
+	}
+	n, err := s.insertIgnore("comment_likes", likes)
+	s.report.Likes += n
+	return err
+}
+
+// seedCourses 插入课程和任课教师
+func (s *seeder) seedCourses() error {
+	courses := make([]models.Course, s.opts.Courses)
+	teachers := make([][]int, s.opts.Courses)
+	codes := make([]string, s.opts.Courses)
+	for c := range courses {
+		courses[c], teachers[c] = s.gen.course(c)
+		codes[c] = courses[c].Code
+	}
+
+	existing, err := s.existingIDs(&models.Course{}, "code", codes)
+	if err != nil {
+		return err
+	}
+	var newCourses []models.Course
+	var newIdx []int
+	for c, course := range courses {
+		if _, ok := existing[course.Code]; ok {
+			s.report.Skipped++
+			continue
+		}
+		newCourses = append(newCourses, course)
+		newIdx = append(newIdx, c)
+	}
+	if len(newCourses) > 0 {
+		if err := s.tx.Omit(clause.Associations).CreateInBatches(&newCourses, s.opts.BatchSize).Error; err != nil {
+			return err
+		}
+	}
+	for _, c := range newCourses {
+		existing[c.Code] = c.ID
+	}
+	s.report.Courses += len(newCourses)
+
+	s.courseIDs = make([]uint, s.opts.Courses)
+	for c := range courses {
+		s.courseIDs[c] = existing[courses[c].Code]
+	}
+	var rows []map[string]interface{}
+	for _, c := range newIdx {
+		for _, t := range teachers[c] {
+			rows = append(rows, map[string]interface{}{"course_id": s.courseIDs[c], "user_id": s.userIDs[t]})
+		}
+	}
+	n, err := s.insertIgnore("course_teachers", rows)
+	s.report.Teachers += n
+	return err
+}
+
+// seedEnrollments 为新增的用户插入选课记录
+func (s *seeder) seedEnrollments() error {
+	var rows []models.UserCourse
+	for i := 0; i < s.opts.Users; i++ {
+		if !s.newUsers[i] {
+			continue
+		}
+		courses, enrollments := s.gen.enrollments(i, s.userCreated[i])
+		for j, c := range courses {
+			enrollments[j].UserID = s.userIDs[i]
+			enrollments[j].CourseID = s.courseIDs[c]
+			rows = append(rows, enrollments[j])
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	res := s.tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&rows, s.opts.BatchSize)
+	s.report.Enrollments += int(res.RowsAffected)
+	return res.Error
+}
+
+// existingIDs 按唯一列查询已存在的记录（含已软删除的），返回 列值 -> ID
+func (s *seeder) existingIDs(model interface{}, column string, values []string) (map[string]uint, error) {
+	ids := make(map[string]uint, len(values))
+	for start := 0; start < len(values); start += s.opts.BatchSize {
+		end := min(start+s.opts.BatchSize, len(values))
+		var rows []struct {
+			ID         uint
+			NaturalKey string
+		}
+		err := s.tx.Unscoped().Model(model).Select("id, "+column+" AS natural_key").
+			Where(column+" IN ?", values[start:end]).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			ids[row.NaturalKey] = row.ID
+		}
+	}
+	return ids, nil
+}
+
+// insertIgnore 批量插入中间表或点赞记录，主键冲突的行忽略，返回实际插入的行数
+func (s *seeder) insertIgnore(table string, rows []map[string]interface{}) (int, error) {
+	inserted := 0
+	for start := 0; start < len(rows); start += s.opts.BatchSize {
+		end := min(start+s.opts.BatchSize, len(rows))
+		res := s.tx.Table(table).Clauses(clause.OnConflict{DoNothing: true}).Create(rows[start:end])
+		if res.Error != nil {
+			return inserted, res.Error
+		}
+		inserted += int(res.RowsAffected)
+	}
+	return inserted, nil
+}
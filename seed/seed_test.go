@@ -0,0 +1,143 @@
+package seed_test
+
+import (
+	"slices"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+	"exercise/seed"
+
+	"gorm.io/gorm"
+)
+
+// smallOptions 测试用的小规模填充
+func smallOptions(seedValue int64) seed.Options {
+	return seed.Options{
+		Seed:               seedValue,
+		Users:              8,
+		PostsPerUser:       2,
+		CommentsPerPost:    3,
+		Courses:            4,
+		EnrollmentsPerUser: 2,
+		BatchSize:          3,
+	}
+}
+
+// snapshot 数据库中的示例数据摘要
+type snapshot struct {
+	users    []string
+	posts    []string
+	courses  []string
+	comments []string
+}
+
+// take 读取数据摘要（不含自增ID，便于在不同数据库之间比较）
+func take(t *testing.T, db *gorm.DB) snapshot {
+	t.Helper()
+	var s snapshot
+	var users []models.User
+	if err := db.Order("username").Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range users {
+		s.users = append(s.users, u.Username+"|"+u.Email+"|"+u.CreatedAt.UTC().String())
+	}
+	if err := db.Model(&models.Post{}).Order("slug").Pluck("slug", &s.posts).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Course{}).Order("code").Pluck("code", &s.courses).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Comment{}).Order("content").Pluck("content", &s.comments).Error; err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRunIsDeterministic(t *testing.T) {
+	first := testdb.Open(t)
+	report, err := seed.Run(smallOptions(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Users != 8 || report.Courses != 4 || report.Posts == 0 || report.Comments == 0 || report.Skipped != 0 {
+		t.Errorf("report = %+v, want 8 个用户、4 门课程以及文章和评论", report)
+	}
+	want := take(t, first)
+
+	second := testdb.Open(t)
+	if _, err := seed.Run(smallOptions(7)); err != nil {
+		t.Fatal(err)
+	}
+	got := take(t, second)
+	for name, pair := range map[string][2][]string{
+		"用户": {got.users, want.users},
+		"文章": {got.posts, want.posts},
+		"课程": {got.courses, want.courses},
+		"评论": {got.comments, want.comments},
+	} {
+		if !slices.Equal(pair[0], pair[1]) {
+			t.Errorf("相同种子生成的%s不同:\n%v\n%v", name, pair[0], pair[1])
+		}
+	}
+
+	third := testdb.Open(t)
+	if _, err := seed.Run(smallOptions(8)); err != nil {
+		t.Fatal(err)
+	}
+	if other := take(t, third); slices.Equal(other.users, want.users) {
+		t.Error("不同种子生成了相同的用户")
+	}
+}
+
+func TestRunIsRepeatable(t *testing.T) {
+	db := testdb.Open(t)
+	first, err := seed.Run(smallOptions(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := take(t, db)
+
+	again, err := seed.Run(smallOptions(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Users != 0 || again.Posts != 0 || again.Courses != 0 || again.Comments != 0 || again.Enrollments != 0 {
+		t.Errorf("重复执行新增了数据: %+v", again)
+	}
+	if want := first.Users + first.Posts + first.Courses; again.Skipped != want {
+		t.Errorf("跳过 %d 条, want %d", again.Skipped, want)
+	}
+	after := take(t, db)
+	if !slices.Equal(before.users, after.users) || !slices.Equal(before.posts, after.posts) || len(before.comments) != len(after.comments) {
+		t.Error("重复执行改动了已有数据")
+	}
+
+	// 增加用户数时只为新增的用户生成数据
+	more := smallOptions(1)
+	more.Users = 10
+	report, err := seed.Run(more)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Users != 2 {
+		t.Errorf("新增用户 %d 个, want 2", report.Users)
+	}
+	if n := len(take(t, db).users); n != 10 {
+		t.Errorf("用户总数 = %d, want 10", n)
+	}
+}
+
+func TestRunRecordsNoEvents(t *testing.T) {
+	db := testdb.Open(t)
+	if _, err := seed.Run(smallOptions(1)); err != nil {
+		t.Fatal(err)
+	}
+	var events, audits int64
+	db.Model(&models.OutboxEvent{}).Count(&events)
+	db.Model(&models.AuditLog{}).Count(&audits)
+	if events != 0 || audits != 0 {
+		t.Errorf("填充数据产生了 %d 条事件、%d 条审计日志, want 0", events, audits)
+	}
+}
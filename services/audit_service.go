@@ -0,0 +1,86 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"exercise/models"
+	"exercise/repositories"
+)
+
+// ErrInvalidEntityType 不支持审计的实体类型
+var ErrInvalidEntityType = errors.New("实体类型无效")
+
+// auditEntityTypes 记录审计日志的实体类型（表名）
+var auditEntityTypes = map[string]bool{"users": true, "posts": true, "courses": true}
+
+// AuditEntry 解析后的审计记录
+type AuditEntry struct {
+	ID         uint                          `json:"id"`
+	Actor      string                        `json:"actor"`
+	EntityType string                        `json:"entity_type"`
+	EntityID   uint                          `json:"entity_id"`
+	Action     string                        `json:"action"`
+	Changes    map[string]models.FieldChange `json:"changes"`
+	RequestID  string                        `json:"request_id"`
+	CreatedAt  time.Time                     `json:"created_at"`
+}
+
+// AuditService 审计服务接口
+type AuditService interface {
+	History(entityType string, entityID uint, page, pageSize int) ([]AuditEntry, int64, error)
+	Search(filter repositories.AuditFilter, page, pageSize int) ([]AuditEntry, int64, error)
+}
+
+// auditServiceImpl 审计服务实现
+type auditServiceImpl struct {
+	auditRepo repositories.AuditRepository
+}
+
+// NewAuditService 创建审计服务
+func NewAuditService() AuditService {
+	return &auditServiceImpl{
+		auditRepo: repositories.NewAuditRepository(),
+	}
+}
+
+// History 获取某条数据的变更历史（按时间倒序）
+func (s *auditServiceImpl) History(entityType string, entityID uint, page, pageSize int) ([]AuditEntry, int64, error) {
+	if !auditEntityTypes[entityType] {
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidEntityType, entityType)
+	}
+	return s.Search(repositories.AuditFilter{EntityType: entityType, EntityID: entityID}, page, pageSize)
+}
+
+// Search 按条件查询审计日志
+func (s *auditServiceImpl) Search(filter repositories.AuditFilter, page, pageSize int) ([]AuditEntry, int64, error) {
+	page, pageSize = normalizePage(page, pageSize)
+
+	logs, total, err := s.auditRepo.Find(filter, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询审计日志失败: %v", err)
+	}
+
+	entries := make([]AuditEntry, 0, len(logs))
+	for _, l := range logs {
+		entry := AuditEntry{
+			ID:         l.ID,
+			Actor:      l.Actor,
+			EntityType: l.EntityType,
+			EntityID:   l.EntityID,
+			Action:     l.Action,
+			RequestID:  l.RequestID,
+			CreatedAt:  l.CreatedAt,
+		}
+		if len(l.Changes) > 0 {
+			if err := json.Unmarshal(l.Changes, &entry.Changes); err != nil {
+				return nil, 0, fmt.Errorf("解析审计日志 %d 失败: %v", l.ID, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	_ "image/png" // 注册 PNG 解码器
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"exercise/models"
+	"exercise/repositories"
+	"exercise/storage"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册 WebP 解码器
+)
+
+var (
+	ErrAvatarTooLarge    = errors.New("头像文件过大")
+	ErrUnsupportedAvatar = errors.New("不支持的头像格式")
+)
+
+// AvatarSizes 头像缩略图边长（像素），第一个为 Profile.AvatarURL 指向的尺寸
+var AvatarSizes = []int{256, 128, 64}
+
+// avatarQuality 缩略图的 JPEG 质量
+const avatarQuality = 85
+
+// avatarFormats 允许上传的图片类型（按文件内容识别）
+var avatarFormats = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// avatarKey 头像对象键：avatars/<用户ID>/<随机串>/<边长>.jpg
+var avatarKey = regexp.MustCompile(`^(avatars/(\d+)/[0-9a-f]+)/\d+\.jpg$`)
+
+// AvatarPolicy 头像上传限制
+type AvatarPolicy struct {
+	MaxBytes  int // 上传文件大小上限（字节）
+	MaxPixels int // 图片像素数上限
+}
+
+// AvatarService 头像服务接口
+type AvatarService interface {
+	UploadAvatar(userID uint, r io.Reader) (*models.Profile, error)
+	RemoveAvatar(userID uint) error
+	AvatarURLs(profile *models.Profile) map[int]string
+}
+
+// avatarServiceImpl 头像服务实现
+type avatarServiceImpl struct {
+	store       storage.BlobStore
+	policy      AvatarPolicy
+	userRepo    repositories.UserRepository
+	profileRepo repositories.ProfileRepository
+}
+
+// NewAvatarService 创建头像服务
+func NewAvatarService(store storage.BlobStore, policy AvatarPolicy) AvatarService {
+	return &avatarServiceImpl{
+		store:       store,
+		policy:      policy,
+		userRepo:    repositories.NewUserRepository(),
+		profileRepo: repositories.NewProfileRepository(),
+	}
+}
+
+// UploadAvatar 上传头像：校验大小和格式，生成各尺寸缩略图并保存，
+// 更新 Profile.AvatarURL 后删除旧头像
+func (s *avatarServiceImpl) UploadAvatar(userID uint, r io.Reader) (*models.Profile, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(s.policy.MaxBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取头像失败: %v", err)
+	}
+	if len(data) > s.policy.MaxBytes {
+		return nil, fmt.Errorf("%w: 不能超过%d字节", ErrAvatarTooLarge, s.policy.MaxBytes)
+	}
+
+	thumbnails, err := s.thumbnails(data)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.userRepo.Exists(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户失败: %v", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	old, err := s.currentAvatar(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每次上传使用新的目录，旧地址在删除前仍然可用
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("生成头像地址失败: %v", err)
+	}
+	prefix := fmt.Sprintf("avatars/%d/%s", userID, hex.EncodeToString(token))
+
+	for i, size := range AvatarSizes {
+		if err := s.store.Put(avatarSizeKey(prefix, size), thumbnails[i], "image/jpeg"); err != nil {
+			s.deleteAvatar(prefix)
+			return nil, fmt.Errorf("保存头像失败: %v", err)
+		}
+	}
+
+	profile := &models.Profile{UserID: userID, AvatarURL: s.store.URL(avatarSizeKey(prefix, AvatarSizes[0]))}
+	if err := s.profileRepo.Upsert(profile, []string{"avatar_url"}); err != nil {
+		s.deleteAvatar(prefix)
+		return nil, fmt.Errorf("更新头像地址失败: %v", err)
+	}
+	if old != "" {
+		s.deleteAvatar(old)
+	}
+
+	updated, err := s.profileRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	return updated, nil
+}
+
+// RemoveAvatar 清空头像地址并删除头像文件
+func (s *avatarServiceImpl) RemoveAvatar(userID uint) error {
+	profile, err := s.profileRepo.FindByUserID(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	if profile.AvatarURL == "" {
+		return nil
+	}
+
+	old, _ := s.avatarPrefix(profile.AvatarURL, userID)
+	profile.AvatarURL = ""
+	if err := s.profileRepo.Upsert(profile, []string{"avatar_url"}); err != nil {
+		return fmt.Errorf("更新头像地址失败: %v", err)
+	}
+	if old != "" {
+		s.deleteAvatar(old)
+	}
+	return nil
+}
+
+// AvatarURLs 返回各尺寸头像的地址，头像不是由本服务上传时只返回原地址
+func (s *avatarServiceImpl) AvatarURLs(profile *models.Profile) map[int]string {
+	if profile == nil || profile.AvatarURL == "" {
+		return nil
+	}
+	prefix, ok := s.avatarPrefix(profile.AvatarURL, profile.UserID)
+	if !ok {
+		return map[int]string{0: profile.AvatarURL}
+	}
+	urls := make(map[int]string, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		urls[size] = s.store.URL(avatarSizeKey(prefix, size))
+	}
+	return urls
+}
+
+// thumbnails 按文件内容识别格式，解码后生成各尺寸的正方形缩略图
+func (s *avatarServiceImpl) thumbnails(data []byte) ([][]byte, error) {
+	contentType := http.DetectContentType(data)
+	if !avatarFormats[contentType] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAvatar, contentType)
+	}
+
+	// 先读取尺寸，避免解码超大图片耗尽内存
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAvatar, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > s.policy.MaxPixels {
+		return nil, fmt.Errorf("%w: 图片尺寸 %dx%d 超出限制", ErrAvatarTooLarge, cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAvatar, err)
+	}
+
+	// 居中裁剪为正方形
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+
+	thumbnails := make([][]byte, len(AvatarSizes))
+	for i, size := range AvatarSizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		// JPEG 不支持透明，透明部分填充白色
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: avatarQuality}); err != nil {
+			return nil, fmt.Errorf("生成缩略图失败: %v", err)
+		}
+		thumbnails[i] = buf.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// currentAvatar 返回用户当前头像在本存储中的目录，没有或不是由本服务上传时返回空
+func (s *avatarServiceImpl) currentAvatar(userID uint) (string, error) {
+	profile, err := s.profileRepo.FindByUserID(userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	prefix, _ := s.avatarPrefix(profile.AvatarURL, userID)
+	return prefix, nil
+}
+
+// avatarPrefix 从头像地址中取出该用户头像的目录
+func (s *avatarServiceImpl) avatarPrefix(avatarURL string, userID uint) (string, bool) {
+	key, ok := s.store.KeyFromURL(avatarURL)
+	if !ok {
+		return "", false
+	}
+	m := avatarKey.FindStringSubmatch(key)
+	if m == nil || m[2] != strconv.FormatUint(uint64(userID), 10) {
+		return "", false
+	}
+	return m[1], true
+}
+
+// deleteAvatar 删除一次上传的全部尺寸，失败只记录日志
+func (s *avatarServiceImpl) deleteAvatar(prefix string) {
+	for _, size := range AvatarSizes {
+		if err := s.store.Delete(avatarSizeKey(prefix, size)); err != nil {
+			log.Printf("⚠️ 删除头像 %s 失败: %v", prefix, err)
+		}
+	}
+}
+
+// avatarSizeKey 返回某个尺寸的头像对象键
+func avatarSizeKey(prefix string, size int) string {
+	return fmt.Sprintf("%s/%d.jpg", prefix, size)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/storage"
+)
+
+// pngImage 生成 w×h 的纯色 PNG
+func pngImage(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newAvatarTest 创建使用临时目录存储的头像服务，返回存储目录
+func newAvatarTest(t *testing.T, policy AvatarPolicy) (AvatarService, storage.BlobStore, string) {
+	t.Helper()
+	testdb.Open(t)
+	dir := t.TempDir()
+	store, err := storage.NewLocalStore(dir, "http://localhost/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAvatarService(store, policy), store, dir
+}
+
+// storedFiles 返回存储目录下的全部文件（相对路径）
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestUploadAvatarRejectsOversize(t *testing.T) {
+	svc, _, dir := newAvatarTest(t, AvatarPolicy{MaxBytes: 100, MaxPixels: 1 << 20})
+	user := createUser(t, "alice")
+
+	_, err := svc.UploadAvatar(user.ID, bytes.NewReader(pngImage(t, 64, 64)))
+	if !errors.Is(err, ErrAvatarTooLarge) {
+		t.Errorf("超过字节上限返回 %v, want ErrAvatarTooLarge", err)
+	}
+
+	svc = NewAvatarService(storeAt(t, dir), AvatarPolicy{MaxBytes: 1 << 20, MaxPixels: 100})
+	_, err = svc.UploadAvatar(user.ID, bytes.NewReader(pngImage(t, 20, 20)))
+	if !errors.Is(err, ErrAvatarTooLarge) {
+		t.Errorf("超过像素上限返回 %v, want ErrAvatarTooLarge", err)
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("被拒绝的上传留下了文件: %v", files)
+	}
+}
+
+func TestUploadAvatarRejectsWrongType(t *testing.T) {
+	svc, _, dir := newAvatarTest(t, AvatarPolicy{MaxBytes: 1 << 20, MaxPixels: 1 << 20})
+	user := createUser(t, "alice")
+
+	for name, data := range map[string][]byte{
+		"文本":     []byte("hello, this is not an image"),
+		"SVG":    []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`),
+		"损坏的PNG": pngImage(t, 8, 8)[:40],
+	} {
+		if _, err := svc.UploadAvatar(user.ID, bytes.NewReader(data)); !errors.Is(err, ErrUnsupportedAvatar) {
+			t.Errorf("%s: 返回 %v, want ErrUnsupportedAvatar", name, err)
+		}
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("被拒绝的上传留下了文件: %v", files)
+	}
+}
+
+func TestUploadAvatarReplacesOld(t *testing.T) {
+	svc, store, dir := newAvatarTest(t, AvatarPolicy{MaxBytes: 1 << 20, MaxPixels: 1 << 20})
+	user := createUser(t, "alice")
+
+	first, err := svc.UploadAvatar(user.ID, bytes.NewReader(pngImage(t, 300, 200)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := storedFiles(t, dir); len(files) != len(AvatarSizes) {
+		t.Fatalf("首次上传后文件 = %v, want %d 个尺寸", files, len(AvatarSizes))
+	}
+	firstKey, ok := store.KeyFromURL(first.AvatarURL)
+	if !ok || !strings.HasSuffix(firstKey, "/256.jpg") {
+		t.Fatalf("头像地址 = %s", first.AvatarURL)
+	}
+
+	second, err := svc.UploadAvatar(user.ID, bytes.NewReader(pngImage(t, 64, 64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.AvatarURL == first.AvatarURL {
+		t.Fatal("重新上传后头像地址未变化")
+	}
+	if _, err := store.Get(firstKey); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("旧头像未删除: %v", err)
+	}
+	files := storedFiles(t, dir)
+	if len(files) != len(AvatarSizes) {
+		t.Errorf("替换后文件 = %v, want 只剩新头像的 %d 个尺寸", files, len(AvatarSizes))
+	}
+	for size, url := range svc.AvatarURLs(second) {
+		key, _ := store.KeyFromURL(url)
+		data, err := store.Get(key)
+		if err != nil {
+			t.Fatalf("读取 %d 尺寸失败: %v", size, err)
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || cfg.Width != size || cfg.Height != size {
+			t.Errorf("%d 尺寸缩略图 = %dx%d, %v", size, cfg.Width, cfg.Height, err)
+		}
+	}
+
+	if err := svc.RemoveAvatar(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("删除头像后仍有文件: %v", files)
+	}
+}
+
+func TestUploadAvatarUnknownUser(t *testing.T) {
+	svc, _, dir := newAvatarTest(t, AvatarPolicy{MaxBytes: 1 << 20, MaxPixels: 1 << 20})
+	if _, err := svc.UploadAvatar(404, bytes.NewReader(pngImage(t, 16, 16))); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("返回 %v, want ErrUserNotFound", err)
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("不存在的用户留下了文件: %v", files)
+	}
+}
+
+// storeAt 在已有目录上创建本地存储
+func storeAt(t *testing.T, dir string) storage.BlobStore {
+	t.Helper()
+	store, err := storage.NewLocalStore(dir, "http://localhost/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"exercise/database"
+	"exercise/repositories"
+)
+
+// ErrCommentNotFound 评论不存在或已删除
+var ErrCommentNotFound = errors.New("评论不存在")
+
+// CommentService 评论服务接口
+type CommentService interface {
+	LikeComment(commentID, userID uint) (int, error)
+	UnlikeComment(commentID, userID uint) (int, error)
+}
+
+// commentServiceImpl 评论服务实现
+type commentServiceImpl struct {
+	txm         database.TxManager
+	commentRepo repositories.CommentRepository
+	userRepo    repositories.UserRepository
+}
+
+// NewCommentService 创建评论服务
+func NewCommentService() CommentService {
+	return &commentServiceImpl{
+		txm:         database.NewTxManager(),
+		commentRepo: repositories.NewCommentRepository(),
+		userRepo:    repositories.NewUserRepository(),
+	}
+}
+
+// LikeComment 点赞评论（重复点赞不重复计数），返回评论当前的点赞数
+func (s *commentServiceImpl) LikeComment(commentID, userID uint) (int, error) {
+	var likes int
+	err := s.txm.WithinTx(context.Background(), func(ctx context.Context) error {
+		exists, err := s.userRepo.WithContext(ctx).Exists(userID)
+		if err != nil {
+			return fmt.Errorf("获取用户失败: %v", err)
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+
+		commentRepo := s.commentRepo.WithContext(ctx)
+		if _, err := commentRepo.Like(commentID, userID); err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrCommentNotFound
+			}
+			return fmt.Errorf("点赞失败: %v", err)
+		}
+		likes, err = currentLikes(commentRepo, commentID)
+		return err
+	})
+	return likes, err
+}
+
+// UnlikeComment 取消点赞（未点赞时不做修改），返回评论当前的点赞数
+func (s *commentServiceImpl) UnlikeComment(commentID, userID uint) (int, error) {
+	var likes int
+	err := s.txm.WithinTx(context.Background(), func(ctx context.Context) error {
+		commentRepo := s.commentRepo.WithContext(ctx)
+		if _, err := commentRepo.Unlike(commentID, userID); err != nil {
+			return fmt.Errorf("取消点赞失败: %v", err)
+		}
+		var err error
+		likes, err = currentLikes(commentRepo, commentID)
+		return err
+	})
+	return likes, err
+}
+
+// currentLikes 读取评论的点赞数
+func currentLikes(commentRepo repositories.CommentRepository, commentID uint) (int, error) {
+	comment, err := commentRepo.FindByID(commentID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return 0, ErrCommentNotFound
+		}
+		return 0, fmt.Errorf("获取评论失败: %v", err)
+	}
+	return comment.Likes, nil
+}
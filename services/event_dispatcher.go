@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"exercise/models"
+	"exercise/repositories"
+)
+
+// ErrEventNotFound 事件不存在或不在死信中
+var ErrEventNotFound = errors.New("事件不存在或不在死信中")
+
+// EventHandler 领域事件订阅者
+// 事件至少投递一次：处理失败、超时或进程在标记结果前退出时会再次投递，且同一事件类型的
+// 所有订阅者都会重新收到，因此订阅者必须是幂等的（如按事件ID去重）
+type EventHandler func(ctx context.Context, event *models.OutboxEvent) error
+
+// DispatchPolicy 事件投递策略（同样用于 Webhook 发送）
+type DispatchPolicy struct {
+	PollInterval time.Duration // 轮询 outbox 的间隔
+	BatchSize    int           // 每次领取的事件数
+	MaxAttempts  int           // 最多投递次数，用尽后移入死信
+	RetryBackoff time.Duration // 第一次重试前的等待时间，之后每次加倍
+	MaxBackoff   time.Duration // 重试等待时间的上限
+	Timeout      time.Duration // 单个事件（所有订阅者）或单个 Webhook 请求的处理时限
+}
+
+// DispatchReport 一次投递的结果
+type DispatchReport struct {
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"` // 失败后等待重试
+	Dead      int `json:"dead"`   // 重试次数用尽
+}
+
+// EventDispatcher 领域事件分发服务接口
+type EventDispatcher interface {
+	Subscribe(eventType string, handler EventHandler)
+	DispatchPending(ctx context.Context) (*DispatchReport, error)
+	Start(ctx context.Context)
+	ListEvents(status string, page, pageSize int) ([]models.OutboxEvent, int64, error)
+	Requeue(id uint) error
+	RequeueDead() (int64, error)
+}
+
+// eventDispatcherImpl 领域事件分发服务实现
+type eventDispatcherImpl struct {
+	outboxRepo repositories.OutboxRepository
+	policy     DispatchPolicy
+
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+// withDefaults 为未设置的项填入默认值
+func (p DispatchPolicy) withDefaults() DispatchPolicy {
+	if p.PollInterval <= 0 {
+		p.PollInterval = time.Second
+	}
+	if p.BatchSize <= 0 {
+		p.BatchSize = 100
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 5
+	}
+	if p.RetryBackoff <= 0 {
+		p.RetryBackoff = 5 * time.Second
+	}
+	if p.MaxBackoff < p.RetryBackoff {
+		p.MaxBackoff = p.RetryBackoff
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = 30 * time.Second
+	}
+	return p
+}
+
+// lease 领取一批后的租约时长：足够依次处理完整批，进程退出时未处理的在租约到期后被重新领取
+func (p DispatchPolicy) lease() time.Duration {
+	return time.Duration(p.BatchSize) * p.Timeout
+}
+
+// expiring 判断租约是否即将到期（剩余时间不足以再处理一个），到期后其他进程可能已重新领取
+func (p DispatchPolicy) expiring(claimedAt time.Time) bool {
+	return time.Since(claimedAt)+p.Timeout > p.lease()
+}
+
+// backoff 第 attempts 次失败后的等待时间（指数增长，不超过 MaxBackoff）
+func (p DispatchPolicy) backoff(attempts int) time.Duration {
+	return exponentialBackoff(p.RetryBackoff, p.MaxBackoff, attempts)
+}
+
+// NewEventDispatcher 创建领域事件分发服务
+func NewEventDispatcher(policy DispatchPolicy) EventDispatcher {
+	return &eventDispatcherImpl{
+		outboxRepo: repositories.NewOutboxRepository(),
+		policy:     policy.withDefaults(),
+		handlers:   make(map[string][]EventHandler),
+	}
+}
+
+// Subscribe 订阅事件类型（如 models.EventUserRegistered），应在 Start 之前调用
+func (d *eventDispatcherImpl) Subscribe(eventType string, handler EventHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[eventType] = append(d.handlers[eventType], handler)
+}
+
+// DispatchPending 领取一批到期的事件并依次投递给订阅者
+// 没有订阅者的事件直接标记为已投递
+func (d *eventDispatcherImpl) DispatchPending(ctx context.Context) (*DispatchReport, error) {
+	report := &DispatchReport{}
+	claimedAt := time.Now()
+	events, err := d.outboxRepo.Claim(d.policy.BatchSize, d.policy.lease(), claimedAt)
+	if err != nil {
+		return report, fmt.Errorf("领取事件失败: %v", err)
+	}
+
+	var errs []error
+	for i := range events {
+		if ctx.Err() != nil || d.policy.expiring(claimedAt) {
+			// 未处理的事件在租约到期后会被重新领取
+			break
+		}
+		if err := d.dispatch(ctx, &events[i], report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return report, fmt.Errorf("记录投递结果失败: %w", errors.Join(errs...))
+	}
+	return report, nil
+}
+
+// dispatch 投递一个事件并记录结果，只有记录结果失败时返回错误
+func (d *eventDispatcherImpl) dispatch(ctx context.Context, event *models.OutboxEvent, report *DispatchReport) error {
+	var err error
+	// 上次领取后未能记录结果（如处理中进程退出）也计入投递次数，避免有问题的事件反复导致进程退出
+	if event.Attempts > d.policy.MaxAttempts {
+		err = fmt.Errorf("投递 %d 次仍未完成", event.Attempts-1)
+	} else {
+		err = d.deliver(ctx, event)
+	}
+
+	if err == nil {
+		report.Delivered++
+		return d.outboxRepo.MarkDelivered(event.ID, time.Now())
+	}
+	if event.Attempts >= d.policy.MaxAttempts {
+		report.Dead++
+		log.Printf("💀 事件 %d（%s）投递失败 %d 次，已移入死信: %v", event.ID, event.EventType, event.Attempts, err)
+		return d.outboxRepo.MarkDead(event.ID, err.Error())
+	}
+
+	report.Failed++
+	next := time.Now().Add(d.policy.backoff(event.Attempts))
+	log.Printf("⚠️ 事件 %d（%s）第 %d 次投递失败，将于 %s 重试: %v",
+		event.ID, event.EventType, event.Attempts, next.Format(time.RFC3339), err)
+	return d.outboxRepo.MarkFailed(event.ID, err.Error(), next)
+}
+
+// deliver 在时限内依次调用事件类型的全部订阅者，任一订阅者失败或 panic 即视为投递失败
+func (d *eventDispatcherImpl) deliver(ctx context.Context, event *models.OutboxEvent) (err error) {
+	d.mu.RLock()
+	handlers := d.handlers[event.EventType]
+	d.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, d.policy.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("订阅者 panic: %v", r)
+		}
+	}()
+
+	for _, handle := range handlers {
+		if err := handle(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start 在后台定期投递事件，ctx 取消后停止；一批事件已满时立即领取下一批
+func (d *eventDispatcherImpl) Start(ctx context.Context) {
+	go poll(ctx, d.policy, "投递事件", d.DispatchPending)
+}
+
+// poll 按 policy.PollInterval 定期执行 run 直到 ctx 取消；一批已满时不等待，立即执行下一批
+func poll(ctx context.Context, policy DispatchPolicy, name string, run func(ctx context.Context) (*DispatchReport, error)) {
+	ticker := time.NewTicker(policy.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		report, err := run(ctx)
+		if err != nil {
+			log.Printf("❌ %s失败: %v", name, err)
+		}
+		busy := err == nil && report.Delivered+report.Failed+report.Dead >= policy.BatchSize
+
+		if !busy {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// ListEvents 按状态分页列出 outbox 中的事件（如 models.OutboxDead 查看死信）
+func (d *eventDispatcherImpl) ListEvents(status string, page, pageSize int) ([]models.OutboxEvent, int64, error) {
+	switch status {
+	case models.OutboxPending, models.OutboxDelivered, models.OutboxDead:
+	default:
+		return nil, 0, fmt.Errorf("无效的事件状态: %q", status)
+	}
+	events, total, err := d.outboxRepo.ListByStatus(status, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取事件失败: %v", err)
+	}
+	return events, total, nil
+}
+
+// Requeue 将死信事件重新放回待投递队列，投递次数清零
+func (d *eventDispatcherImpl) Requeue(id uint) error {
+	if err := d.outboxRepo.Requeue(id, time.Now()); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrEventNotFound
+		}
+		return fmt.Errorf("重新投递事件失败: %v", err)
+	}
+	return nil
+}
+
+// RequeueDead 将全部死信事件重新放回待投递队列，返回数量
+func (d *eventDispatcherImpl) RequeueDead() (int64, error) {
+	n, err := d.outboxRepo.RequeueDead(time.Now())
+	if err != nil {
+		return n, fmt.Errorf("重新投递死信事件失败: %v", err)
+	}
+	return n, nil
+}
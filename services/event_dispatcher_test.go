@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"exercise/database"
+	"exercise/internal/testdb"
+	"exercise/models"
+	"exercise/repositories"
+
+	"gorm.io/gorm"
+)
+
+// outboxEvents 返回 outbox 中的全部事件（按ID顺序）
+func outboxEvents(t *testing.T, db *gorm.DB) []models.OutboxEvent {
+	t.Helper()
+	var events []models.OutboxEvent
+	if err := db.Order("id").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestRegisterRecordsEventWithUser(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "alice")
+
+	events := outboxEvents(t, db)
+	if len(events) != 1 || events[0].EventType != models.EventUserRegistered || events[0].Status != models.OutboxPending {
+		t.Fatalf("outbox = %+v, want 1 条待投递的 user.registered", events)
+	}
+	var payload models.UserRegistered
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.UserID != user.ID {
+		t.Errorf("事件中的用户ID = %d, want %d", payload.UserID, user.ID)
+	}
+
+	// 事务回滚时事件一并回滚
+	failed := errors.New("后续步骤失败")
+	err := database.NewTxManager().WithinTx(context.Background(), func(ctx context.Context) error {
+		bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "secret123", Age: 30}
+		if err := repositories.NewUserRepository().WithContext(ctx).Create(bob); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithinTx = %v, want %v", err, failed)
+	}
+	if n := len(outboxEvents(t, db)); n != 1 {
+		t.Errorf("回滚后 outbox 中有 %d 条事件, want 1", n)
+	}
+}
+
+func TestDispatchDeliversToSubscribers(t *testing.T) {
+	db := testdb.Open(t)
+	createUser(t, "alice")
+
+	var mu sync.Mutex
+	var received []string
+	dispatcher := NewEventDispatcher(DispatchPolicy{})
+	for _, name := range []string{"first", "second"} {
+		name := name
+		dispatcher.Subscribe(models.EventUserRegistered, func(ctx context.Context, event *models.OutboxEvent) error {
+			mu.Lock()
+			received = append(received, name)
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	report, err := dispatcher.DispatchPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Delivered != 1 {
+		t.Errorf("report = %+v, want 1 条已投递", report)
+	}
+	if len(received) != 2 || received[0] != "first" || received[1] != "second" {
+		t.Errorf("订阅者收到 = %v, want [first second]", received)
+	}
+	event := outboxEvents(t, db)[0]
+	if event.Status != models.OutboxDelivered || event.DeliveredAt == nil {
+		t.Errorf("事件 = %s/%v, want delivered/已投递", event.Status, event.DeliveredAt)
+	}
+
+	// 已投递的事件不再投递
+	if report, err := dispatcher.DispatchPending(context.Background()); err != nil || report.Delivered != 0 {
+		t.Errorf("再次投递 = %+v, %v", report, err)
+	}
+	if len(received) != 2 {
+		t.Errorf("订阅者重复收到事件: %v", received)
+	}
+}
+
+func TestDispatchRetriesThenMovesToDeadLetter(t *testing.T) {
+	db := testdb.Open(t)
+	createUser(t, "alice")
+
+	var mu sync.Mutex
+	calls := 0
+	healthy := false
+	dispatcher := NewEventDispatcher(DispatchPolicy{MaxAttempts: 2, RetryBackoff: time.Millisecond})
+	dispatcher.Subscribe(models.EventUserRegistered, func(ctx context.Context, event *models.OutboxEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		switch {
+		case healthy:
+			return nil
+		case calls == 1:
+			return errors.New("下游不可用")
+		}
+		panic("下游崩溃")
+	})
+
+	var total DispatchReport
+	for i := 0; i < 3; i++ {
+		time.Sleep(10 * time.Millisecond) // 等待退避结束
+		report, err := dispatcher.DispatchPending(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		total.Failed += report.Failed
+		total.Dead += report.Dead
+		total.Delivered += report.Delivered
+	}
+	if total != (DispatchReport{Failed: 1, Dead: 1}) {
+		t.Errorf("投递结果 = %+v, want 失败 1 次后进入死信", total)
+	}
+	event := outboxEvents(t, db)[0]
+	if event.Status != models.OutboxDead || event.Attempts != 2 || event.LastError == "" {
+		t.Errorf("事件 = %s/%d 次/%q, want dead/2 次/有错误", event.Status, event.Attempts, event.LastError)
+	}
+	if calls != 2 {
+		t.Errorf("订阅者被调用 %d 次, want 2", calls)
+	}
+
+	// 人工处理后重新投递
+	healthy = true
+	if err := dispatcher.Requeue(event.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Requeue(event.ID); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("重复放回死信以外的事件 = %v, want ErrEventNotFound", err)
+	}
+	report, err := dispatcher.DispatchPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Delivered != 1 {
+		t.Errorf("重新投递 = %+v, want 1 条已投递", report)
+	}
+	if event := outboxEvents(t, db)[0]; event.Status != models.OutboxDelivered || event.Attempts != 1 {
+		t.Errorf("重新投递后事件 = %s/%d 次, want delivered/1 次", event.Status, event.Attempts)
+	}
+}
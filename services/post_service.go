@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"exercise/models"
+	"exercise/repositories"
+)
+
+// ErrPostNotFound 文章不存在或未发布
+var ErrPostNotFound = errors.New("文章不存在")
+
+// PostService 文章服务接口
+type PostService interface {
+	ViewPost(id uint) (*models.Post, error)
+}
+
+// postServiceImpl 文章服务实现
+type postServiceImpl struct {
+	postRepo repositories.PostRepository
+	views    ViewCounter
+}
+
+// NewPostService 创建文章服务，浏览量记录到 views 中批量写入
+func NewPostService(views ViewCounter) PostService {
+	return &postServiceImpl{
+		postRepo: repositories.NewPostRepository(),
+		views:    views,
+	}
+}
+
+// ViewPost 读取已发布的文章并记录一次浏览，返回的浏览量包含尚未写入数据库的部分
+func (s *postServiceImpl) ViewPost(id uint) (*models.Post, error) {
+	post, err := s.postRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrPostNotFound
+		}
+		return nil, fmt.Errorf("获取文章失败: %v", err)
+	}
+	if post.Status != "published" {
+		return nil, ErrPostNotFound
+	}
+
+	s.views.Record(post.ID)
+	post.Views += int(s.views.Pending(post.ID))
+	return post, nil
+}
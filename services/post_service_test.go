@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+)
+
+func TestViewPostRecordsView(t *testing.T) {
+	db := testdb.Open(t)
+	author := createUser(t, "author")
+	post := createPostBy(t, db, author, "文章", "正文")
+	draft := createPostBy(t, db, author, "草稿", "正文")
+	if err := db.Model(post).UpdateColumns(map[string]interface{}{"status": "published", "views": 10}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	views := NewViewCounter(ViewPolicy{FlushInterval: time.Hour})
+	service := NewPostService(views)
+	for i := 1; i <= 3; i++ {
+		got, err := service.ViewPost(post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Views != 10+i {
+			t.Errorf("第 %d 次浏览后浏览量 = %d, want %d", i, got.Views, 10+i)
+		}
+	}
+
+	for _, id := range []uint{draft.ID, post.ID + 100} {
+		if _, err := service.ViewPost(id); !errors.Is(err, ErrPostNotFound) {
+			t.Errorf("ViewPost(%d) = %v, want ErrPostNotFound", id, err)
+		}
+	}
+	if views.Pending(draft.ID) != 0 {
+		t.Error("未发布的文章记录了浏览")
+	}
+
+	if err := views.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var stored models.Post
+	if err := db.First(&stored, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Views != 13 {
+		t.Errorf("写入后浏览量 = %d, want 13", stored.Views)
+	}
+}
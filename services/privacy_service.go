@@ -0,0 +1,197 @@
+package services
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"exercise/repositories"
+	"exercise/storage"
+)
+
+// exportFormatVersion 个人数据导出包的格式版本
+const exportFormatVersion = 1
+
+// 导出包中的文件
+const (
+	exportDataFile     = "user.json"
+	exportManifestFile = "manifest.json"
+	exportAttachDir    = "attachments"
+)
+
+// PrivacyPolicy 个人数据处理策略
+type PrivacyPolicy struct {
+	KeepContent bool // 擦除时未指定 ErasureOptions.KeepContent 的默认值
+}
+
+// ExportManifest 导出包清单
+type ExportManifest struct {
+	FormatVersion int                `json:"format_version"`
+	ExportedAt    time.Time          `json:"exported_at"`
+	UserID        uint               `json:"user_id"`
+	Files         []ExportFile       `json:"files"`       // 包内文件及校验和（不含清单本身）
+	Attachments   []ExportAttachment `json:"attachments"` // 与用户相关的附件
+	Counts        map[string]int     `json:"counts"`      // 各类数据条数
+	Notes         []string           `json:"notes,omitempty"`
+}
+
+// ExportFile 导出包内的文件
+type ExportFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ExportAttachment 与用户相关的附件
+type ExportAttachment struct {
+	Kind     string `json:"kind"`           // 如 avatar
+	Source   string `json:"source"`         // 原始地址
+	Path     string `json:"path,omitempty"` // 已打包时在包内的路径
+	Included bool   `json:"included"`       // 是否已打包
+}
+
+// PrivacyService 个人数据服务接口
+type PrivacyService interface {
+	ExportUserData(id uint, w io.Writer) (*ExportManifest, error)
+	EraseUser(id uint, opts repositories.ErasureOptions) (*repositories.ErasureResult, error)
+}
+
+// privacyServiceImpl 个人数据服务实现
+type privacyServiceImpl struct {
+	store       storage.BlobStore
+	policy      PrivacyPolicy
+	privacyRepo repositories.PrivacyRepository
+}
+
+// NewPrivacyService 创建个人数据服务，store 用于打包头像，为nil时只在清单中记录头像地址
+func NewPrivacyService(store storage.BlobStore, policy PrivacyPolicy) PrivacyService {
+	return &privacyServiceImpl{
+		store:       store,
+		policy:      policy,
+		privacyRepo: repositories.NewPrivacyRepository(),
+	}
+}
+
+// ExportUserData 将用户的个人数据导出为zip包（user.json、manifest.json 以及存储中的头像）
+func (s *privacyServiceImpl) ExportUserData(id uint, w io.Writer) (*ExportManifest, error) {
+	data, err := s.privacyRepo.LoadUserData(id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("加载用户数据失败: %v", err)
+	}
+
+	manifest := &ExportManifest{
+		FormatVersion: exportFormatVersion,
+		ExportedAt:    time.Now(),
+		UserID:        id,
+		Counts: map[string]int{
+			"posts":          len(data.Posts),
+			"comments":       len(data.Comments),
+			"comment_likes":  len(data.CommentLikes),
+			"courses":        len(data.Courses),
+			"taught_courses": len(data.TaughtCourses),
+		},
+		Notes: []string{"密码不在导出范围内"},
+	}
+
+	body, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化用户数据失败: %v", err)
+	}
+
+	zw := zip.NewWriter(w)
+	file, err := writeZipFile(zw, exportDataFile, body, manifest.ExportedAt)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Files = append(manifest.Files, file)
+
+	if data.Profile != nil && data.Profile.AvatarURL != "" {
+		if err := s.exportAvatar(zw, manifest, data.Profile.AvatarURL); err != nil {
+			return nil, err
+		}
+	}
+
+	manifestBody, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化导出清单失败: %v", err)
+	}
+	if _, err := writeZipFile(zw, exportManifestFile, manifestBody, manifest.ExportedAt); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("写入导出包失败: %v", err)
+	}
+	return manifest, nil
+}
+
+// exportAvatar 将存储中的头像写入导出包；头像不在存储中（如外部地址）时只在清单中记录地址
+func (s *privacyServiceImpl) exportAvatar(zw *zip.Writer, manifest *ExportManifest, avatarURL string) error {
+	attachment := ExportAttachment{Kind: "avatar", Source: avatarURL}
+	defer func() { manifest.Attachments = append(manifest.Attachments, attachment) }()
+
+	if s.store == nil {
+		manifest.Notes = append(manifest.Notes, "未配置文件存储，头像未打包")
+		return nil
+	}
+	key, ok := s.store.KeyFromURL(avatarURL)
+	if !ok {
+		manifest.Notes = append(manifest.Notes, "头像不在本站存储中，未打包")
+		return nil
+	}
+	data, err := s.store.Get(key)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		manifest.Notes = append(manifest.Notes, "头像文件已不存在，未打包")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取头像失败: %v", err)
+	}
+
+	name := exportAttachDir + "/avatar" + path.Ext(key)
+	file, err := writeZipFile(zw, name, data, manifest.ExportedAt)
+	if err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, file)
+	attachment.Path, attachment.Included = name, true
+	return nil
+}
+
+// EraseUser 擦除用户的个人数据并记录审计日志，未指定是否保留内容时使用策略中的默认值
+func (s *privacyServiceImpl) EraseUser(id uint, opts repositories.ErasureOptions) (*repositories.ErasureResult, error) {
+	if opts.KeepContent == nil {
+		keep := s.policy.KeepContent
+		opts.KeepContent = &keep
+	}
+	result, err := s.privacyRepo.EraseUser(id, opts)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("擦除用户数据失败: %v", err)
+	}
+	return result, nil
+}
+
+// writeZipFile 向zip包写入一个文件并返回其校验信息
+func writeZipFile(zw *zip.Writer, name string, body []byte, modified time.Time) (ExportFile, error) {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return ExportFile{}, fmt.Errorf("写入 %s 失败: %v", name, err)
+	}
+	if _, err := fw.Write(body); err != nil {
+		return ExportFile{}, fmt.Errorf("写入 %s 失败: %v", name, err)
+	}
+	sum := sha256.Sum256(body)
+	return ExportFile{Name: name, Size: int64(len(body)), SHA256: hex.EncodeToString(sum[:])}, nil
+}
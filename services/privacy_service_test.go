@@ -0,0 +1,180 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+	"exercise/repositories"
+	"exercise/storage"
+
+	"gorm.io/gorm"
+)
+
+// createPostBy 以 author 的名义创建文章
+func createPostBy(t *testing.T, db *gorm.DB, author *models.User, title, content string) *models.Post {
+	t.Helper()
+	post := &models.Post{Title: title, Content: content, Slug: author.Username + "-" + title, AuthorID: author.ID}
+	if err := db.Omit("Author", "Comments").Create(post).Error; err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func TestEraseSoftDeletedUser(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "ghost")
+	if err := db.Delete(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewPrivacyService(nil, PrivacyPolicy{KeepContent: true}).EraseUser(user.ID, repositories.ErasureOptions{}); err != nil {
+		t.Fatalf("擦除已软删除的用户失败: %v", err)
+	}
+	var erased models.User
+	if err := db.Unscoped().First(&erased, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if erased.Username != "deleted_"+itoa(user.ID) || erased.Email == user.Email {
+		t.Errorf("账户未匿名化: %s %s", erased.Username, erased.Email)
+	}
+}
+
+func TestEraseKeepContentDefaultsToPolicy(t *testing.T) {
+	keep, drop := true, false
+	tests := []struct {
+		name        string
+		policy      bool
+		opt         *bool
+		wantContent string
+	}{
+		{"策略保留内容", true, nil, "[已删除] 的学习笔记"},
+		{"策略不保留内容", false, nil, repositories.ErasedPlaceholder},
+		{"显式保留覆盖策略", false, &keep, "[已删除] 的学习笔记"},
+		{"显式不保留覆盖策略", true, &drop, repositories.ErasedPlaceholder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t)
+			user := createUser(t, "alice")
+			post := createPostBy(t, db, user, "笔记", "alice 的学习笔记")
+
+			svc := NewPrivacyService(nil, PrivacyPolicy{KeepContent: tt.policy})
+			if _, err := svc.EraseUser(user.ID, repositories.ErasureOptions{KeepContent: tt.opt}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.First(post, post.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if post.Content != tt.wantContent {
+				t.Errorf("文章内容 = %q, want %q", post.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestEraseRedactsAuditLogs(t *testing.T) {
+	for _, keep := range []bool{true, false} {
+		db := testdb.Open(t)
+		user := createUser(t, "alice")
+		post := createPostBy(t, db, user, "alice 的第一篇", "作者 alice@example.com")
+		if err := db.Model(post).Update("content", "作者 alice").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		svc := NewPrivacyService(nil, PrivacyPolicy{})
+		if _, err := svc.EraseUser(user.ID, repositories.ErasureOptions{KeepContent: &keep}); err != nil {
+			t.Fatal(err)
+		}
+
+		var logs []models.AuditLog
+		if err := db.Where("entity_type = ? AND entity_id = ?", "posts", post.ID).Find(&logs).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) < 2 {
+			t.Fatalf("keep=%v: 文章审计日志 %d 条, want >= 2", keep, len(logs))
+		}
+		for _, entry := range logs {
+			switch {
+			case !keep && entry.Changes != nil:
+				t.Errorf("keep=false: 审计日志 %d 未清空: %s", entry.ID, entry.Changes)
+			case keep && (entry.Changes == nil || !json.Valid(entry.Changes)):
+				t.Errorf("keep=true: 审计日志 %d 应保留为合法JSON: %s", entry.ID, entry.Changes)
+			case strings.Contains(strings.ToLower(string(entry.Changes)), "alice"):
+				t.Errorf("keep=%v: 审计日志 %d 仍含个人信息: %s", keep, entry.ID, entry.Changes)
+			}
+		}
+	}
+}
+
+func TestExportIncludesAvatar(t *testing.T) {
+	db := testdb.Open(t)
+	store, err := storage.NewLocalStore(t.TempDir(), "http://cdn.example.com/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := createUser(t, "alice")
+	key := "avatars/" + itoa(user.ID) + "/abc123/256.jpg"
+	avatar := []byte("fake jpeg bytes")
+	if err := store.Put(key, avatar, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	profile := models.Profile{UserID: user.ID, FirstName: "A", LastName: "Lice", AvatarURL: store.URL(key)}
+	if err := db.Create(&profile).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := NewPrivacyService(store, PrivacyPolicy{}).ExportUserData(user.ID, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Attachments) != 1 || !manifest.Attachments[0].Included || manifest.Attachments[0].Path != "attachments/avatar.jpg" {
+		t.Fatalf("清单附件 = %+v", manifest.Attachments)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	if !bytes.Equal(files["attachments/avatar.jpg"], avatar) {
+		t.Errorf("导出包中的头像 = %q, want %q", files["attachments/avatar.jpg"], avatar)
+	}
+	for _, name := range []string{"user.json", "manifest.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("导出包缺少 %s", name)
+		}
+	}
+
+	// 外部头像地址只记录在清单中
+	if err := db.Model(&profile).Update("avatar_url", "https://gravatar.example.com/a.png").Error; err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	manifest, err = NewPrivacyService(store, PrivacyPolicy{}).ExportUserData(user.ID, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Attachments) != 1 || manifest.Attachments[0].Included {
+		t.Errorf("外部头像不应打包: %+v", manifest.Attachments)
+	}
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"exercise/repositories"
+)
+
+// RetentionPolicy 数据保留策略，各类数据的保留天数分别检查
+type RetentionPolicy struct {
+	Days         int           // 软删除超过该天数后彻底删除，0 表示不清理
+	EventDays    int           // 已投递的领域事件保留天数，0 表示不清理
+	DeliveryDays int           // 成功的 Webhook 投递记录保留天数，0 表示不清理
+	Interval     time.Duration // 清理任务执行间隔
+	BatchSize    int           // 每批删除的行数
+}
+
+// Enabled 是否有需要清理的数据
+func (p RetentionPolicy) Enabled() bool {
+	return p.Days > 0 || p.EventDays > 0 || p.DeliveryDays > 0
+}
+
+// RetentionReport 一次清理的结果，未清理的数据截止时间为零值
+type RetentionReport struct {
+	Cutoff           time.Time `json:"cutoff"` // 软删除的用户和评论
+	Users            int64     `json:"users"`
+	Comments         int64     `json:"comments"`
+	EventsCutoff     time.Time `json:"events_cutoff"`
+	Events           int64     `json:"events"` // 已投递的领域事件
+	DeliveriesCutoff time.Time `json:"deliveries_cutoff"`
+	Deliveries       int64     `json:"deliveries"` // 成功的 Webhook 投递记录
+}
+
+// RetentionService 数据清理服务接口
+type RetentionService interface {
+	PurgeExpired(policy RetentionPolicy) (*RetentionReport, error)
+	Start(ctx context.Context, policy RetentionPolicy)
+}
+
+// retentionServiceImpl 数据清理服务实现
+type retentionServiceImpl struct {
+	userRepo    repositories.UserRepository
+	commentRepo repositories.CommentRepository
+	outboxRepo  repositories.OutboxRepository
+	webhookRepo repositories.WebhookRepository
+}
+
+// NewRetentionService 创建数据清理服务
+func NewRetentionService() RetentionService {
+	return &retentionServiceImpl{
+		userRepo:    repositories.NewUserRepository(),
+		commentRepo: repositories.NewCommentRepository(),
+		outboxRepo:  repositories.NewOutboxRepository(),
+		webhookRepo: repositories.NewWebhookRepository(),
+	}
+}
+
+// PurgeExpired 彻底删除软删除时间早于保留期的用户和评论，以及投递时间早于各自保留期的领域事件和 Webhook 投递记录
+func (s *retentionServiceImpl) PurgeExpired(policy RetentionPolicy) (*RetentionReport, error) {
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 500
+	}
+
+	now := time.Now()
+	report := &RetentionReport{}
+
+	if policy.Days > 0 {
+		report.Cutoff = now.AddDate(0, 0, -policy.Days)
+
+		// 先清理评论，清理用户时其评论会一并删除
+		comments, err := s.commentRepo.PurgeDeletedBefore(report.Cutoff, policy.BatchSize)
+		report.Comments = comments
+		if err != nil {
+			return report, fmt.Errorf("清理过期评论失败: %v", err)
+		}
+
+		users, err := s.userRepo.PurgeDeletedBefore(report.Cutoff, policy.BatchSize)
+		report.Users = users
+		if err != nil {
+			return report, fmt.Errorf("清理过期用户失败: %v", err)
+		}
+	}
+
+	if policy.EventDays > 0 {
+		report.EventsCutoff = now.AddDate(0, 0, -policy.EventDays)
+		events, err := s.outboxRepo.PurgeDeliveredBefore(report.EventsCutoff, policy.BatchSize)
+		report.Events = events
+		if err != nil {
+			return report, fmt.Errorf("清理已投递事件失败: %v", err)
+		}
+	}
+
+	if policy.DeliveryDays > 0 {
+		report.DeliveriesCutoff = now.AddDate(0, 0, -policy.DeliveryDays)
+		deliveries, err := s.webhookRepo.PurgeSucceededBefore(report.DeliveriesCutoff, policy.BatchSize)
+		report.Deliveries = deliveries
+		if err != nil {
+			return report, fmt.Errorf("清理 Webhook 投递记录失败: %v", err)
+		}
+	}
+
+	return report, nil
+}
+
+// Start 按间隔定期执行清理，ctx 取消后停止
+func (s *retentionServiceImpl) Start(ctx context.Context, policy RetentionPolicy) {
+	if !policy.Enabled() || policy.Interval <= 0 {
+		log.Println("ℹ️ 未启用数据清理")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			report, err := s.PurgeExpired(policy)
+			if err != nil {
+				log.Printf("❌ 数据清理失败: %v", err)
+			} else if report.Users > 0 || report.Comments > 0 || report.Events > 0 || report.Deliveries > 0 {
+				log.Printf("🧹 已彻底删除 %d 个用户、%d 条评论、%d 个已投递事件、%d 条 Webhook 投递记录",
+					report.Users, report.Comments, report.Events, report.Deliveries)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
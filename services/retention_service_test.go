@@ -0,0 +1,128 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+	"exercise/repositories"
+)
+
+func TestPurgeExpiredUsesRetentionDays(t *testing.T) {
+	db := testdb.Open(t)
+	expired := createUser(t, "expired")
+	recent := createUser(t, "recent")
+	active := createUser(t, "active")
+
+	// 过期用户点赞过的评论，彻底删除用户后点赞数随之扣除
+	post := models.Post{Title: "文章", Content: "正文", Slug: "retention", AuthorID: active.ID}
+	if err := db.Omit("Author", "Comments").Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	comment := models.Comment{Content: "评论", PostID: post.ID, UserID: active.ID}
+	if err := db.Omit("Post", "User").Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repositories.NewCommentRepository().Like(comment.ID, expired.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []*models.User{expired, recent} {
+		if err := db.Delete(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := db.Unscoped().Model(&models.User{}).Where("id = ?", expired.ID).
+		UpdateColumn("deleted_at", time.Now().AddDate(0, 0, -40)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewRetentionService()
+	report, err := svc.PurgeExpired(RetentionPolicy{Days: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Users != 0 {
+		t.Errorf("保留天数为 0 时删除了 %d 个用户", report.Users)
+	}
+
+	report, err = svc.PurgeExpired(RetentionPolicy{Days: 30, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Users != 1 {
+		t.Errorf("彻底删除 %d 个用户, want 1", report.Users)
+	}
+
+	var remaining []uint
+	if err := db.Unscoped().Model(&models.User{}).Order("id").Pluck("id", &remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint{recent.ID, active.ID}; len(remaining) != 2 || remaining[0] != want[0] || remaining[1] != want[1] {
+		t.Errorf("剩余用户 = %v, want %v", remaining, want)
+	}
+	if err := db.First(&comment, comment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if comment.Likes != 0 {
+		t.Errorf("评论点赞数 = %d, want 0", comment.Likes)
+	}
+}
+
+func TestPurgeExpiredChecksEachRetentionSeparately(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "deleted")
+	if err := db.Delete(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().AddDate(0, 0, -40)
+	err := db.Unscoped().Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("deleted_at", old).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 注册时记录的事件已于 40 天前投递
+	err = db.Model(&models.OutboxEvent{}).Where("1 = 1").
+		UpdateColumns(map[string]interface{}{"status": models.OutboxDelivered, "delivered_at": old}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := models.Webhook{URL: "https://example.com/hook", EventTypes: []string{models.EventUserRegistered}, Secret: "s"}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatal(err)
+	}
+	delivery := models.WebhookDelivery{
+		WebhookID: hook.ID, EventID: 1, EventType: models.EventUserRegistered, Payload: []byte(`{}`),
+		Status: models.DeliverySucceeded, DeliveredAt: &old, NextAttemptAt: old,
+	}
+	if err := db.Omit("Webhook").Create(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewRetentionService()
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   RetentionReport
+	}{
+		// 永久保留软删除的用户时，事件和投递记录仍按各自的保留期清理
+		{"只清理事件", RetentionPolicy{EventDays: 30}, RetentionReport{Events: 1}},
+		{"只清理投递记录", RetentionPolicy{DeliveryDays: 30}, RetentionReport{Deliveries: 1}},
+		{"未到保留期", RetentionPolicy{Days: 60}, RetentionReport{}},
+		{"清理软删除的用户", RetentionPolicy{Days: 30}, RetentionReport{Users: 1}},
+	}
+	for _, tt := range tests {
+		report, err := svc.PurgeExpired(tt.policy)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := RetentionReport{Users: report.Users, Comments: report.Comments, Events: report.Events, Deliveries: report.Deliveries}
+		if got != tt.want {
+			t.Errorf("%s: 删除 %+v, want %+v", tt.name, got, tt.want)
+		}
+		if (tt.policy.EventDays > 0) == report.EventsCutoff.IsZero() {
+			t.Errorf("%s: 事件截止时间 = %v", tt.name, report.EventsCutoff)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"exercise/repositories"
+)
+
+// ErrConflict 数据在读取后已被其他操作修改，重新读取后再试
+var ErrConflict = repositories.ErrConflict
+
+// 版本冲突时的重试策略
+const (
+	maxConflictRetries = 3
+	conflictBackoff    = 10 * time.Millisecond
+)
+
+// retryOnConflict 执行 fn，遇到 ErrConflict 时等待片刻后重试，最多重试 maxConflictRetries 次
+// fn 每次都必须重新读取数据再写入，只适用于可以安全重放的修改（如按当前状态停用账户）
+func retryOnConflict(fn func() error) error {
+	err := fn()
+	for attempt := 1; attempt <= maxConflictRetries && errors.Is(err, ErrConflict); attempt++ {
+		// 随机退避，避免并发的重试再次同时提交
+		time.Sleep(time.Duration(attempt)*conflictBackoff + time.Duration(rand.Int63n(int64(conflictBackoff))))
+		err = fn()
+	}
+	return err
+}
+
+// exponentialBackoff 第 attempts 次失败后的等待时间：从 base 开始每次加倍，不超过 max
+func exponentialBackoff(base, max time.Duration, attempts int) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	return min(wait, max)
+}
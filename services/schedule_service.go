@@ -0,0 +1,329 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"exercise/database"
+	"exercise/models"
+	"exercise/repositories"
+)
+
+// ErrCourseNotFound 课程不存在
+var ErrCourseNotFound = errors.New("课程不存在")
+
+// ErrAlreadyEnrolled 用户已选修该课程
+var ErrAlreadyEnrolled = errors.New("已选修该课程")
+
+// ErrScheduleConflict 课程与已选课程时间冲突
+var ErrScheduleConflict = errors.New("课程与已选课程时间冲突")
+
+// openEndedHorizon 未设置结束日期的课程展开的时长
+const openEndedHorizon = 365 * 24 * time.Hour
+
+// ScheduleEntry 课表中的一次课
+type ScheduleEntry struct {
+	CourseID   uint   `json:"course_id"`
+	CourseName string `json:"course_name"`
+	models.Occurrence
+}
+
+// ScheduleConflict 两次时间重叠的课
+type ScheduleConflict struct {
+	First  ScheduleEntry `json:"first"`
+	Second ScheduleEntry `json:"second"`
+}
+
+// ScheduleService 课程安排服务接口
+type ScheduleService interface {
+	Timetable(userID uint, from, to time.Time) ([]ScheduleEntry, error)
+	DetectConflicts(userID uint) ([]ScheduleConflict, error)
+	CheckEnrollment(userID, courseID uint) ([]ScheduleConflict, error)
+	Enroll(userID, courseID uint) ([]ScheduleConflict, error)
+	ExportICS(userID uint, w io.Writer) error
+}
+
+// scheduleServiceImpl 课程安排服务实现
+type scheduleServiceImpl struct {
+	txm        database.TxManager
+	courseRepo repositories.CourseRepository
+}
+
+// NewScheduleService 创建课程安排服务
+func NewScheduleService() ScheduleService {
+	return &scheduleServiceImpl{
+		txm:        database.NewTxManager(),
+		courseRepo: repositories.NewCourseRepository(),
+	}
+}
+
+// Timetable 获取用户在 [from, to) 区间内的课表
+func (s *scheduleServiceImpl) Timetable(userID uint, from, to time.Time) ([]ScheduleEntry, error) {
+	courses, err := s.courseRepo.FindEnrolledByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取已选课程失败: %v", err)
+	}
+
+	var entries []ScheduleEntry
+	for i := range courses {
+		courseEntries, err := expandCourse(&courses[i], from, to)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, courseEntries...)
+	}
+	sortEntries(entries)
+
+	return entries, nil
+}
+
+// DetectConflicts 检测用户已选课程之间的时间冲突
+func (s *scheduleServiceImpl) DetectConflicts(userID uint) ([]ScheduleConflict, error) {
+	courses, err := s.courseRepo.FindEnrolledByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取已选课程失败: %v", err)
+	}
+	return conflictsOf(courses)
+}
+
+// CheckEnrollment 检测用户选修指定课程后会产生的冲突
+func (s *scheduleServiceImpl) CheckEnrollment(userID, courseID uint) ([]ScheduleConflict, error) {
+	courses, err := s.courseRepo.FindEnrolledByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取已选课程失败: %v", err)
+	}
+	return enrollmentConflicts(s.courseRepo, courseID, courses)
+}
+
+// Enroll 选修课程（产生 Enrolled 事件），与已选课程时间冲突时不选修，返回冲突和 ErrScheduleConflict
+// 冲突检查与选课在同一事务中进行，并锁定用户已选的课程，并发选课不会同时通过检查
+func (s *scheduleServiceImpl) Enroll(userID, courseID uint) ([]ScheduleConflict, error) {
+	var conflicts []ScheduleConflict
+	err := s.txm.WithinTx(context.Background(), func(ctx context.Context) error {
+		courseRepo := s.courseRepo.WithContext(ctx)
+		enrolled, err := courseRepo.LockEnrollments(userID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("获取已选课程失败: %v", err)
+		}
+
+		conflicts, err = enrollmentConflicts(courseRepo, courseID, enrolled)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return ErrScheduleConflict
+		}
+
+		if _, err := courseRepo.Enroll(userID, courseID); err != nil {
+			var dbErr *repositories.DBError
+			switch {
+			case errors.Is(err, repositories.ErrDuplicate):
+				return ErrAlreadyEnrolled
+			case errors.As(err, &dbErr) && dbErr.Field == "course_id":
+				return ErrCourseNotFound
+			case errors.Is(err, repositories.ErrInvalidReference):
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("选课失败: %v", err)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrScheduleConflict) {
+		return conflicts, err
+	}
+	return nil, err
+}
+
+// enrollmentConflicts 检测在已选课程 enrolled 之外再选修课程后会产生的冲突（只返回涉及新课程的冲突）
+func enrollmentConflicts(courseRepo repositories.CourseRepository, courseID uint, enrolled []models.Course) ([]ScheduleConflict, error) {
+	course, err := courseRepo.FindByID(courseID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrCourseNotFound
+		}
+		return nil, fmt.Errorf("获取课程失败: %v", err)
+	}
+
+	all := []models.Course{*course}
+	for _, c := range enrolled {
+		if c.ID != course.ID {
+			all = append(all, c)
+		}
+	}
+
+	conflicts, err := conflictsOf(all)
+	if err != nil {
+		return nil, err
+	}
+
+	result := conflicts[:0]
+	for _, c := range conflicts {
+		if c.First.CourseID == course.ID || c.Second.CourseID == course.ID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// ExportICS 以iCalendar格式导出用户课表
+func (s *scheduleServiceImpl) ExportICS(userID uint, w io.Writer) error {
+	courses, err := s.courseRepo.FindEnrolledByUser(userID)
+	if err != nil {
+		return fmt.Errorf("获取已选课程失败: %v", err)
+	}
+
+	var entries []ScheduleEntry
+	for i := range courses {
+		courseEntries, err := expandCourse(&courses[i], time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		entries = append(entries, courseEntries...)
+	}
+	sortEntries(entries)
+
+	return writeICS(w, entries, time.Now())
+}
+
+// expandCourse 展开课程在 [from, to) 区间内的课次，区间为零值时使用 courseWindow
+func expandCourse(course *models.Course, from, to time.Time) ([]ScheduleEntry, error) {
+	if from.IsZero() || to.IsZero() {
+		first, last := courseWindow(course)
+		if from.IsZero() {
+			from = first
+		}
+		if to.IsZero() {
+			to = last
+		}
+	}
+
+	occurrences, err := course.Occurrences(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("展开课程 %s 的安排失败: %v", course.Name, err)
+	}
+
+	entries := make([]ScheduleEntry, 0, len(occurrences))
+	for _, occ := range occurrences {
+		entries = append(entries, ScheduleEntry{CourseID: course.ID, CourseName: course.Name, Occurrence: occ})
+	}
+	return entries, nil
+}
+
+// courseWindow 返回课程全部课次所在的区间：开始日期至结束日次日，未设置结束日期时展开 openEndedHorizon；
+// 未设置开始日期的课程只有单次课次（见 Schedule.Validate），使用单次课次的时间范围
+func courseWindow(course *models.Course) (time.Time, time.Time) {
+	from, to := course.StartDate, course.EndDate
+	if from.IsZero() {
+		first, last := course.Schedule.Data().SessionSpan()
+		if to.IsZero() {
+			return first, last
+		}
+		from = first
+	}
+	if to.IsZero() {
+		to = from.Add(openEndedHorizon)
+	}
+	return from, to.AddDate(0, 0, 1) // 包含结束日当天
+}
+
+// conflictsOf 检测多门课程之间的时间冲突
+func conflictsOf(courses []models.Course) ([]ScheduleConflict, error) {
+	var entries []ScheduleEntry
+	for i := range courses {
+		courseEntries, err := expandCourse(&courses[i], time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, courseEntries...)
+	}
+	sortEntries(entries)
+
+	// 按开始时间扫描，只与仍未结束的课比较
+	var conflicts []ScheduleConflict
+	var active []ScheduleEntry
+	for _, entry := range entries {
+		kept := active[:0]
+		for _, a := range active {
+			if a.End.After(entry.Start) {
+				kept = append(kept, a)
+				if a.CourseID != entry.CourseID {
+					conflicts = append(conflicts, ScheduleConflict{First: a, Second: entry})
+				}
+			}
+		}
+		active = append(kept, entry)
+	}
+
+	return conflicts, nil
+}
+
+// sortEntries 按开始时间排序
+func sortEntries(entries []ScheduleEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start.Before(entries[j].Start)
+	})
+}
+
+// icsEscaper 转义iCalendar文本值
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsTimeLayout iCalendar的UTC时间格式
+const icsTimeLayout = "20060102T150405Z"
+
+// writeICS 写出iCalendar（每次课一个VEVENT，时间统一为UTC）
+func writeICS(w io.Writer, entries []ScheduleEntry, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		// 按RFC 5545每行不超过75字节，续行以空格开头
+		for len(s) > 75 {
+			cut := 75
+			for cut > 0 && !isRuneStart(s[cut]) {
+				cut--
+			}
+			bw.WriteString(s[:cut] + "\r\n")
+			s = " " + s[cut:]
+		}
+		bw.WriteString(s + "\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//exercise//timetable//CN")
+	line("CALSCALE:GREGORIAN")
+	for _, e := range entries {
+		summary := e.CourseName
+		if e.Title != "" {
+			summary += " - " + e.Title
+		}
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:course-%d-%d@exercise", e.CourseID, e.Start.Unix()))
+		line("DTSTAMP:" + now.UTC().Format(icsTimeLayout))
+		line("DTSTART:" + e.Start.UTC().Format(icsTimeLayout))
+		line("DTEND:" + e.End.UTC().Format(icsTimeLayout))
+		line("SUMMARY:" + icsEscaper.Replace(summary))
+		if e.Room != "" {
+			line("LOCATION:" + icsEscaper.Replace(e.Room))
+		}
+		if e.Link != "" {
+			line("URL:" + e.Link)
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// isRuneStart 判断字节是否为UTF-8字符的首字节（折行时不拆开多字节字符）
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+	"exercise/repositories"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// createCourse 创建每周 weekday 的 start-end 上课、为期四周的课程
+func createCourse(t *testing.T, db *gorm.DB, code string, weekday time.Weekday, start, end string) *models.Course {
+	t.Helper()
+	first := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC) // 周一
+	course := &models.Course{
+		Name: code, Title: code, Code: code, IsActive: true,
+		StartDate: first, EndDate: first.AddDate(0, 0, 27),
+		Schedule: datatypes.NewJSONType(models.Schedule{
+			TimeZone: "UTC",
+			Weekly:   []models.WeeklySession{{Weekday: weekday, Start: start, End: end}},
+		}),
+	}
+	if err := db.Omit("Users", "Teachers").Create(course).Error; err != nil {
+		t.Fatalf("创建课程 %s 失败: %v", code, err)
+	}
+	return course
+}
+
+func TestTimetableIgnoresDroppedAndCompleted(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "student")
+	courses := map[string]*models.Course{
+		models.EnrollmentEnrolled:  createCourse(t, db, "A", time.Monday, "09:00", "10:00"),
+		models.EnrollmentCompleted: createCourse(t, db, "B", time.Monday, "09:30", "10:30"),
+		models.EnrollmentDropped:   createCourse(t, db, "C", time.Tuesday, "09:00", "10:00"),
+	}
+	for status, c := range courses {
+		if err := db.Create(&models.UserCourse{UserID: user.ID, CourseID: c.ID, Status: status}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := NewScheduleService()
+	entries, err := svc.Timetable(user.ID, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("课表有 %d 次课，want 4（只含正在选修的课程）", len(entries))
+	}
+	for _, e := range entries {
+		if e.CourseName != "A" {
+			t.Errorf("课表中出现了 %s", e.CourseName)
+		}
+	}
+
+	// 已结课的 B 与 A 时间重叠，但不算冲突
+	conflicts, err := svc.DetectConflicts(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("DetectConflicts() = %d 个冲突，want 0", len(conflicts))
+	}
+}
+
+func TestEnroll(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "student")
+	a := createCourse(t, db, "A", time.Monday, "09:00", "10:00")
+	b := createCourse(t, db, "B", time.Monday, "09:30", "10:30")
+	c := createCourse(t, db, "C", time.Tuesday, "09:00", "10:00")
+	svc := NewScheduleService()
+
+	if _, err := svc.Enroll(user.ID, a.ID); err != nil {
+		t.Fatalf("选修 A 失败: %v", err)
+	}
+	conflicts, err := svc.Enroll(user.ID, b.ID)
+	if !errors.Is(err, ErrScheduleConflict) || len(conflicts) != 4 {
+		t.Fatalf("选修 B = %d 个冲突, %v; want 4, ErrScheduleConflict", len(conflicts), err)
+	}
+	if _, err := svc.Enroll(user.ID, c.ID); err != nil {
+		t.Fatalf("选修 C 失败: %v", err)
+	}
+	if _, err := svc.Enroll(user.ID, 9999); !errors.Is(err, ErrCourseNotFound) {
+		t.Errorf("选修不存在的课程: %v, want ErrCourseNotFound", err)
+	}
+	if _, err := svc.Enroll(9999, c.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("不存在的用户选课: %v, want ErrUserNotFound", err)
+	}
+
+	var n int64
+	db.Model(&models.UserCourse{}).Where("user_id = ?", user.ID).Count(&n)
+	if n != 2 {
+		t.Errorf("选课记录 %d 条，want 2", n)
+	}
+}
+
+func TestEnrollConcurrentConflicts(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "student")
+	courses := []*models.Course{
+		createCourse(t, db, "A", time.Monday, "09:00", "10:00"),
+		createCourse(t, db, "B", time.Monday, "09:30", "10:30"),
+		createCourse(t, db, "C", time.Monday, "09:45", "11:00"),
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(courses))
+	for i, c := range courses {
+		wg.Add(1)
+		go func(i int, courseID uint) {
+			defer wg.Done()
+			_, errs[i] = NewScheduleService().Enroll(user.ID, courseID)
+		}(i, c.ID)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrScheduleConflict):
+			t.Errorf("选课失败: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d 门互相冲突的课程选修成功，want 1", succeeded)
+	}
+}
+
+// courseNotFoundRepo 选课时外键检查失败的课程仓储（模拟选课过程中课程被删除）
+type courseNotFoundRepo struct {
+	repositories.CourseRepository
+	field string
+}
+
+func (r courseNotFoundRepo) WithContext(ctx context.Context) repositories.CourseRepository {
+	return courseNotFoundRepo{CourseRepository: r.CourseRepository.WithContext(ctx), field: r.field}
+}
+
+func (r courseNotFoundRepo) Enroll(userID, courseID uint) (*models.UserCourse, error) {
+	return nil, &repositories.DBError{Kind: repositories.ErrInvalidReference, Table: "user_courses", Field: r.field}
+}
+
+func TestEnrollMapsForeignKeyField(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "student")
+	course := createCourse(t, db, "A", time.Monday, "09:00", "10:00")
+
+	for field, want := range map[string]error{"course_id": ErrCourseNotFound, "user_id": ErrUserNotFound} {
+		svc := NewScheduleService().(*scheduleServiceImpl)
+		svc.courseRepo = courseNotFoundRepo{CourseRepository: svc.courseRepo, field: field}
+		if _, err := svc.Enroll(user.ID, course.ID); !errors.Is(err, want) {
+			t.Errorf("%s 外键检查失败: %v, want %v", field, err, want)
+		}
+	}
+}
+
+func TestExportICSWithoutStartDate(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "student")
+	session := time.Date(2026, 5, 20, 14, 0, 0, 0, time.UTC)
+	course := &models.Course{
+		Name: "讲座", Title: "讲座", Code: "TALK", IsActive: true,
+		Schedule: datatypes.NewJSONType(models.Schedule{
+			Sessions: []models.Session{
+				{Title: "上篇", Start: session, End: session.Add(time.Hour)},
+				{Title: "下篇", Start: session.AddDate(0, 0, 7), End: session.AddDate(0, 0, 7).Add(time.Hour)},
+			},
+		}),
+	}
+	if err := db.Omit("Users", "Teachers").Create(course).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewScheduleService().Enroll(user.ID, course.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := NewScheduleService().ExportICS(user.ID, &b); err != nil {
+		t.Fatal(err)
+	}
+	ics := b.String()
+	for _, want := range []string{"DTSTART:20260520T140000Z", "DTSTART:20260527T140000Z", "SUMMARY:讲座 - 下篇"} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("ICS 中缺少 %q:\n%s", want, ics)
+		}
+	}
+}
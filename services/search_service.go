@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"exercise/models"
+	"exercise/repositories"
+)
+
+var (
+	ErrEmptyKeyword  = errors.New("搜索关键字不能为空")
+	ErrInvalidStatus = errors.New("文章状态无效")
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	snippetRadius   = 60 // 摘要中命中词前后保留的字符数
+)
+
+// 高亮标记
+const (
+	HighlightPre  = "<mark>"
+	HighlightPost = "</mark>"
+)
+
+// PostSearchRequest 文章搜索请求
+type PostSearchRequest struct {
+	Keyword       string
+	Status        string // 为空时只搜索已发布的文章
+	IncludeDrafts bool   // Status 为空时不限状态（包括草稿）
+	AuthorID      uint
+	Tags          []string
+	From          *time.Time
+	To            *time.Time
+	Page          int
+	PageSize      int
+}
+
+// PostHit 文章搜索命中
+type PostHit struct {
+	Post    models.Post `json:"post"`
+	Score   float64     `json:"score"`
+	Title   string      `json:"title"`   // 高亮后的标题（已做HTML转义）
+	Snippet string      `json:"snippet"` // 高亮后的正文摘要（已做HTML转义）
+}
+
+// UserHit 用户搜索命中
+type UserHit struct {
+	User    models.User `json:"user"`
+	Score   float64     `json:"score"`
+	Snippet string      `json:"snippet"` // 高亮后的简介摘要（已做HTML转义）
+}
+
+// SearchService 搜索服务接口
+type SearchService interface {
+	SearchPosts(req PostSearchRequest) ([]PostHit, int64, error)
+	SearchUsers(keyword string, active repositories.ActiveFilter, page, pageSize int) ([]UserHit, int64, error)
+}
+
+// searchServiceImpl 搜索服务实现
+type searchServiceImpl struct {
+	searchRepo repositories.SearchRepository
+}
+
+// NewSearchService 创建搜索服务
+func NewSearchService() SearchService {
+	return &searchServiceImpl{
+		searchRepo: repositories.NewSearchRepository(),
+	}
+}
+
+// SearchPosts 搜索文章（支持状态、作者、标签、发布时间过滤），默认只搜索已发布的文章
+func (s *searchServiceImpl) SearchPosts(req PostSearchRequest) ([]PostHit, int64, error) {
+	switch req.Status {
+	case "", "draft", "published", "archived":
+	default:
+		return nil, 0, ErrInvalidStatus
+	}
+
+	keyword := strings.TrimSpace(req.Keyword)
+	page, pageSize := normalizePage(req.Page, req.PageSize)
+
+	results, total, err := s.searchRepo.SearchPosts(repositories.PostSearchFilter{
+		Keyword:       keyword,
+		Status:        req.Status,
+		IncludeDrafts: req.IncludeDrafts,
+		AuthorID:      req.AuthorID,
+		Tags:          req.Tags,
+		From:          req.From,
+		To:            req.To,
+	}, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("搜索文章失败: %v", err)
+	}
+
+	terms := strings.Fields(keyword)
+	hits := make([]PostHit, 0, len(results))
+	for _, r := range results {
+		hits = append(hits, PostHit{
+			Post:    r.Post,
+			Score:   r.Score,
+			Title:   highlight(r.Post.Title, terms),
+			Snippet: snippet(r.Post.Content, terms),
+		})
+	}
+
+	return hits, total, nil
+}
+
+// SearchUsers 搜索用户（用户名、邮箱前缀及个人简介），默认只返回活跃账户
+func (s *searchServiceImpl) SearchUsers(keyword string, active repositories.ActiveFilter, page, pageSize int) ([]UserHit, int64, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, 0, ErrEmptyKeyword
+	}
+	page, pageSize = normalizePage(page, pageSize)
+
+	results, total, err := s.searchRepo.SearchUsers(keyword, active, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("搜索用户失败: %v", err)
+	}
+
+	terms := strings.Fields(keyword)
+	hits := make([]UserHit, 0, len(results))
+	for _, r := range results {
+		hit := UserHit{User: r.User, Score: r.Score}
+		if r.User.Profile != nil {
+			hit.Snippet = snippet(r.User.Profile.Bio, terms)
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, total, nil
+}
+
+// normalizePage 规范化分页参数
+func normalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	} else if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+// highlight 转义文本并用高亮标记包裹所有命中词（不区分大小写）
+func highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := lowerRunes(runes)
+	needles := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		if term != "" {
+			needles = append(needles, lowerRunes([]rune(term)))
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		matched := 0
+		for _, n := range needles {
+			if len(n) > matched && hasRunePrefix(lower[i:], n) {
+				matched = len(n)
+			}
+		}
+		if matched > 0 {
+			b.WriteString(HighlightPre)
+			b.WriteString(html.EscapeString(string(runes[i : i+matched])))
+			b.WriteString(HighlightPost)
+			i += matched
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		i++
+	}
+	return b.String()
+}
+
+// snippet 截取首个命中词附近的正文并高亮
+func snippet(text string, terms []string) string {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return ""
+	}
+
+	// 定位首个命中词（按字符计）
+	start := -1
+	lower := lowerRunes(runes)
+	for _, term := range terms {
+		if idx := runeIndex(lower, lowerRunes([]rune(term))); idx >= 0 && (start < 0 || idx < start) {
+			start = idx
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	from := start - snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	to := start + snippetRadius
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	result := highlight(string(runes[from:to]), terms)
+	if from > 0 {
+		result = "…" + result
+	}
+	if to < len(runes) {
+		result += "…"
+	}
+	return result
+}
+
+// lowerRunes 逐字符转小写（保持字符数不变，便于对齐原文位置）
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+// hasRunePrefix 判断字符切片是否以prefix开头
+func hasRunePrefix(s, prefix []rune) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// runeIndex 在字符切片中查找子串位置
+func runeIndex(s, sub []rune) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if hasRunePrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
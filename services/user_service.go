@@ -1,25 +1,29 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-
-	"log"
+	"strings"
 	"time"
 
+	"exercise/database"
 	"exercise/models"
 	"exercise/repositories"
-
-	"gorm.io/gorm"
 )
 
 var (
-	ErrUserNotFound   = errors.New("用户不存在")
-	ErrInvalidEmail   = errors.New("邮箱格式错误")
-	ErrInvalidAge     = errors.New("年龄必须大于0且小于150")
-	ErrWeakPassword   = errors.New("密码强度不足")
-	ErrDuplicateEmail = errors.New("邮箱已存在")
+	ErrUserNotFound        = errors.New("用户不存在")
+	ErrInvalidEmail        = errors.New("邮箱格式错误")
+	ErrInvalidAge          = errors.New("年龄必须大于0且小于150")
+	ErrWeakPassword        = errors.New("密码强度不足")
+	ErrDuplicateEmail      = errors.New("邮箱已存在")
+	ErrDuplicateUsername   = errors.New("用户名已存在")
+	ErrAccountInactive     = errors.New("账户已停用")
+	ErrAccountActive       = errors.New("账户未停用")
+	ErrEmptyProfilePatch   = errors.New("未指定要更新的资料字段")
+	ErrInvalidProfileField = errors.New("不支持更新的资料字段")
 )
 
 // UserService 用户服务接口
@@ -28,8 +32,15 @@ type UserService interface {
 	Login(email, password string) (*models.User, error)
 	GetUserByID(id uint) (*models.User, error)
 	UpdateProfile(id uint, profile *models.Profile) error
-	DeactivateAccount(id uint) error
-	SearchUsers(keyword string, page, pageSize int) ([]models.User, int64, error)
+	PatchProfile(id uint, patch ProfilePatch) (*models.Profile, error)
+	DeactivateAccount(id uint, by, reason string) error
+	ReactivateAccount(id uint) error
+	DeleteAccount(id uint) error
+	RestoreAccount(id uint) error
+	ListDeletedUsers(page, pageSize int) ([]models.User, int64, error)
+	PurgeAccount(id uint) error
+	SearchUsers(keyword string, active repositories.ActiveFilter, page, pageSize int) ([]models.User, int64, error)
+	ListUsers(active repositories.ActiveFilter, page, pageSize int) ([]models.User, int64, error)
 	GetUserStats() (*UserStats, error)
 	ExportUsers() ([]byte, error)
 	ImportUsers(data []byte) (int, error)
@@ -45,15 +56,31 @@ type UserStats struct {
 	TopDomains     []string `json:"top_domains"`
 }
 
+// ProfilePatch 用户资料的部分更新
+// Mask 列出要更新的字段（json 字段名），字段取 Values 中的值；
+// 在 Mask 中但值为空的字段会被清空，不在 Mask 中的字段保持不变
+// Version 非零时只在资料版本号仍为该值时更新，否则返回 ErrConflict；为零时资料不存在则创建
+type ProfilePatch struct {
+	Values  models.Profile
+	Mask    []string
+	Version uint
+}
+
 // userServiceImpl 用户服务实现
 type userServiceImpl struct {
-	userRepo repositories.UserRepository
+	txm         database.TxManager
+	userRepo    repositories.UserRepository
+	profileRepo repositories.ProfileRepository
+	searchRepo  repositories.SearchRepository
 }
 
 // NewUserService 创建用户服务
 func NewUserService() UserService {
 	return &userServiceImpl{
-		userRepo: repositories.NewUserRepository(),
+		txm:         database.NewTxManager(),
+		userRepo:    repositories.NewUserRepository(),
+		profileRepo: repositories.NewProfileRepository(),
+		searchRepo:  repositories.NewSearchRepository(),
 	}
 }
 
@@ -70,7 +97,7 @@ func (s *userServiceImpl) Register(user *models.User) error {
 
 	// 检查邮箱是否已存在
 	existing, err := s.userRepo.FindByEmail(user.Email)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
 		return fmt.Errorf("检查邮箱失败: %v", err)
 	}
 	if existing != nil {
@@ -86,7 +113,7 @@ func (s *userServiceImpl) Login(email, password string) (*models.User, error) {
 	// 查找用户
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repositories.ErrNotFound) {
 			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("查找用户失败: %v", err)
@@ -97,6 +124,11 @@ func (s *userServiceImpl) Login(email, password string) (*models.User, error) {
 		return nil, errors.New("密码错误")
 	}
 
+	// 密码正确后再提示停用，避免泄露账户状态
+	if !user.IsActive {
+		return nil, ErrAccountInactive
+	}
+
 	// TODO: 更新最后登录时间（需要在User模型中添加LastLoginAt字段）
 	// user.LastLoginAt = time.Now()
 	// if err := s.userRepo.Update(user); err != nil {
@@ -110,7 +142,7 @@ func (s *userServiceImpl) Login(email, password string) (*models.User, error) {
 func (s *userServiceImpl) GetUserByID(id uint) (*models.User, error) {
 	user, err := s.userRepo.FindByID(id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repositories.ErrNotFound) {
 			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("获取用户失败: %v", err)
@@ -130,43 +162,212 @@ func (s *userServiceImpl) GetUserByID(id uint) (*models.User, error) {
 	return user, nil
 }
 
-// UpdateProfile 更新用户资料
+// UpdateProfile 更新用户资料（只更新非空字段，清空字段使用 PatchProfile）
+// 以读取到的版本号更新，与其他更新冲突时重新读取后重试
 func (s *userServiceImpl) UpdateProfile(id uint, profile *models.Profile) error {
-	// 获取用户
-	user, err := s.userRepo.FindByID(id)
-	if err != nil {
-		return ErrUserNotFound
+	var mask []string
+	for _, field := range profileFields {
+		if *profileField(profile, field) != "" {
+			mask = append(mask, field)
+		}
+	}
+	if len(mask) == 0 {
+		return nil
 	}
 
-	// 更新profile
-	if user.Profile == nil {
-		user.Profile = profile
-	} else {
-		user.Profile = mergeProfiles(user.Profile, profile)
+	return retryOnConflict(func() error {
+		patch := ProfilePatch{Values: *profile, Mask: mask}
+		current, err := s.profileRepo.FindByUserID(id)
+		if err == nil {
+			patch.Version = current.Version
+		} else if !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("获取用户资料失败: %v", err)
+		}
+		_, err = s.PatchProfile(id, patch)
+		return err
+	})
+}
+
+// PatchProfile 按字段掩码更新用户资料，资料不存在时创建，返回更新后的资料
+// 检查用户、写入和重新读取资料在同一事务中完成
+func (s *userServiceImpl) PatchProfile(id uint, patch ProfilePatch) (*models.Profile, error) {
+	if len(patch.Mask) == 0 {
+		return nil, ErrEmptyProfilePatch
+	}
+
+	// 只取掩码内的字段，避免创建资料时写入掩码外的值
+	profile := models.Profile{UserID: id}
+	var columns []string
+	seen := make(map[string]bool)
+	for _, field := range patch.Mask {
+		if !isProfileField(field) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProfileField, field)
+		}
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		*profileField(&profile, field) = *profileField(&patch.Values, field)
+		columns = append(columns, field)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
 	}
 
-	// 保存更新
-	return s.userRepo.Update(user)
+	var updated *models.Profile
+	err := s.txm.WithinTx(context.Background(), func(ctx context.Context) error {
+		userRepo, profileRepo := s.userRepo.WithContext(ctx), s.profileRepo.WithContext(ctx)
+
+		exists, err := userRepo.Exists(id)
+		if err != nil {
+			return fmt.Errorf("获取用户失败: %v", err)
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+
+		if patch.Version != 0 {
+			err = profileRepo.UpdateVersioned(&profile, columns, patch.Version)
+		} else {
+			err = profileRepo.Upsert(&profile, columns)
+		}
+		if err != nil {
+			if errors.Is(err, ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("更新用户资料失败: %v", err)
+		}
+
+		if updated, err = profileRepo.FindByUserID(id); err != nil {
+			return fmt.Errorf("获取用户资料失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
-// DeactivateAccount 停用账户（软删除）
-func (s *userServiceImpl) DeactivateAccount(id uint) error {
-	// 停用用户
-	user, err := s.userRepo.FindByID(id)
+// DeactivateAccount 停用账户（不删除数据，可通过 ReactivateAccount 恢复；删除账户使用 DeleteAccount）
+// 并发修改时重新读取账户状态后重试，已被他人停用则返回 ErrAccountInactive
+func (s *userServiceImpl) DeactivateAccount(id uint, by, reason string) error {
+	return retryOnConflict(func() error {
+		user, err := s.userRepo.FindByIDForUpdate(id)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("获取用户失败: %v", err)
+		}
+		if !user.IsActive {
+			return ErrAccountInactive
+		}
+
+		if err := s.userRepo.Deactivate(id, user.Version, by, reason, time.Now()); err != nil {
+			if errors.Is(err, ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("停用账户失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// ReactivateAccount 重新启用已停用的账户，并发修改时重新读取账户状态后重试
+func (s *userServiceImpl) ReactivateAccount(id uint) error {
+	return retryOnConflict(func() error {
+		user, err := s.userRepo.FindByIDForUpdate(id)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("获取用户失败: %v", err)
+		}
+		if user.IsActive {
+			return ErrAccountActive
+		}
+
+		if err := s.userRepo.Reactivate(id, user.Version); err != nil {
+			if errors.Is(err, ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("启用账户失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// DeleteAccount 删除账户（软删除，可通过 RestoreAccount 恢复，保留期满后彻底删除）
+func (s *userServiceImpl) DeleteAccount(id uint) error {
+	if _, err := s.userRepo.FindByID(id); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("获取用户失败: %v", err)
+	}
+	return s.userRepo.Delete(id)
+}
+
+// RestoreAccount 恢复已删除的账户，用户名或邮箱已被他人使用时失败
+func (s *userServiceImpl) RestoreAccount(id uint) error {
+	user, err := s.userRepo.FindDeletedByID(id)
 	if err != nil {
-		return ErrUserNotFound
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("获取已删除用户失败: %v", err)
+	}
+
+	if _, err := s.userRepo.FindByEmail(user.Email); err == nil {
+		return ErrDuplicateEmail
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return fmt.Errorf("检查邮箱失败: %v", err)
+	}
+	if _, err := s.userRepo.FindByUsername(user.Username); err == nil {
+		return ErrDuplicateUsername
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return fmt.Errorf("检查用户名失败: %v", err)
 	}
 
-	user.IsActive = false
-	// TODO: 添加DeactivatedAt字段到User模型
-	// user.DeactivatedAt = time.Now()
+	return s.userRepo.Restore(id)
+}
+
+// ListDeletedUsers 分页列出已删除的账户
+func (s *userServiceImpl) ListDeletedUsers(page, pageSize int) ([]models.User, int64, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	return s.userRepo.ListDeleted(page, pageSize)
+}
 
-	return s.userRepo.Update(user)
+// PurgeAccount 彻底删除已删除的账户及其资料和评论，不可恢复
+func (s *userServiceImpl) PurgeAccount(id uint) error {
+	if err := s.userRepo.Purge(id); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("彻底删除用户失败: %v", err)
+	}
+	return nil
 }
 
-// SearchUsers 搜索用户（分页）
-func (s *userServiceImpl) SearchUsers(keyword string, page, pageSize int) ([]models.User, int64, error) {
-	return s.userRepo.Search(keyword, page, pageSize)
+// SearchUsers 按用户名、邮箱前缀及个人简介搜索用户（分页，按相关度排序），关键字为空时列出全部
+func (s *userServiceImpl) SearchUsers(keyword string, active repositories.ActiveFilter, page, pageSize int) ([]models.User, int64, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	results, total, err := s.searchRepo.SearchUsers(strings.TrimSpace(keyword), active, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("搜索用户失败: %v", err)
+	}
+	users := make([]models.User, 0, len(results))
+	for _, r := range results {
+		users = append(users, r.User)
+	}
+	return users, total, nil
+}
+
+// ListUsers 按账户状态分页列出用户
+func (s *userServiceImpl) ListUsers(active repositories.ActiveFilter, page, pageSize int) ([]models.User, int64, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	return s.userRepo.FindAll(active, page, pageSize)
 }
 
 // GetUserStats 获取用户统计信息
@@ -174,7 +375,7 @@ func (s *userServiceImpl) GetUserStats() (*UserStats, error) {
 	var stats UserStats
 
 	// 获取所有用户进行统计
-	users, _, err := s.userRepo.FindAll(1, 1000000)
+	users, _, err := s.userRepo.FindAll(repositories.AnyActiveState, 1, 1000000)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户数据失败: %v", err)
 	}
@@ -204,7 +405,7 @@ func (s *userServiceImpl) GetUserStats() (*UserStats, error) {
 
 // ExportUsers 导出用户数据（JSON格式）
 func (s *userServiceImpl) ExportUsers() ([]byte, error) {
-	users, _, err := s.userRepo.FindAll(1, 1000000) // 获取所有用户
+	users, _, err := s.userRepo.FindAll(repositories.AnyActiveState, 1, 1000000) // 获取所有用户
 	if err != nil {
 		return nil, fmt.Errorf("获取用户数据失败: %v", err)
 	}
@@ -213,7 +414,8 @@ func (s *userServiceImpl) ExportUsers() ([]byte, error) {
 	return json.Marshal(users)
 }
 
-// ImportUsers 导入用户数据（批量创建）
+// ImportUsers 导入用户数据（批量创建），已存在的用户跳过
+// 返回成功导入的数量，其余失败的用户汇总为一个错误
 func (s *userServiceImpl) ImportUsers(data []byte) (int, error) {
 	var users []models.User
 	if err := json.Unmarshal(data, &users); err != nil {
@@ -222,35 +424,53 @@ func (s *userServiceImpl) ImportUsers(data []byte) (int, error) {
 
 	// 批量创建
 	count := 0
+	var errs []error
 	for _, user := range users {
 		if err := s.userRepo.Create(&user); err != nil {
-			// 跳过重复邮箱的用户
-			if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// 跳过邮箱或用户名已存在的用户
+			if errors.Is(err, repositories.ErrDuplicate) {
 				continue
 			}
-			log.Printf("导入用户失败: %v", err)
+			errs = append(errs, fmt.Errorf("导入用户 %s 失败: %v", user.Username, err))
 			continue
 		}
 		count++
 	}
 
+	if len(errs) > 0 {
+		return count, fmt.Errorf("%d 个用户导入失败: %w", len(errs), errors.Join(errs...))
+	}
 	return count, nil
 }
 
-// 辅助函数：合并用户资料
-func mergeProfiles(old, new *models.Profile) *models.Profile {
-	result := *old
-	if new.Bio != "" {
-		result.Bio = new.Bio
-	}
-	if new.Location != "" {
-		result.Location = new.Location
-	}
-	if new.AvatarURL != "" {
-		result.AvatarURL = new.AvatarURL
+// profileFields 可以通过 PatchProfile 更新的资料字段（json 字段名与列名相同）
+var profileFields = []string{"first_name", "last_name", "bio", "avatar_url", "location", "website"}
+
+// isProfileField 判断是否为可更新的资料字段
+func isProfileField(field string) bool {
+	for _, f := range profileFields {
+		if f == field {
+			return true
+		}
 	}
-	if new.Website != "" {
-		result.Website = new.Website
+	return false
+}
+
+// 辅助函数：返回资料字段的指针，field 必须是 profileFields 之一
+func profileField(profile *models.Profile, field string) *string {
+	switch field {
+	case "first_name":
+		return &profile.FirstName
+	case "last_name":
+		return &profile.LastName
+	case "bio":
+		return &profile.Bio
+	case "avatar_url":
+		return &profile.AvatarURL
+	case "location":
+		return &profile.Location
+	case "website":
+		return &profile.Website
 	}
-	return &result
+	return nil
 }
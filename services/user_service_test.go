@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+	"exercise/repositories"
+)
+
+// createUser 创建测试用户
+func createUser(t *testing.T, username string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", Password: "secret123", Age: 30, IsActive: true}
+	if err := NewUserService().Register(user); err != nil {
+		t.Fatalf("创建用户 %s 失败: %v", username, err)
+	}
+	return user
+}
+
+func TestSearchUsersMatchesPrefix(t *testing.T) {
+	testdb.Open(t)
+	createUser(t, "alice")
+	createUser(t, "malice")
+	createUser(t, "bob")
+
+	svc := NewUserService()
+	tests := []struct {
+		keyword string
+		want    []string
+	}{
+		{"ali", []string{"alice"}},               // 前缀匹配，不再匹配 malice 中间的 ali
+		{"lice", nil},                            // 不做 %kw% 匹配
+		{"bob@", []string{"bob"}},                // 邮箱前缀
+		{"", []string{"alice", "malice", "bob"}}, // 无关键字时列出全部
+	}
+	for _, tt := range tests {
+		users, total, err := svc.SearchUsers(tt.keyword, repositories.ActiveOnly, 1, 10)
+		if err != nil {
+			t.Fatalf("SearchUsers(%q) 失败: %v", tt.keyword, err)
+		}
+		if int(total) != len(tt.want) || len(users) != len(tt.want) {
+			t.Fatalf("SearchUsers(%q) = %d 个（total %d），want %v", tt.keyword, len(users), total, tt.want)
+		}
+		got := make(map[string]bool)
+		for _, u := range users {
+			got[u.Username] = true
+		}
+		for _, name := range tt.want {
+			if !got[name] {
+				t.Errorf("SearchUsers(%q) 缺少 %s", tt.keyword, name)
+			}
+		}
+	}
+}
+
+func TestDeactivateAndReactivateAccount(t *testing.T) {
+	testdb.Open(t)
+	user := createUser(t, "alice")
+	svc := NewUserService()
+
+	if err := svc.ReactivateAccount(user.ID); !errors.Is(err, ErrAccountActive) {
+		t.Errorf("启用活跃账户返回 %v, want ErrAccountActive", err)
+	}
+	if err := svc.DeactivateAccount(user.ID, "admin", "违规"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeactivateAccount(user.ID, "admin", "违规"); !errors.Is(err, ErrAccountInactive) {
+		t.Errorf("重复停用返回 %v, want ErrAccountInactive", err)
+	}
+	if err := svc.ReactivateAccount(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeactivateAccount(user.ID+100, "admin", ""); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("停用不存在的用户返回 %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestConcurrentDeactivateRetriesStaleWriter(t *testing.T) {
+	testdb.Open(t)
+	user := createUser(t, "alice")
+	svc := NewUserService()
+
+	// 两个请求读到同一版本后并发停用：落后的一方冲突后重新读取，看到账户已停用
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = svc.DeactivateAccount(user.ID, "admin", "违规")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var ok, inactive int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, ErrAccountInactive):
+			inactive++
+		default:
+			t.Errorf("并发停用返回 %v", err)
+		}
+	}
+	if ok != 1 || inactive != 1 {
+		t.Errorf("成功 %d 次、已停用 %d 次, want 各 1 次", ok, inactive)
+	}
+}
+
+// usernames 返回用户名（按原顺序）
+func usernames(users []models.User) []string {
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	return names
+}
+
+func TestLoginRejectsDeactivatedAccount(t *testing.T) {
+	db := testdb.Open(t)
+	user := createUser(t, "alice")
+	svc := NewUserService()
+
+	if _, err := svc.Login("alice@example.com", "secret123"); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if err := svc.DeactivateAccount(user.ID, "admin", "违规"); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored models.User
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.IsActive || stored.DeactivatedAt == nil || stored.DeactivatedBy != "admin" || stored.DeactivationReason != "违规" {
+		t.Errorf("停用后 = {IsActive:%v DeactivatedAt:%v By:%q Reason:%q}, want 记录停用信息",
+			stored.IsActive, stored.DeactivatedAt, stored.DeactivatedBy, stored.DeactivationReason)
+	}
+
+	if _, err := svc.Login("alice@example.com", "secret123"); !errors.Is(err, ErrAccountInactive) {
+		t.Errorf("停用后登录返回 %v, want ErrAccountInactive", err)
+	}
+	// 密码错误时不泄露账户已停用
+	if _, err := svc.Login("alice@example.com", "wrong"); err == nil || errors.Is(err, ErrAccountInactive) {
+		t.Errorf("停用后密码错误返回 %v, want 密码错误", err)
+	}
+
+	if err := svc.ReactivateAccount(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login("alice@example.com", "secret123"); err != nil {
+		t.Errorf("重新启用后登录失败: %v", err)
+	}
+	stored = models.User{}
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsActive || stored.DeactivatedAt != nil || stored.DeactivatedBy != "" || stored.DeactivationReason != "" {
+		t.Errorf("重新启用后 = {IsActive:%v DeactivatedAt:%v By:%q Reason:%q}, want 清空停用信息",
+			stored.IsActive, stored.DeactivatedAt, stored.DeactivatedBy, stored.DeactivationReason)
+	}
+}
+
+func TestActiveFilter(t *testing.T) {
+	testdb.Open(t)
+	createUser(t, "alice")
+	bob := createUser(t, "bob")
+	createUser(t, "alma")
+	svc := NewUserService()
+	if err := svc.DeactivateAccount(bob.ID, "admin", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		active  repositories.ActiveFilter
+		keyword string
+		want    []string
+	}{
+		{"活跃", repositories.ActiveOnly, "", []string{"alice", "alma"}},
+		{"已停用", repositories.InactiveOnly, "", []string{"bob"}},
+		{"不限", repositories.AnyActiveState, "", []string{"alice", "alma", "bob"}},
+		{"活跃且匹配关键字", repositories.ActiveOnly, "al", []string{"alice", "alma"}},
+		{"已停用且匹配关键字", repositories.InactiveOnly, "bo", []string{"bob"}},
+		{"已停用但不匹配关键字", repositories.InactiveOnly, "al", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := svc.SearchUsers(tt.keyword, tt.active, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := usernames(users)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) || int(total) != len(tt.want) {
+				t.Errorf("SearchUsers(%q) = %v（total %d）, want %v", tt.keyword, got, total, tt.want)
+			}
+
+			if tt.keyword != "" {
+				return
+			}
+			users, total, err = svc.ListUsers(tt.active, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = usernames(users)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) || int(total) != len(tt.want) {
+				t.Errorf("ListUsers = %v（total %d）, want %v", got, total, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"exercise/repositories"
+)
+
+// ViewPolicy 浏览量批量写入策略
+type ViewPolicy struct {
+	FlushInterval time.Duration // 定期写入数据库的间隔
+	MaxPending    int           // 累计的文章数达到该值时提前写入
+}
+
+// ViewCounter 文章浏览量计数服务接口
+// 浏览量先在内存中按文章累计，定期以一条语句批量原子地加到数据库中，
+// 热门文章的大量浏览只产生少量写入；进程异常退出时最多丢失一个间隔内的计数
+type ViewCounter interface {
+	Record(postID uint)
+	Pending(postID uint) int64
+	Flush() error
+	Start(ctx context.Context) <-chan struct{}
+}
+
+// viewCounterImpl 文章浏览量计数服务实现
+type viewCounterImpl struct {
+	postRepo repositories.PostRepository
+	policy   ViewPolicy
+
+	mu      sync.Mutex
+	pending map[uint]int64
+	flushMu sync.Mutex    // 同一时间只有一次写入
+	full    chan struct{} // 累计的文章数达到上限时通知后台写入
+}
+
+// NewViewCounter 创建文章浏览量计数服务
+func NewViewCounter(policy ViewPolicy) ViewCounter {
+	if policy.FlushInterval <= 0 {
+		policy.FlushInterval = 5 * time.Second
+	}
+	if policy.MaxPending <= 0 {
+		policy.MaxPending = 1000
+	}
+	return &viewCounterImpl{
+		postRepo: repositories.NewPostRepository(),
+		policy:   policy,
+		pending:  make(map[uint]int64),
+		full:     make(chan struct{}, 1),
+	}
+}
+
+// Record 记录一次浏览（只写内存）
+func (c *viewCounterImpl) Record(postID uint) {
+	c.mu.Lock()
+	c.pending[postID]++
+	full := len(c.pending) >= c.policy.MaxPending
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Pending 返回文章尚未写入数据库的浏览量，展示时加到数据库中的值上
+func (c *viewCounterImpl) Pending(postID uint) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending[postID]
+}
+
+// Flush 将累计的浏览量写入数据库，失败时计数放回内存，下次写入时重试
+func (c *viewCounterImpl) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	counts := c.pending
+	c.pending = make(map[uint]int64, len(counts))
+	c.mu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	if err := c.postRepo.AddViews(counts); err != nil {
+		c.mu.Lock()
+		for id, n := range counts {
+			c.pending[id] += n
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("写入浏览量失败: %v", err)
+	}
+	return nil
+}
+
+// Start 在后台定期写入浏览量，ctx 取消后写入剩余的计数并停止
+// 返回的通道在最后一次写入完成后关闭，关闭数据库前须等待该通道
+func (c *viewCounterImpl) Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(c.policy.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if err := c.Flush(); err != nil {
+					log.Printf("❌ 停止前%v，未写入的浏览量已丢失", err)
+				}
+				return
+			case <-ticker.C:
+			case <-c.full:
+			}
+			if err := c.Flush(); err != nil {
+				log.Printf("❌ %v", err)
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+
+	"gorm.io/gorm"
+)
+
+// recordPostUpdates 记录对 posts 表执行的 UPDATE 语句
+func recordPostUpdates(t *testing.T, db *gorm.DB) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var statements []string
+	err := db.Callback().Update().After("gorm:update").Register("test:record_post_updates", func(tx *gorm.DB) {
+		if tx.Statement.Table == "posts" {
+			mu.Lock()
+			statements = append(statements, tx.Statement.SQL.String())
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), statements...)
+	}
+}
+
+func TestViewCounterBatchesConcurrentRecords(t *testing.T) {
+	db := testdb.Open(t)
+	author := createUser(t, "author")
+	posts := make([]*models.Post, 3)
+	for i := range posts {
+		posts[i] = createPostBy(t, db, author, "文章"+itoa(uint(i)), "正文")
+	}
+	updates := recordPostUpdates(t, db)
+
+	// 每篇文章的浏览次数：第 i 篇 (i+1)*100 次，由多个 goroutine 并发记录
+	counter := NewViewCounter(ViewPolicy{FlushInterval: time.Hour, MaxPending: 100})
+	const workers = 10
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, post := range posts {
+				for n := 0; n < (i+1)*100/workers; n++ {
+					counter.Record(post.ID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, post := range posts {
+		if got, want := counter.Pending(post.ID), int64((i+1)*100); got != want {
+			t.Errorf("文章 %d 待写入 %d, want %d", post.ID, got, want)
+		}
+	}
+	if got := updates(); len(got) != 0 {
+		t.Fatalf("Flush 前已写入数据库: %v", got)
+	}
+
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	statements := updates()
+	if len(statements) != 1 || !strings.Contains(statements[0], "views + CASE id") {
+		t.Fatalf("Flush 执行的 UPDATE = %v, want 一条 views + CASE 语句", statements)
+	}
+	for i, post := range posts {
+		var stored models.Post
+		if err := db.First(&stored, post.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if want := (i + 1) * 100; stored.Views != want {
+			t.Errorf("文章 %d 浏览量 = %d, want %d", post.ID, stored.Views, want)
+		}
+		if counter.Pending(post.ID) != 0 {
+			t.Errorf("文章 %d 写入后仍有待写入的浏览量", post.ID)
+		}
+	}
+
+	// 没有新的浏览时不再写入
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := updates(); len(got) != 1 {
+		t.Errorf("没有新浏览时执行了 UPDATE: %v", got[1:])
+	}
+}
+
+func TestViewCounterFlushesOnStop(t *testing.T) {
+	db := testdb.Open(t)
+	author := createUser(t, "author")
+	post := createPostBy(t, db, author, "文章", "正文")
+
+	counter := NewViewCounter(ViewPolicy{FlushInterval: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := counter.Start(ctx)
+	for i := 0; i < 5; i++ {
+		counter.Record(post.ID)
+	}
+	cancel()
+	// 与 events run 一样，等待最后一次写入完成
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("停止后没有完成写入")
+	}
+
+	var stored models.Post
+	if err := db.First(&stored, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Views != 5 {
+		t.Errorf("浏览量 = %d, want 5", stored.Views)
+	}
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"exercise/models"
+	"exercise/repositories"
+)
+
+// Webhook 请求头
+const (
+	WebhookEventHeader     = "X-Webhook-Event"     // 事件类型
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"  // 投递ID，重新投递时不变
+	WebhookTimestampHeader = "X-Webhook-Timestamp" // 发送时间（Unix 秒），参与签名，接收方可据此拒绝过旧的请求
+	WebhookSignatureHeader = "X-Webhook-Signature" // sha256=<HMAC-SHA256(密钥, 时间戳 + "." + 请求体) 的十六进制>
+)
+
+// webhookResponseLimit 投递日志中保存的响应体长度上限
+const webhookResponseLimit = 1024
+
+// webhookSecretBytes 自动生成的签名密钥长度
+const webhookSecretBytes = 32
+
+var (
+	// ErrWebhookNotFound Webhook 订阅不存在
+	ErrWebhookNotFound = errors.New("Webhook 订阅不存在")
+	// ErrDeliveryNotFound 投递记录不存在
+	ErrDeliveryNotFound = errors.New("投递记录不存在")
+	// ErrInvalidWebhook Webhook 订阅参数无效
+	ErrInvalidWebhook = errors.New("Webhook 订阅参数无效")
+)
+
+// webhookEnvelope Webhook 请求体
+type webhookEnvelope struct {
+	ID        uint            `json:"id"` // 事件ID，接收方据此去重
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SignWebhook 计算 Webhook 请求的签名（WebhookSignatureHeader 的值）
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature 校验接收到的 Webhook 请求的签名，供接收方使用
+func VerifyWebhookSignature(secret, timestamp string, body []byte, signature string) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(SignWebhook(secret, ts, body)), []byte(signature))
+}
+
+// WebhookService Webhook 订阅与投递服务接口
+// 订阅 EventDispatcher 中的领域事件，为每个订阅了该事件的 Webhook 生成一条投递记录，
+// 再由 Start 启动的发送任务逐个发送；每条投递独立重试，一个接收方失败不影响其他接收方
+type WebhookService interface {
+	CreateWebhook(webhook *models.Webhook) error
+	ListWebhooks() ([]models.Webhook, error)
+	DeleteWebhook(id uint) error
+	Subscribe(dispatcher EventDispatcher)
+	DeliverPending(ctx context.Context) (*DispatchReport, error)
+	Start(ctx context.Context)
+	ListDeliveries(webhookID uint, status string, page, pageSize int) ([]models.WebhookDelivery, int64, error)
+	ListAttempts(deliveryID uint) ([]models.WebhookDeliveryAttempt, error)
+	Redeliver(id uint) error
+}
+
+// webhookServiceImpl Webhook 订阅与投递服务实现
+type webhookServiceImpl struct {
+	webhookRepo repositories.WebhookRepository
+	policy      DispatchPolicy
+	client      *http.Client
+}
+
+// NewWebhookService 创建 Webhook 服务，policy.Timeout 为单个请求的时限
+func NewWebhookService(policy DispatchPolicy) WebhookService {
+	policy = policy.withDefaults()
+	return &webhookServiceImpl{
+		webhookRepo: repositories.NewWebhookRepository(),
+		policy:      policy,
+		client: &http.Client{
+			Timeout: policy.Timeout,
+			// 不跟随重定向，3xx 视为失败，避免请求被转发到订阅时未确认的地址
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// CreateWebhook 创建 Webhook 订阅，未指定密钥时自动生成（创建后 webhook.Secret 中为密钥，只在此时可见）
+func (s *webhookServiceImpl) CreateWebhook(webhook *models.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: 不是有效的http(s)地址: %q", ErrInvalidWebhook, webhook.URL)
+	}
+	if len(webhook.EventTypes) == 0 {
+		return fmt.Errorf("%w: 至少需要订阅一种事件", ErrInvalidWebhook)
+	}
+	known := models.EventTypes()
+	var types []string
+	for _, t := range webhook.EventTypes {
+		if !slices.Contains(known, t) {
+			return fmt.Errorf("%w: 未知的事件类型 %q（可选 %s）", ErrInvalidWebhook, t, strings.Join(known, "/"))
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	webhook.EventTypes = types
+
+	if webhook.Secret == "" {
+		secret := make([]byte, webhookSecretBytes)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("生成签名密钥失败: %v", err)
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+	webhook.IsActive = true
+
+	if err := s.webhookRepo.Create(webhook); err != nil {
+		return fmt.Errorf("创建 Webhook 订阅失败: %v", err)
+	}
+	return nil
+}
+
+// ListWebhooks 列出全部 Webhook 订阅
+func (s *webhookServiceImpl) ListWebhooks() ([]models.Webhook, error) {
+	webhooks, err := s.webhookRepo.FindAll()
+	if err != nil {
+		return nil, fmt.Errorf("获取 Webhook 订阅失败: %v", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook 删除 Webhook 订阅及其投递记录
+func (s *webhookServiceImpl) DeleteWebhook(id uint) error {
+	if err := s.webhookRepo.Delete(id); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrWebhookNotFound
+		}
+		return fmt.Errorf("删除 Webhook 订阅失败: %v", err)
+	}
+	return nil
+}
+
+// Subscribe 订阅全部领域事件，事件到达时为订阅了该事件的 Webhook 生成投递记录
+func (s *webhookServiceImpl) Subscribe(dispatcher EventDispatcher) {
+	for _, eventType := range models.EventTypes() {
+		dispatcher.Subscribe(eventType, s.enqueue)
+	}
+}
+
+// enqueue 为订阅了事件的 Webhook 生成投递记录（事件重复投递时不会重复生成）
+func (s *webhookServiceImpl) enqueue(ctx context.Context, event *models.OutboxEvent) error {
+	webhookRepo := s.webhookRepo.WithContext(ctx)
+	webhooks, err := webhookRepo.FindSubscribed(event.EventType)
+	if err != nil {
+		return fmt.Errorf("获取 Webhook 订阅失败: %v", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        event.ID,
+		Type:      event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return fmt.Errorf("生成 Webhook 请求体失败: %v", err)
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(webhooks))
+	for i, w := range webhooks {
+		deliveries[i] = models.WebhookDelivery{
+			WebhookID:     w.ID,
+			EventID:       event.ID,
+			EventType:     event.EventType,
+			Payload:       body,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		}
+	}
+	if _, err := webhookRepo.EnqueueDeliveries(deliveries); err != nil {
+		return fmt.Errorf("生成 Webhook 投递失败: %v", err)
+	}
+	return nil
+}
+
+// DeliverPending 领取一批到期的投递并依次发送，记录每次请求的结果
+func (s *webhookServiceImpl) DeliverPending(ctx context.Context) (*DispatchReport, error) {
+	report := &DispatchReport{}
+	claimedAt := time.Now()
+	deliveries, err := s.webhookRepo.ClaimDeliveries(s.policy.BatchSize, s.policy.lease(), claimedAt)
+	if err != nil {
+		return report, fmt.Errorf("领取 Webhook 投递失败: %v", err)
+	}
+
+	webhooks := make(map[uint]*models.Webhook)
+	var errs []error
+	for i := range deliveries {
+		if ctx.Err() != nil || s.policy.expiring(claimedAt) {
+			// 未发送的投递在租约到期后会被重新领取
+			break
+		}
+		d := &deliveries[i]
+		webhook, ok := webhooks[d.WebhookID]
+		if !ok {
+			if webhook, err = s.webhookRepo.FindByID(d.WebhookID); err != nil {
+				errs = append(errs, fmt.Errorf("获取 Webhook 订阅 %d 失败: %v", d.WebhookID, err))
+				continue
+			}
+			webhooks[d.WebhookID] = webhook
+		}
+		if err := s.deliver(ctx, webhook, d, report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return report, fmt.Errorf("发送 Webhook 失败: %w", errors.Join(errs...))
+	}
+	return report, nil
+}
+
+// deliver 发送一次投递并记录结果，只有记录结果失败时返回错误
+func (s *webhookServiceImpl) deliver(ctx context.Context, webhook *models.Webhook, d *models.WebhookDelivery, report *DispatchReport) error {
+	var result repositories.DeliveryResult
+	if d.Attempts > s.policy.MaxAttempts {
+		// 上次领取后未能记录结果（如发送中进程退出）
+		result.Error = fmt.Sprintf("发送 %d 次仍未完成", d.Attempts-1)
+	} else {
+		result = s.send(ctx, webhook, d)
+	}
+	result.Attempt = d.Attempts
+
+	if result.Error == "" {
+		report.Delivered++
+		return s.webhookRepo.MarkSucceeded(d.ID, result, time.Now())
+	}
+	if d.Attempts >= s.policy.MaxAttempts {
+		report.Dead++
+		log.Printf("💀 Webhook 投递 %d（%s → %s）失败 %d 次，已停止重试: %s", d.ID, d.EventType, webhook.URL, d.Attempts, result.Error)
+		return s.webhookRepo.MarkDead(d.ID, result)
+	}
+
+	report.Failed++
+	next := time.Now().Add(s.policy.backoff(d.Attempts))
+	log.Printf("⚠️ Webhook 投递 %d（%s → %s）第 %d 次失败，将于 %s 重试: %s",
+		d.ID, d.EventType, webhook.URL, d.Attempts, next.Format(time.RFC3339), result.Error)
+	return s.webhookRepo.MarkFailed(d.ID, result, next)
+}
+
+// send 向接收方发送签名的 POST 请求，接收方返回 2xx 视为成功
+func (s *webhookServiceImpl) send(ctx context.Context, webhook *models.Webhook, d *models.WebhookDelivery) repositories.DeliveryResult {
+	var result repositories.DeliveryResult
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		result.Error = fmt.Sprintf("创建请求失败: %v", err)
+		return result
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, timestamp, d.Payload))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = fmt.Sprintf("请求失败: %v", err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	result.ResponseCode = resp.StatusCode
+	result.ResponseBody = strings.ToValidUTF8(string(body), "")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("接收方返回 %s", resp.Status)
+	}
+	return result
+}
+
+// Start 在后台定期发送 Webhook，ctx 取消后停止
+func (s *webhookServiceImpl) Start(ctx context.Context) {
+	go poll(ctx, s.policy, "发送 Webhook", s.DeliverPending)
+}
+
+// ListDeliveries 分页查看投递日志，webhookID 为0、status 为空时不按该条件过滤
+func (s *webhookServiceImpl) ListDeliveries(webhookID uint, status string, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		return nil, 0, fmt.Errorf("无效的投递状态: %q", status)
+	}
+	deliveries, total, err := s.webhookRepo.ListDeliveries(webhookID, status, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取投递记录失败: %v", err)
+	}
+	return deliveries, total, nil
+}
+
+// ListAttempts 查看投递的每次请求记录（按发送顺序），投递不存在时返回 ErrDeliveryNotFound
+func (s *webhookServiceImpl) ListAttempts(deliveryID uint) ([]models.WebhookDeliveryAttempt, error) {
+	attempts, err := s.webhookRepo.ListAttempts(deliveryID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("获取请求记录失败: %v", err)
+	}
+	return attempts, nil
+}
+
+// Redeliver 手动重新投递（无论之前成功与否），使用原来的请求体和投递ID
+func (s *webhookServiceImpl) Redeliver(id uint) error {
+	if err := s.webhookRepo.Redeliver(id, time.Now()); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return ErrDeliveryNotFound
+		}
+		return fmt.Errorf("重新投递失败: %v", err)
+	}
+	return nil
+}
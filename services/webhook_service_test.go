@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"exercise/internal/testdb"
+	"exercise/models"
+	"exercise/repositories"
+
+	"gorm.io/gorm"
+)
+
+// webhookRequest 接收方收到的一次请求
+type webhookRequest struct {
+	deliveryID string
+	eventType  string
+	body       []byte
+	signed     bool // 签名校验通过
+}
+
+// webhookReceiver 校验签名并按 status 响应的接收方
+type webhookReceiver struct {
+	server *httptest.Server
+	secret string
+
+	mu       sync.Mutex
+	status   int
+	requests []webhookRequest
+}
+
+// newWebhookReceiver 启动接收方，默认返回 200
+func newWebhookReceiver(t *testing.T, secret string) *webhookReceiver {
+	t.Helper()
+	r := &webhookReceiver{secret: secret, status: http.StatusOK}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, webhookRequest{
+			deliveryID: req.Header.Get(WebhookDeliveryHeader),
+			eventType:  req.Header.Get(WebhookEventHeader),
+			body:       body,
+			signed: VerifyWebhookSignature(r.secret, req.Header.Get(WebhookTimestampHeader), body,
+				req.Header.Get(WebhookSignatureHeader)),
+		})
+		status := r.status
+		r.mu.Unlock()
+		if status >= 300 && status < 400 {
+			w.Header().Set("Location", "/moved")
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	})
+	// 重定向的目标：不应被请求
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("发送 Webhook 时跟随了重定向")
+	})
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// respond 设置之后请求的响应码
+func (r *webhookReceiver) respond(status int) {
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+}
+
+// received 返回已收到的请求
+func (r *webhookReceiver) received() []webhookRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]webhookRequest(nil), r.requests...)
+}
+
+// newWebhookTest 创建订阅了 user.registered 的 Webhook 和一个待发送的投递
+func newWebhookTest(t *testing.T, policy DispatchPolicy) (WebhookService, *webhookReceiver, *models.WebhookDelivery, *gorm.DB) {
+	t.Helper()
+	db := testdb.Open(t)
+	receiver := newWebhookReceiver(t, "test-secret")
+	svc := NewWebhookService(policy)
+	webhook := &models.Webhook{
+		URL:        receiver.server.URL + "/hook",
+		EventTypes: []string{models.EventUserRegistered},
+		Secret:     receiver.secret,
+	}
+	if err := svc.CreateWebhook(webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	event := &models.OutboxEvent{ID: 42, EventType: models.EventUserRegistered, Payload: []byte(`{"user_id":7}`), CreatedAt: time.Now()}
+	if err := svc.(*webhookServiceImpl).enqueue(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	var delivery models.WebhookDelivery
+	if err := db.Where("webhook_id = ? AND event_id = ?", webhook.ID, event.ID).First(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+	return svc, receiver, &delivery, db
+}
+
+// loadDelivery 重新读取投递
+func loadDelivery(t *testing.T, db *gorm.DB, id uint) *models.WebhookDelivery {
+	t.Helper()
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &delivery
+}
+
+// attemptCodes 返回投递每次请求的次数和响应码
+func attemptCodes(t *testing.T, svc WebhookService, id uint) [][2]int {
+	t.Helper()
+	attempts, err := svc.ListAttempts(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes [][2]int
+	for _, a := range attempts {
+		codes = append(codes, [2]int{a.Attempt, a.ResponseCode})
+	}
+	return codes
+}
+
+// checkRequests 检查接收方收到的请求都带有正确的签名和投递ID
+func checkRequests(t *testing.T, requests []webhookRequest, delivery *models.WebhookDelivery) {
+	t.Helper()
+	for i, req := range requests {
+		if !req.signed {
+			t.Errorf("第 %d 个请求的签名校验失败", i+1)
+		}
+		if req.deliveryID != strconv.FormatUint(uint64(delivery.ID), 10) {
+			t.Errorf("第 %d 个请求的投递ID = %q, want %d", i+1, req.deliveryID, delivery.ID)
+		}
+		if req.eventType != delivery.EventType {
+			t.Errorf("第 %d 个请求的事件类型 = %q, want %q", i+1, req.eventType, delivery.EventType)
+		}
+		if string(req.body) != string(delivery.Payload) {
+			t.Errorf("第 %d 个请求的请求体 = %s, want %s", i+1, req.body, delivery.Payload)
+		}
+	}
+}
+
+func TestWebhookDeliverySucceeds(t *testing.T) {
+	svc, receiver, delivery, db := newWebhookTest(t, DispatchPolicy{})
+
+	report, err := svc.DeliverPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Delivered != 1 || report.Failed != 0 || report.Dead != 0 {
+		t.Errorf("report = %+v, want 1 条成功", report)
+	}
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("接收方收到 %d 个请求, want 1", len(requests))
+	}
+	checkRequests(t, requests, delivery)
+
+	got := loadDelivery(t, db, delivery.ID)
+	if got.Status != models.DeliverySucceeded || got.DeliveredAt == nil || got.ResponseCode != http.StatusOK || got.Attempts != 1 {
+		t.Errorf("投递 = %s/%v/%d/%d 次, want succeeded/已送达/200/1 次", got.Status, got.DeliveredAt, got.ResponseCode, got.Attempts)
+	}
+	if codes := attemptCodes(t, svc, delivery.ID); len(codes) != 1 || codes[0] != [2]int{1, http.StatusOK} {
+		t.Errorf("请求记录 = %v, want [[1 200]]", codes)
+	}
+}
+
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusFound} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			backoff := time.Minute
+			svc, receiver, delivery, db := newWebhookTest(t, DispatchPolicy{MaxAttempts: 3, RetryBackoff: backoff, MaxBackoff: time.Hour})
+			receiver.respond(status)
+
+			before := time.Now()
+			report, err := svc.DeliverPending(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Failed != 1 || report.Delivered != 0 || report.Dead != 0 {
+				t.Errorf("report = %+v, want 1 条等待重试", report)
+			}
+
+			got := loadDelivery(t, db, delivery.ID)
+			if got.Status != models.DeliveryPending || got.ResponseCode != status || got.LastError == "" {
+				t.Errorf("投递 = %s/%d/%q, want pending/%d/有错误", got.Status, got.ResponseCode, got.LastError, status)
+			}
+			if got.NextAttemptAt.Before(before.Add(backoff)) || got.NextAttemptAt.After(time.Now().Add(backoff)) {
+				t.Errorf("下次发送时间 = %s, want 约 %s 之后", got.NextAttemptAt, backoff)
+			}
+
+			// 退避期间不再发送
+			if _, err := svc.DeliverPending(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(receiver.received()); n != 1 {
+				t.Errorf("退避期间收到 %d 个请求, want 1", n)
+			}
+			checkRequests(t, receiver.received(), delivery)
+			if codes := attemptCodes(t, svc, delivery.ID); len(codes) != 1 || codes[0] != [2]int{1, status} {
+				t.Errorf("请求记录 = %v, want [[1 %d]]", codes, status)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	svc, receiver, delivery, db := newWebhookTest(t, DispatchPolicy{MaxAttempts: 3, RetryBackoff: time.Millisecond})
+	receiver.respond(http.StatusServiceUnavailable)
+
+	var dead int
+	for i := 0; i < 3; i++ {
+		time.Sleep(10 * time.Millisecond) // 等待退避结束
+		report, err := svc.DeliverPending(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		dead += report.Dead
+	}
+	if dead != 1 {
+		t.Errorf("停止重试的投递 = %d, want 1", dead)
+	}
+
+	got := loadDelivery(t, db, delivery.ID)
+	if got.Status != models.DeliveryFailed || got.Attempts != 3 || got.DeliveredAt != nil {
+		t.Errorf("投递 = %s/%d 次/%v, want failed/3 次/未送达", got.Status, got.Attempts, got.DeliveredAt)
+	}
+	want := [][2]int{{1, 503}, {2, 503}, {3, 503}}
+	if codes := attemptCodes(t, svc, delivery.ID); len(codes) != len(want) || codes[0] != want[0] || codes[1] != want[1] || codes[2] != want[2] {
+		t.Errorf("请求记录 = %v, want %v", codes, want)
+	}
+
+	// 失败后不再自动发送
+	time.Sleep(10 * time.Millisecond)
+	if _, err := svc.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(receiver.received()); n != 3 {
+		t.Errorf("接收方收到 %d 个请求, want 3", n)
+	}
+	checkRequests(t, receiver.received(), delivery)
+}
+
+func TestWebhookRedeliverResendsOriginalPayload(t *testing.T) {
+	svc, receiver, delivery, db := newWebhookTest(t, DispatchPolicy{})
+	if _, err := svc.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Redeliver(delivery.ID); err != nil {
+		t.Fatal(err)
+	}
+	got := loadDelivery(t, db, delivery.ID)
+	if got.Status != models.DeliveryPending || got.Attempts != 0 || got.DeliveredAt != nil {
+		t.Errorf("重新投递后 = %s/%d 次/%v, want pending/0 次/未送达", got.Status, got.Attempts, got.DeliveredAt)
+	}
+
+	receiver.respond(http.StatusAccepted)
+	report, err := svc.DeliverPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Delivered != 1 {
+		t.Errorf("report = %+v, want 1 条成功", report)
+	}
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("接收方收到 %d 个请求, want 2", len(requests))
+	}
+	// 两次请求的投递ID和请求体相同，接收方可据此去重
+	checkRequests(t, requests, delivery)
+
+	want := [][2]int{{1, http.StatusOK}, {1, http.StatusAccepted}}
+	if codes := attemptCodes(t, svc, delivery.ID); len(codes) != 2 || codes[0] != want[0] || codes[1] != want[1] {
+		t.Errorf("请求记录 = %v, want %v", codes, want)
+	}
+
+	if err := svc.Redeliver(delivery.ID + 100); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("重新投递不存在的投递: %v, want ErrDeliveryNotFound", err)
+	}
+	if _, err := svc.ListAttempts(delivery.ID + 100); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("查看不存在的投递: %v, want ErrDeliveryNotFound", err)
+	}
+}
+
+func TestDeleteWebhookRemovesAttempts(t *testing.T) {
+	svc, _, delivery, db := newWebhookTest(t, DispatchPolicy{})
+	if _, err := svc.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteWebhook(delivery.WebhookID); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := db.Model(&models.WebhookDeliveryAttempt{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("删除 Webhook 后仍有 %d 条请求记录", count)
+	}
+	if _, err := svc.ListAttempts(delivery.ID); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("删除后查看投递: %v, want ErrDeliveryNotFound", err)
+	}
+}
+
+func TestPurgeSucceededDeliveriesRemovesAttempts(t *testing.T) {
+	svc, _, delivery, db := newWebhookTest(t, DispatchPolicy{})
+	if _, err := svc.DeliverPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := repositories.NewWebhookRepository().PurgeSucceededBefore(time.Now().Add(time.Hour), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("删除了 %d 条投递, want 1", purged)
+	}
+	var count int64
+	if err := db.Model(&models.WebhookDeliveryAttempt{}).Where("delivery_id = ?", delivery.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("清理投递后仍有 %d 条请求记录", count)
+	}
+}
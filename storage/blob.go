@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"exercise/config"
+)
+
+// ErrBlobNotFound 对象不存在
+var ErrBlobNotFound = errors.New("对象不存在")
+
+// validKey 对象键只允许字母、数字和 / . _ -，且不能包含空段或 ..
+var validKey = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+
+// BlobStore 对象存储接口，键形如 avatars/1/abc/256.jpg
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error // 对象不存在时不报错
+	URL(key string) string   // 对象的对外访问地址
+	KeyFromURL(rawURL string) (string, bool)
+}
+
+// New 根据配置创建对象存储
+func New(cfg config.StorageConfig) (BlobStore, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocalStore(cfg.Dir, cfg.PublicURL)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	}
+	return nil, fmt.Errorf("不支持的存储类型: %q", cfg.Driver)
+}
+
+// checkKey 校验对象键
+func checkKey(key string) error {
+	if !validKey.MatchString(key) {
+		return fmt.Errorf("无效的对象键: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "." || part == ".." {
+			return fmt.Errorf("无效的对象键: %q", key)
+		}
+	}
+	return nil
+}
+
+// joinURL 拼接访问地址前缀和对象键
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
+
+// keyFromURL 从访问地址中取出对象键，地址不以 base 开头时返回 false
+func keyFromURL(base, rawURL string) (string, bool) {
+	prefix := strings.TrimRight(base, "/") + "/"
+	if !strings.HasPrefix(rawURL, prefix) {
+		return "", false
+	}
+	key, err := url.PathUnescape(strings.TrimPrefix(rawURL, prefix))
+	if err != nil || checkKey(key) != nil {
+		return "", false
+	}
+	return key, true
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore 本地文件系统存储，对象保存为 dir 下的文件
+type LocalStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStore 创建本地存储，目录不存在时自动创建
+func NewLocalStore(dir, baseURL string) (*LocalStore, error) {
+	if baseURL == "" {
+		return nil, errors.New("本地存储需要配置访问地址")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %v", err)
+	}
+	return &LocalStore{dir: dir, baseURL: baseURL}, nil
+}
+
+// Put 写入对象：先写临时文件再重命名，避免读到写了一半的文件
+func (s *LocalStore) Put(key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", key, err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后为空操作
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入 %s 失败: %v", key, err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入 %s 失败: %v", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", key, err)
+	}
+	return nil
+}
+
+// Get 读取对象
+func (s *LocalStore) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+// Delete 删除对象，并清理因此变空的上级目录
+func (s *LocalStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除 %s 失败: %v", key, err)
+	}
+
+	root := filepath.Clean(s.dir)
+	for dir := filepath.Dir(path); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil { // 目录非空
+			break
+		}
+	}
+	return nil
+}
+
+// URL 返回对象的访问地址
+func (s *LocalStore) URL(key string) string {
+	return joinURL(s.baseURL, key)
+}
+
+// KeyFromURL 从访问地址中取出对象键，不是本存储的地址时返回 false
+func (s *LocalStore) KeyFromURL(rawURL string) (string, bool) {
+	return keyFromURL(s.baseURL, rawURL)
+}
+
+// path 返回对象对应的文件路径
+func (s *LocalStore) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
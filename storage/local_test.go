@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocalStore(filepath.Join(dir, "uploads"), "http://localhost:8080/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "avatars/1/abc/256.jpg"
+	if err := s.Put(key, []byte("v1"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put(key, []byte("v2"), "image/jpeg"); err != nil {
+		t.Fatalf("覆盖写入: %v", err)
+	}
+	got, err := s.Get(key)
+	if err != nil || string(got) != "v2" {
+		t.Fatalf("Get = %q, %v, want v2", got, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "uploads", "avatars", "1", "abc", "256.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("文件权限 = %v, want 0644", info.Mode().Perm())
+	}
+
+	if err := s.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(key); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("删除后 Get 返回 %v, want ErrBlobNotFound", err)
+	}
+	if err := s.Delete(key); err != nil {
+		t.Errorf("删除不存在的对象: %v", err)
+	}
+	// 变空的上级目录一并删除，存储根目录保留
+	if _, err := os.Stat(filepath.Join(dir, "uploads", "avatars")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("空目录未清理: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploads")); err != nil {
+		t.Errorf("存储根目录被删除: %v", err)
+	}
+}
+
+func TestLocalStoreRejectsInvalidKeys(t *testing.T) {
+	s, err := NewLocalStore(t.TempDir(), "http://localhost/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "../etc/passwd", "a/../../b", "/abs", "a//b", "a/./b", "空格 key"} {
+		if err := s.Put(key, []byte("x"), ""); err == nil {
+			t.Errorf("Put(%q) 应返回错误", key)
+		}
+		if _, err := s.Get(key); err == nil || errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("Get(%q) = %v, want 无效对象键错误", key, err)
+		}
+	}
+}
+
+func TestLocalStoreURL(t *testing.T) {
+	s, err := NewLocalStore(t.TempDir(), "http://localhost/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.URL("avatars/1/a/64.jpg"), "http://localhost/uploads/avatars/1/a/64.jpg"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		url  string
+		key  string
+		want bool
+	}{
+		{"http://localhost/uploads/avatars/1/a/64.jpg", "avatars/1/a/64.jpg", true},
+		{"http://localhost/uploads/avatars%2F1/a.jpg", "avatars/1/a.jpg", true},
+		{"http://cdn.example.com/uploads/a.jpg", "", false},
+		{"http://localhost/uploads/../secret", "", false},
+		{"http://localhost/uploadsx/a.jpg", "", false},
+	}
+	for _, tt := range tests {
+		key, ok := s.KeyFromURL(tt.url)
+		if ok != tt.want || key != tt.key {
+			t.Errorf("KeyFromURL(%q) = %q, %v, want %q, %v", tt.url, key, ok, tt.key, tt.want)
+		}
+	}
+
+	if _, err := NewLocalStore(t.TempDir(), ""); err == nil {
+		t.Error("未配置访问地址时应返回错误")
+	}
+}